@@ -0,0 +1,474 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// -----------------------------
+// Attachments
+// -----------------------------
+//
+// Files are uploaded through the API and stored in FileStore. Members
+// download them through short-lived signed links (/files/:attachment_id),
+// which can be used directly in <img> or <a> tags without the JWT. The link
+// is bound to the member it was issued to and stops working if they leave
+// the event.
+
+const attachmentURLTTL = 15 * time.Minute
+
+var allowedAttachmentTypes = map[string]bool{
+	"application/pdf":          true,
+	"image/png":                true,
+	"image/jpeg":               true,
+	"image/gif":                true,
+	"image/webp":               true,
+	"text/plain":               true,
+	"text/csv":                 true,
+	"application/msword":       true,
+	"application/vnd.ms-excel": true,
+	"application/vnd.openxmlformats-officedocument.wordprocessingml.document":   true,
+	"application/vnd.openxmlformats-officedocument.spreadsheetml.sheet":         true,
+	"application/vnd.openxmlformats-officedocument.presentationml.presentation": true,
+}
+
+// Office formats that content sniffing only sees as a zip or OLE container
+var officeExtensions = map[string]string{
+	".docx": "application/vnd.openxmlformats-officedocument.wordprocessingml.document",
+	".xlsx": "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+	".pptx": "application/vnd.openxmlformats-officedocument.presentationml.presentation",
+	".doc":  "application/msword",
+	".xls":  "application/vnd.ms-excel",
+}
+
+var oleMagic = []byte{0xD0, 0xCF, 0x11, 0xE0, 0xA1, 0xB1, 0x1A, 0xE1}
+
+// maxUploadBytes reads MAX_UPLOAD_MB (default 20).
+func maxUploadBytes() int64 {
+	if v, err := strconv.ParseInt(os.Getenv("MAX_UPLOAD_MB"), 10, 64); err == nil && v > 0 {
+		return v << 20
+	}
+	return 20 << 20
+}
+
+// detectAttachmentType sniffs the MIME type from the first bytes of the file.
+// The client-supplied Content-Type is never trusted; the file extension only
+// disambiguates container formats and CSV.
+func detectAttachmentType(head []byte, fileName string) (string, bool) {
+	ext := strings.ToLower(filepath.Ext(fileName))
+	sniffed, _, _ := mime.ParseMediaType(http.DetectContentType(head))
+
+	switch {
+	case sniffed == "application/zip" && (ext == ".docx" || ext == ".xlsx" || ext == ".pptx"):
+		return officeExtensions[ext], true
+	case bytes.HasPrefix(head, oleMagic) && (ext == ".doc" || ext == ".xls"):
+		return officeExtensions[ext], true
+	case sniffed == "text/plain" && ext == ".csv":
+		return "text/csv", true
+	}
+	return sniffed, allowedAttachmentTypes[sniffed]
+}
+
+func cleanFileName(name string) string {
+	name = filepath.Base(strings.ReplaceAll(name, "\\", "/"))
+	name = strings.Map(func(r rune) rune {
+		if r < 0x20 || r == 0x7f {
+			return -1
+		}
+		return r
+	}, strings.TrimSpace(name))
+	if name == "" || name == "." || name == "/" {
+		name = "file"
+	}
+	if len(name) > 255 {
+		name = name[len(name)-255:]
+	}
+	return name
+}
+
+func newStorageKey(eventID uint) (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("events/%d/%s", eventID, hex.EncodeToString(buf)), nil
+}
+
+func attachmentSignature(attachmentID, userID uint, expires int64) string {
+	secret := os.Getenv("JWT_SECRET")
+	if secret == "" {
+		secret = "defaultsecret"
+	}
+	mac := hmac.New(sha256.New, []byte(secret))
+	fmt.Fprintf(mac, "attachment:%d:%d:%d", attachmentID, userID, expires)
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// signedAttachmentURL returns a path relative to the API root.
+func signedAttachmentURL(att Attachment, userID uint) (string, time.Time) {
+	expires := time.Now().Add(attachmentURLTTL).Truncate(time.Second)
+	q := url.Values{}
+	q.Set("uid", strconv.FormatUint(uint64(userID), 10))
+	q.Set("expires", strconv.FormatInt(expires.Unix(), 10))
+	q.Set("sig", attachmentSignature(att.ID, userID, expires.Unix()))
+	return fmt.Sprintf("/files/%d?%s", att.ID, q.Encode()), expires
+}
+
+// serveAttachment streams the file with a download disposition.
+func serveAttachment(c *gin.Context, att Attachment) {
+	body, err := FileStore.Get(c.Request.Context(), att.StorageKey)
+	if errors.Is(err, ErrObjectNotFound) {
+		jsonError(c, http.StatusNotFound, "file content is missing")
+		return
+	}
+	if err != nil {
+		jsonError(c, http.StatusInternalServerError, "storage error: "+err.Error())
+		return
+	}
+	defer body.Close()
+
+	c.DataFromReader(http.StatusOK, att.Size, att.ContentType, body, map[string]string{
+		"Content-Disposition":    mime.FormatMediaType("attachment", map[string]string{"filename": att.FileName}),
+		"X-Content-Type-Options": "nosniff",
+		"Cache-Control":          "private, no-store",
+	})
+}
+
+// findAttachment loads the :attachment_id path param attachment of an event.
+func findAttachment(c *gin.Context, eventID uint) (Attachment, bool) {
+	var att Attachment
+	attachmentID, err := strconv.ParseUint(c.Param("attachment_id"), 10, 64)
+	if err != nil {
+		jsonError(c, http.StatusBadRequest, "invalid attachment id")
+		return att, false
+	}
+	if err := DB.Where("id = ? AND event_id = ?", attachmentID, eventID).First(&att).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			jsonError(c, http.StatusNotFound, "attachment not found")
+			return att, false
+		}
+		jsonError(c, http.StatusInternalServerError, "db error: "+err.Error())
+		return att, false
+	}
+	return att, true
+}
+
+// deleteStoredObjects removes attachment contents after their rows are gone.
+// Failures only leave orphaned objects behind, so they are logged.
+func deleteStoredObjects(keys []string) {
+	for _, key := range keys {
+		if err := FileStore.Delete(context.Background(), key); err != nil {
+			log.Printf("⚠️ could not delete stored object %s: %v", key, err)
+		}
+	}
+}
+
+type AttachmentView struct {
+	Attachment
+	DownloadURL string    `json:"download_url"`
+	ExpiresAt   time.Time `json:"download_url_expires_at"`
+}
+
+// POST /api/events/:id/attachments  (multipart: file, task_id)
+// The organizer can attach files to the event or any task; task assignees can
+// attach files to their tasks.
+func UploadAttachment(c *gin.Context) {
+	userID, ok := getUserIDFromContext(c)
+	if !ok {
+		jsonError(c, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	eventID, ok := parseEventID(c)
+	if !ok {
+		return
+	}
+	ev, ok := findMemberEvent(c, eventID, userID)
+	if !ok {
+		return
+	}
+
+	limit := maxUploadBytes()
+	// Leave room for the multipart framing around the file
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, limit+1<<20)
+	if err := c.Request.ParseMultipartForm(32 << 20); err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			jsonError(c, http.StatusRequestEntityTooLarge, fmt.Sprintf("file exceeds the %d MB limit", limit>>20))
+			return
+		}
+		jsonError(c, http.StatusBadRequest, "expected a multipart/form-data body")
+		return
+	}
+
+	var taskID *uint
+	if v := c.PostForm("task_id"); v != "" {
+		id64, err := strconv.ParseUint(v, 10, 64)
+		if err != nil {
+			jsonError(c, http.StatusBadRequest, "invalid task_id")
+			return
+		}
+		var count int64
+		if err := DB.Model(&Task{}).Where("id = ? AND event_id = ?", id64, eventID).Count(&count).Error; err != nil {
+			jsonError(c, http.StatusInternalServerError, "db error: "+err.Error())
+			return
+		}
+		if count == 0 {
+			jsonError(c, http.StatusBadRequest, "task_id is not a task of this event")
+			return
+		}
+		id := uint(id64)
+		taskID = &id
+	}
+
+	if ev.OrganizerID != userID {
+		allowed := false
+		if taskID != nil {
+			assigned, err := isTaskAssignee(*taskID, userID)
+			if err != nil {
+				jsonError(c, http.StatusInternalServerError, "db error: "+err.Error())
+				return
+			}
+			allowed = assigned
+		}
+		if !allowed {
+			jsonError(c, http.StatusForbidden, "only organizer or task assignees can upload attachments")
+			return
+		}
+	}
+
+	fh, err := c.FormFile("file")
+	if err != nil {
+		jsonError(c, http.StatusBadRequest, "file is required")
+		return
+	}
+	if fh.Size > limit {
+		jsonError(c, http.StatusRequestEntityTooLarge, fmt.Sprintf("file exceeds the %d MB limit", limit>>20))
+		return
+	}
+	if fh.Size == 0 {
+		jsonError(c, http.StatusBadRequest, "file is empty")
+		return
+	}
+
+	f, err := fh.Open()
+	if err != nil {
+		jsonError(c, http.StatusBadRequest, "could not read file: "+err.Error())
+		return
+	}
+	defer f.Close()
+
+	head := make([]byte, 512)
+	n, err := io.ReadFull(f, head)
+	if err != nil && err != io.ErrUnexpectedEOF {
+		jsonError(c, http.StatusBadRequest, "could not read file: "+err.Error())
+		return
+	}
+	head = head[:n]
+
+	att := Attachment{
+		EventID:    eventID,
+		TaskID:     taskID,
+		UploaderID: userID,
+		FileName:   cleanFileName(fh.Filename),
+		Size:       fh.Size,
+	}
+	contentType, allowed := detectAttachmentType(head, att.FileName)
+	if !allowed {
+		jsonError(c, http.StatusUnsupportedMediaType, "file type "+contentType+" is not allowed")
+		return
+	}
+	att.ContentType = contentType
+
+	if att.StorageKey, err = newStorageKey(eventID); err != nil {
+		jsonError(c, http.StatusInternalServerError, "could not store file: "+err.Error())
+		return
+	}
+	if err := FileStore.Put(c.Request.Context(), att.StorageKey, io.MultiReader(bytes.NewReader(head), f), att.Size, att.ContentType); err != nil {
+		jsonError(c, http.StatusInternalServerError, "could not store file: "+err.Error())
+		return
+	}
+	if err := DB.Create(&att).Error; err != nil {
+		deleteStoredObjects([]string{att.StorageKey})
+		jsonError(c, http.StatusInternalServerError, "could not save attachment: "+err.Error())
+		return
+	}
+
+	link, expires := signedAttachmentURL(att, userID)
+	c.JSON(http.StatusCreated, AttachmentView{Attachment: att, DownloadURL: link, ExpiresAt: expires})
+}
+
+var attachmentSorts = map[string]sortSpec{
+	"created_at": {column: "attachments.created_at", desc: true, key: "time"},
+}
+
+// GET /api/events/:id/attachments?task_id=&limit=&cursor=
+// Without task_id, all attachments of the event (including task files) are
+// listed. Newest first, as a page envelope (see pagination.go).
+func GetAttachments(c *gin.Context) {
+	userID, ok := getUserIDFromContext(c)
+	if !ok {
+		jsonError(c, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	eventID, ok := parseEventID(c)
+	if !ok {
+		return
+	}
+	if _, ok := findMemberEvent(c, eventID, userID); !ok {
+		return
+	}
+
+	page, ok := parsePageRequest(c, attachmentSorts, "created_at")
+	if !ok {
+		return
+	}
+	query := DB.Where("event_id = ?", eventID)
+	if v := c.Query("task_id"); v != "" {
+		taskID, err := strconv.ParseUint(v, 10, 64)
+		if err != nil {
+			jsonError(c, http.StatusBadRequest, "invalid task_id")
+			return
+		}
+		query = query.Where("task_id = ?", taskID)
+	}
+
+	list, next, total, ok := pageQuery(c, page, query, "attachments.id", func(att Attachment) (interface{}, uint) {
+		return att.CreatedAt, att.ID
+	})
+	if !ok {
+		return
+	}
+	views := make([]AttachmentView, 0, len(list))
+	for _, att := range list {
+		link, expires := signedAttachmentURL(att, userID)
+		views = append(views, AttachmentView{Attachment: att, DownloadURL: link, ExpiresAt: expires})
+	}
+	c.JSON(http.StatusOK, pageEnvelope(views, next, total))
+}
+
+// GET /api/events/:id/attachments/:attachment_id
+// Authenticated download for API clients that send the JWT anyway.
+func DownloadAttachment(c *gin.Context) {
+	userID, ok := getUserIDFromContext(c)
+	if !ok {
+		jsonError(c, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	eventID, ok := parseEventID(c)
+	if !ok {
+		return
+	}
+	if _, ok := findMemberEvent(c, eventID, userID); !ok {
+		return
+	}
+	att, ok := findAttachment(c, eventID)
+	if !ok {
+		return
+	}
+	serveAttachment(c, att)
+}
+
+// GET /files/:attachment_id?uid=&expires=&sig=   (public, signed)
+func DownloadSignedAttachment(c *gin.Context) {
+	attachmentID, err := strconv.ParseUint(c.Param("attachment_id"), 10, 64)
+	if err != nil {
+		jsonError(c, http.StatusBadRequest, "invalid attachment id")
+		return
+	}
+	uid, err1 := strconv.ParseUint(c.Query("uid"), 10, 64)
+	expires, err2 := strconv.ParseInt(c.Query("expires"), 10, 64)
+	if err1 != nil || err2 != nil {
+		jsonError(c, http.StatusForbidden, "invalid download link")
+		return
+	}
+	expected := attachmentSignature(uint(attachmentID), uint(uid), expires)
+	if !hmac.Equal([]byte(c.Query("sig")), []byte(expected)) {
+		jsonError(c, http.StatusForbidden, "invalid download link")
+		return
+	}
+	if time.Now().Unix() > expires {
+		jsonError(c, http.StatusForbidden, "download link expired")
+		return
+	}
+
+	var att Attachment
+	if err := DB.First(&att, attachmentID).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			jsonError(c, http.StatusNotFound, "attachment not found")
+			return
+		}
+		jsonError(c, http.StatusInternalServerError, "db error: "+err.Error())
+		return
+	}
+	var ev Event
+	if err := DB.First(&ev, att.EventID).Error; err != nil {
+		jsonError(c, http.StatusInternalServerError, "db error: "+err.Error())
+		return
+	}
+	member, err := isEventMember(ev, uint(uid))
+	if err != nil {
+		jsonError(c, http.StatusInternalServerError, "db error: "+err.Error())
+		return
+	}
+	if !member {
+		jsonError(c, http.StatusForbidden, "download link is no longer valid")
+		return
+	}
+	serveAttachment(c, att)
+}
+
+// DELETE /api/events/:id/attachments/:attachment_id
+func DeleteAttachment(c *gin.Context) {
+	userID, ok := getUserIDFromContext(c)
+	if !ok {
+		jsonError(c, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	eventID, ok := parseEventID(c)
+	if !ok {
+		return
+	}
+	ev, ok := findMemberEvent(c, eventID, userID)
+	if !ok {
+		return
+	}
+	att, ok := findAttachment(c, eventID)
+	if !ok {
+		return
+	}
+	if att.UploaderID != userID && ev.OrganizerID != userID {
+		jsonError(c, http.StatusForbidden, "only the uploader or the organizer can delete an attachment")
+		return
+	}
+
+	if err := DB.Delete(&att).Error; err != nil {
+		jsonError(c, http.StatusInternalServerError, "delete failed: "+err.Error())
+		return
+	}
+	deleteStoredObjects([]string{att.StorageKey})
+	c.JSON(http.StatusOK, gin.H{"message": "attachment deleted"})
+}
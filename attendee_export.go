@@ -0,0 +1,183 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// -----------------------------
+// Attendee export
+// -----------------------------
+//
+// GET /api/events/:id/attendees/export?format=csv|xlsx|badges
+//
+// csv and xlsx contain one row per attendee joined with the user's name and
+// email, RSVP status, number of guests and questionnaire answers. badges is a PDF of printable
+// name badges for everyone who is Going (plus the organizer).
+
+// attendeeExportRows builds the header and rows shared by the CSV and XLSX exports.
+func attendeeExportRows(eventID uint) ([][]string, error) {
+	questions, err := loadQuestionnaire(eventID)
+	if err != nil {
+		return nil, err
+	}
+
+	var attendees []EventAttendee
+	if err := DB.Preload("Answers").Where("event_id = ?", eventID).Order("role desc, id asc").Find(&attendees).Error; err != nil {
+		return nil, err
+	}
+	users, err := attendeeUsers(attendees)
+	if err != nil {
+		return nil, err
+	}
+
+	header := []string{"user_id", "name", "email", "role", "status", "guests", "late_response", "responded_at"}
+	for _, q := range questions {
+		header = append(header, q.Label)
+	}
+	rows := [][]string{header}
+
+	for _, a := range attendees {
+		u := users[a.UserID]
+		respondedAt := ""
+		if a.Status != "" {
+			respondedAt = a.UpdatedAt.UTC().Format("2006-01-02 15:04")
+		}
+		row := []string{
+			strconv.FormatUint(uint64(a.UserID), 10),
+			u.Name,
+			u.Email,
+			a.Role,
+			a.Status,
+			strconv.Itoa(a.Guests),
+			strconv.FormatBool(a.LateResponse),
+			respondedAt,
+		}
+
+		answers := make(map[uint][]string, len(a.Answers))
+		for _, ans := range a.Answers {
+			answers[ans.QuestionID] = ans.Values
+		}
+		for _, q := range questions {
+			row = append(row, strings.Join(answers[q.ID], "; "))
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+// attendeeUsers loads the users behind a list of attendee rows, keyed by ID.
+func attendeeUsers(attendees []EventAttendee) (map[uint]User, error) {
+	ids := make([]uint, 0, len(attendees))
+	for _, a := range attendees {
+		ids = append(ids, a.UserID)
+	}
+	users := make(map[uint]User, len(ids))
+	if len(ids) == 0 {
+		return users, nil
+	}
+
+	var list []User
+	if err := DB.Where("id IN ?", ids).Find(&list).Error; err != nil {
+		return nil, err
+	}
+	for _, u := range list {
+		users[u.ID] = u
+	}
+	return users, nil
+}
+
+func ExportAttendees(c *gin.Context) {
+	userID, ok := getUserIDFromContext(c)
+	if !ok {
+		jsonError(c, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	eventID, ok := parseEventID(c)
+	if !ok {
+		return
+	}
+	ev, ok := findMemberEvent(c, eventID, userID)
+	if !ok {
+		return
+	}
+	if ev.OrganizerID != userID {
+		jsonError(c, http.StatusForbidden, "only organizer can export attendees")
+		return
+	}
+
+	format := strings.ToLower(c.DefaultQuery("format", "csv"))
+	filename := fmt.Sprintf("event-%d-attendees", eventID)
+
+	switch format {
+	case "csv", "xlsx":
+		rows, err := attendeeExportRows(eventID)
+		if err != nil {
+			jsonError(c, http.StatusInternalServerError, "db error: "+err.Error())
+			return
+		}
+
+		var buf bytes.Buffer
+		contentType := "text/csv; charset=utf-8"
+		if format == "csv" {
+			w := csv.NewWriter(&buf)
+			for _, row := range rows {
+				_ = w.Write(csvSafeRow(row))
+			}
+			w.Flush()
+		} else {
+			contentType = "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+			if err := writeXLSX(&buf, "Attendees", rows); err != nil {
+				jsonError(c, http.StatusInternalServerError, "could not build spreadsheet: "+err.Error())
+				return
+			}
+		}
+
+		c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=\"%s.%s\"", filename, format))
+		c.Data(http.StatusOK, contentType, buf.Bytes())
+
+	case "badges":
+		var attendees []EventAttendee
+		if err := DB.Where("event_id = ? AND (role = ? OR status = ?)", eventID, "organizer", "Going").Order("role desc, id asc").Find(&attendees).Error; err != nil {
+			jsonError(c, http.StatusInternalServerError, "db error: "+err.Error())
+			return
+		}
+		users, err := attendeeUsers(attendees)
+		if err != nil {
+			jsonError(c, http.StatusInternalServerError, "db error: "+err.Error())
+			return
+		}
+
+		badges := make([]Badge, 0, len(attendees))
+		for _, a := range attendees {
+			u := users[a.UserID]
+			name := strings.TrimSpace(u.Name)
+			if name == "" {
+				name = u.Email
+			}
+			subtitle := u.Email
+			if a.Role == "organizer" {
+				subtitle = "Organizer"
+			}
+			badges = append(badges, Badge{Name: name, Subtitle: subtitle, Event: ev.Title})
+		}
+
+		var buf bytes.Buffer
+		if err := writeBadgesPDF(&buf, badges); err != nil {
+			jsonError(c, http.StatusInternalServerError, "could not build badges: "+err.Error())
+			return
+		}
+		c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=\"%s-badges.pdf\"", filename))
+		c.Data(http.StatusOK, "application/pdf", buf.Bytes())
+
+	default:
+		jsonError(c, http.StatusBadRequest, "format must be one of: csv, xlsx, badges")
+	}
+}
@@ -0,0 +1,228 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/csv"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestWriteXLSXIsWellFormed(t *testing.T) {
+	header := make([]string, 28)
+	for i := range header {
+		header[i] = fmt.Sprintf("q%d", i)
+	}
+	rows := [][]string{
+		header,
+		{"Tom & Jerry", "<b>bold</b>", "Zoë \"quoted\"", "  padded  "},
+	}
+
+	var buf bytes.Buffer
+	if err := writeXLSX(&buf, "Guests: [VIP]/2026 with a very long name", rows); err != nil {
+		t.Fatal(err)
+	}
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatalf("not a zip archive: %v", err)
+	}
+
+	parts := make(map[string][]byte)
+	for _, f := range zr.File {
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, err := io.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		parts[f.Name] = body
+
+		// Every part must be well-formed XML
+		dec := xml.NewDecoder(bytes.NewReader(body))
+		for {
+			if _, err := dec.Token(); err == io.EOF {
+				break
+			} else if err != nil {
+				t.Fatalf("%s: %v", f.Name, err)
+			}
+		}
+	}
+	for _, name := range []string{"[Content_Types].xml", "_rels/.rels", "xl/workbook.xml", "xl/_rels/workbook.xml.rels", "xl/worksheets/sheet1.xml"} {
+		if _, ok := parts[name]; !ok {
+			t.Errorf("missing part %s", name)
+		}
+	}
+
+	var workbook struct {
+		Sheets []struct {
+			Name string `xml:"name,attr"`
+		} `xml:"sheets>sheet"`
+	}
+	if err := xml.Unmarshal(parts["xl/workbook.xml"], &workbook); err != nil {
+		t.Fatal(err)
+	}
+	if len(workbook.Sheets) != 1 || workbook.Sheets[0].Name != "Guests VIP2026 with a very long" {
+		t.Errorf("sheets = %+v", workbook.Sheets)
+	}
+
+	var sheet struct {
+		Rows []struct {
+			R     int `xml:"r,attr"`
+			Cells []struct {
+				Ref  string `xml:"r,attr"`
+				Type string `xml:"t,attr"`
+				Text string `xml:"is>t"`
+			} `xml:"c"`
+		} `xml:"sheetData>row"`
+	}
+	if err := xml.Unmarshal(parts["xl/worksheets/sheet1.xml"], &sheet); err != nil {
+		t.Fatal(err)
+	}
+	if len(sheet.Rows) != len(rows) {
+		t.Fatalf("got %d rows, want %d", len(sheet.Rows), len(rows))
+	}
+	for r, row := range sheet.Rows {
+		if row.R != r+1 || len(row.Cells) != len(rows[r]) {
+			t.Fatalf("row %d: r=%d with %d cells", r, row.R, len(row.Cells))
+		}
+		for col, cell := range row.Cells {
+			if want := xlsxColumn(col) + strconv.Itoa(r+1); cell.Ref != want {
+				t.Errorf("cell ref = %s, want %s", cell.Ref, want)
+			}
+			if cell.Type != "inlineStr" || cell.Text != rows[r][col] {
+				t.Errorf("%s = %q (%s), want %q", cell.Ref, cell.Text, cell.Type, rows[r][col])
+			}
+		}
+	}
+	if got := sheet.Rows[0].Cells[27].Ref; got != "AB1" {
+		t.Errorf("28th column = %s, want AB1", got)
+	}
+}
+
+func TestWriteBadgesPDFXref(t *testing.T) {
+	badges := make([]Badge, 9) // two pages
+	for i := range badges {
+		badges[i] = Badge{Name: fmt.Sprintf("Guest (%d) \\ Zoë", i), Subtitle: "guest@example.com", Event: "Summer party"}
+	}
+	var buf bytes.Buffer
+	if err := writeBadgesPDF(&buf, badges); err != nil {
+		t.Fatal(err)
+	}
+	pdf := buf.Bytes()
+
+	if !bytes.HasPrefix(pdf, []byte("%PDF-1.4\n")) || !bytes.HasSuffix(pdf, []byte("%%EOF\n")) {
+		t.Fatal("missing PDF header or trailer")
+	}
+	m := regexp.MustCompile(`startxref\n(\d+)\n%%EOF\n$`).FindSubmatch(pdf)
+	if m == nil {
+		t.Fatal("missing startxref")
+	}
+	xref, _ := strconv.Atoi(string(m[1]))
+	if !bytes.HasPrefix(pdf[xref:], []byte("xref\n")) {
+		t.Fatalf("startxref %d does not point at the xref table", xref)
+	}
+
+	lines := strings.Split(string(pdf[xref:]), "\n")
+	var first, count int
+	if _, err := fmt.Sscanf(lines[1], "%d %d", &first, &count); err != nil || first != 0 {
+		t.Fatalf("xref subsection header %q", lines[1])
+	}
+	// Catalog, page tree, two fonts and a page + content pair per page
+	if count != 1+4+2*2 {
+		t.Fatalf("xref lists %d entries, want 9", count)
+	}
+	if len(lines[2]) != 19 || !strings.HasSuffix(lines[2], " f ") {
+		t.Errorf("free entry = %q", lines[2])
+	}
+	for obj := 1; obj < count; obj++ {
+		entry := lines[2+obj]
+		if len(entry) != 19 || !strings.HasSuffix(entry, " 00000 n ") {
+			t.Fatalf("entry %d = %q is not 20 bytes with EOL", obj, entry)
+		}
+		off, _ := strconv.Atoi(entry[:10])
+		if want := fmt.Sprintf("%d 0 obj\n", obj); !bytes.HasPrefix(pdf[off:], []byte(want)) {
+			t.Errorf("object %d: offset %d points at %q", obj, off, pdf[off:off+10])
+		}
+	}
+	if !strings.Contains(lines[2+count], "trailer") || !strings.Contains(string(pdf[xref:]), fmt.Sprintf("/Size %d", count)) {
+		t.Error("trailer does not match the xref table")
+	}
+
+	// Stream lengths must match their content
+	streams := regexp.MustCompile(`(?s)<< /Length (\d+) >>\nstream\n(.*?)\nendstream`).FindAllSubmatch(pdf, -1)
+	if len(streams) != 2 {
+		t.Fatalf("found %d content streams, want 2", len(streams))
+	}
+	for i, s := range streams {
+		if n, _ := strconv.Atoi(string(s[1])); n != len(s[2]) {
+			t.Errorf("stream %d: /Length %d, content is %d bytes", i, n, len(s[2]))
+		}
+	}
+	if !bytes.Contains(pdf, []byte("/Count 2")) {
+		t.Error("page tree does not count two pages")
+	}
+	if !bytes.Contains(pdf, []byte(`(Guest \(0\) \\ Zo`+"\xeb"+`)`)) {
+		t.Error("badge text is not escaped and WinAnsi-encoded")
+	}
+}
+
+func TestExportAttendeesIncludesGuests(t *testing.T) {
+	useTestDB(t)
+	r := testRouter()
+	org := createTestUser(t, "ExportOrg")
+	guest := createTestUser(t, "ExportGuest")
+	ev := createTestEvent(t, org.ID, "Export party", false)
+	inviteTestUser(t, ev.ID, guest.ID, "attendee")
+
+	respond := fmt.Sprintf("/api/events/%d/respond", ev.ID)
+	if w := apiRequest(t, r, guest.ID, http.MethodPost, respond, gin.H{"status": "Going", "guests": maxRSVPGuests + 1}); w.Code != http.StatusBadRequest {
+		t.Fatalf("too many guests: status %d: %s", w.Code, w.Body.String())
+	}
+	if w := apiRequest(t, r, guest.ID, http.MethodPost, respond, gin.H{"status": "Going", "guests": 2}); w.Code != http.StatusOK {
+		t.Fatalf("respond: status %d: %s", w.Code, w.Body.String())
+	}
+	// Omitting guests keeps the previous count
+	if w := apiRequest(t, r, guest.ID, http.MethodPost, respond, gin.H{"status": "Maybe"}); w.Code != http.StatusOK {
+		t.Fatalf("respond: status %d: %s", w.Code, w.Body.String())
+	}
+
+	w := apiRequest(t, r, org.ID, http.MethodGet, fmt.Sprintf("/api/events/%d/attendees/export?format=csv", ev.ID), nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("export: status %d: %s", w.Code, w.Body.String())
+	}
+	records, err := csv.NewReader(w.Body).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	col := -1
+	for i, h := range records[0] {
+		if h == "guests" {
+			col = i
+		}
+	}
+	if col < 0 {
+		t.Fatalf("no guests column in %v", records[0])
+	}
+	got := make(map[string]string)
+	for _, rec := range records[1:] {
+		got[rec[2]] = rec[col]
+	}
+	if got[guest.Email] != "2" || got[org.Email] != "0" {
+		t.Errorf("guests by email = %v", got)
+	}
+
+	if w := apiRequest(t, r, guest.ID, http.MethodGet, fmt.Sprintf("/api/events/%d/attendees/export", ev.ID), nil); w.Code != http.StatusForbidden {
+		t.Errorf("member export: status %d, want 403", w.Code)
+	}
+}
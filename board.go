@@ -0,0 +1,495 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// -----------------------------
+// Kanban board
+// -----------------------------
+//
+// Each event can have custom columns. Tasks are placed in a column at a
+// fractional Position, so a drag-and-drop move only updates the moved task.
+// Moves lock the target column row, which serializes concurrent drops into
+// the same column.
+
+var errColumnNotFound = errors.New("column not found")
+
+// lockColumn loads a column of the event with a row lock held until the transaction ends.
+func lockColumn(tx *gorm.DB, eventID, columnID uint) (BoardColumn, error) {
+	var col BoardColumn
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("id = ? AND event_id = ?", columnID, eventID).
+		First(&col).Error
+	if err == gorm.ErrRecordNotFound {
+		return col, errColumnNotFound
+	}
+	return col, err
+}
+
+// columnTasks returns the tasks of a column in board order, without excludeID.
+func columnTasks(tx *gorm.DB, columnID, excludeID uint) ([]Task, error) {
+	var tasks []Task
+	err := tx.Where("column_id = ? AND id <> ?", columnID, excludeID).
+		Order(`position COLLATE "C" asc, id asc`).
+		Find(&tasks).Error
+	return tasks, err
+}
+
+// assignMissingPositions gives tasks that were put in a column without a
+// position (e.g. before the board existed) a rank after the existing ones.
+func assignMissingPositions(tx *gorm.DB, columnID uint) error {
+	var last string
+	if err := tx.Model(&Task{}).
+		Where("column_id = ? AND position <> ''", columnID).
+		Select(`COALESCE(MAX(position COLLATE "C"), '')`).
+		Scan(&last).Error; err != nil {
+		return err
+	}
+
+	var missing []Task
+	if err := tx.Where("column_id = ? AND position = ''", columnID).Order("id asc").Find(&missing).Error; err != nil {
+		return err
+	}
+	for _, t := range missing {
+		last = rankBetween(last, "")
+		if err := tx.Model(&Task{}).Where("id = ?", t.ID).Update("position", last).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// appendToColumn locks the column and returns a position after its last task.
+func appendToColumn(tx *gorm.DB, eventID, columnID uint) (string, error) {
+	if _, err := lockColumn(tx, eventID, columnID); err != nil {
+		return "", err
+	}
+	if err := assignMissingPositions(tx, columnID); err != nil {
+		return "", err
+	}
+	var last string
+	if err := tx.Model(&Task{}).
+		Where("column_id = ?", columnID).
+		Select(`COALESCE(MAX(position COLLATE "C"), '')`).
+		Scan(&last).Error; err != nil {
+		return "", err
+	}
+	return rankBetween(last, ""), nil
+}
+
+// findColumn loads the :column_id path param column of an event.
+func findColumn(c *gin.Context, eventID uint) (BoardColumn, bool) {
+	var col BoardColumn
+	columnID, err := strconv.ParseUint(c.Param("column_id"), 10, 64)
+	if err != nil {
+		jsonError(c, http.StatusBadRequest, "invalid column id")
+		return col, false
+	}
+	if err := DB.Where("id = ? AND event_id = ?", columnID, eventID).First(&col).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			jsonError(c, http.StatusNotFound, "column not found")
+			return col, false
+		}
+		jsonError(c, http.StatusInternalServerError, "db error: "+err.Error())
+		return col, false
+	}
+	return col, true
+}
+
+type BoardColumnView struct {
+	BoardColumn
+	Tasks []Task `json:"tasks"`
+}
+
+// GET /api/events/:id/board
+// Columns in order with their tasks; tasks not on the board are listed under "unplaced".
+func GetBoard(c *gin.Context) {
+	userID, ok := getUserIDFromContext(c)
+	if !ok {
+		jsonError(c, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	eventID, ok := parseEventID(c)
+	if !ok {
+		return
+	}
+	if _, ok := findMemberEvent(c, eventID, userID); !ok {
+		return
+	}
+
+	var columns []BoardColumn
+	if err := DB.Where("event_id = ?", eventID).Order(`position COLLATE "C" asc, id asc`).Find(&columns).Error; err != nil {
+		jsonError(c, http.StatusInternalServerError, "db error: "+err.Error())
+		return
+	}
+	var tasks []Task
+	if err := DB.Preload("Assignees").Where("event_id = ?", eventID).
+		Order(`position COLLATE "C" asc, id asc`).Find(&tasks).Error; err != nil {
+		jsonError(c, http.StatusInternalServerError, "db error: "+err.Error())
+		return
+	}
+
+	views := make([]BoardColumnView, 0, len(columns))
+	index := make(map[uint]int, len(columns))
+	for i, col := range columns {
+		index[col.ID] = i
+		views = append(views, BoardColumnView{BoardColumn: col, Tasks: []Task{}})
+	}
+	unplaced := make([]Task, 0)
+	for _, t := range tasks {
+		if t.ColumnID != nil {
+			if i, ok := index[*t.ColumnID]; ok {
+				views[i].Tasks = append(views[i].Tasks, t)
+				continue
+			}
+		}
+		unplaced = append(unplaced, t)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"columns": views, "unplaced": unplaced})
+}
+
+type BoardColumnRequest struct {
+	Name *string `json:"name"`
+	// AfterColumnID places the column right after another one; 0 moves it to
+	// the front. Omitted on create means "at the end".
+	AfterColumnID *uint `json:"after_column_id"`
+}
+
+// columnPositionAfter returns a position right after afterID (0 = front),
+// ignoring excludeID. Caller must hold the event's column lock.
+func columnPositionAfter(tx *gorm.DB, eventID, afterID, excludeID uint) (string, error) {
+	var columns []BoardColumn
+	if err := tx.Where("event_id = ? AND id <> ?", eventID, excludeID).
+		Order(`position COLLATE "C" asc, id asc`).Find(&columns).Error; err != nil {
+		return "", err
+	}
+	if afterID == 0 {
+		if len(columns) == 0 {
+			return rankBetween("", ""), nil
+		}
+		return rankBetween("", columns[0].Position), nil
+	}
+	for i, col := range columns {
+		if col.ID != afterID {
+			continue
+		}
+		next := ""
+		if i+1 < len(columns) {
+			next = columns[i+1].Position
+		}
+		return rankBetween(col.Position, next), nil
+	}
+	return "", errColumnNotFound
+}
+
+// lockEventColumns serializes column reordering for an event.
+func lockEventColumns(tx *gorm.DB, eventID uint) error {
+	var ev Event
+	return tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id").First(&ev, eventID).Error
+}
+
+// POST /api/events/:id/board/columns
+func CreateBoardColumn(c *gin.Context) {
+	userID, ok := getUserIDFromContext(c)
+	if !ok {
+		jsonError(c, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	eventID, ok := parseEventID(c)
+	if !ok {
+		return
+	}
+	ev, ok := findMemberEvent(c, eventID, userID)
+	if !ok {
+		return
+	}
+	if ev.OrganizerID != userID {
+		jsonError(c, http.StatusForbidden, "only organizer can edit the board")
+		return
+	}
+
+	var body BoardColumnRequest
+	if err := c.ShouldBindJSON(&body); err != nil {
+		jsonError(c, http.StatusBadRequest, "invalid body: "+err.Error())
+		return
+	}
+	if body.Name == nil || strings.TrimSpace(*body.Name) == "" {
+		jsonError(c, http.StatusBadRequest, "name is required")
+		return
+	}
+
+	col := BoardColumn{EventID: eventID, Name: strings.TrimSpace(*body.Name)}
+	err := DB.Transaction(func(tx *gorm.DB) error {
+		if err := lockEventColumns(tx, eventID); err != nil {
+			return err
+		}
+		if body.AfterColumnID != nil {
+			pos, err := columnPositionAfter(tx, eventID, *body.AfterColumnID, 0)
+			if err != nil {
+				return err
+			}
+			col.Position = pos
+		} else {
+			var last string
+			if err := tx.Model(&BoardColumn{}).Where("event_id = ?", eventID).
+				Select(`COALESCE(MAX(position COLLATE "C"), '')`).Scan(&last).Error; err != nil {
+				return err
+			}
+			col.Position = rankBetween(last, "")
+		}
+		return tx.Create(&col).Error
+	})
+	if err == errColumnNotFound {
+		jsonError(c, http.StatusBadRequest, "after_column_id is not a column of this event")
+		return
+	}
+	if err != nil {
+		jsonError(c, http.StatusInternalServerError, "could not create column: "+err.Error())
+		return
+	}
+	c.JSON(http.StatusCreated, col)
+}
+
+// PATCH /api/events/:id/board/columns/:column_id
+func UpdateBoardColumn(c *gin.Context) {
+	userID, ok := getUserIDFromContext(c)
+	if !ok {
+		jsonError(c, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	eventID, ok := parseEventID(c)
+	if !ok {
+		return
+	}
+	ev, ok := findMemberEvent(c, eventID, userID)
+	if !ok {
+		return
+	}
+	if ev.OrganizerID != userID {
+		jsonError(c, http.StatusForbidden, "only organizer can edit the board")
+		return
+	}
+	col, ok := findColumn(c, eventID)
+	if !ok {
+		return
+	}
+
+	var body BoardColumnRequest
+	if err := c.ShouldBindJSON(&body); err != nil {
+		jsonError(c, http.StatusBadRequest, "invalid body: "+err.Error())
+		return
+	}
+	if body.Name != nil {
+		name := strings.TrimSpace(*body.Name)
+		if name == "" {
+			jsonError(c, http.StatusBadRequest, "name cannot be empty")
+			return
+		}
+		col.Name = name
+	}
+
+	err := DB.Transaction(func(tx *gorm.DB) error {
+		if body.AfterColumnID != nil {
+			if *body.AfterColumnID == col.ID {
+				return errColumnNotFound
+			}
+			if err := lockEventColumns(tx, eventID); err != nil {
+				return err
+			}
+			pos, err := columnPositionAfter(tx, eventID, *body.AfterColumnID, col.ID)
+			if err != nil {
+				return err
+			}
+			col.Position = pos
+		}
+		return tx.Save(&col).Error
+	})
+	if err == errColumnNotFound {
+		jsonError(c, http.StatusBadRequest, "after_column_id must be another column of this event")
+		return
+	}
+	if err != nil {
+		jsonError(c, http.StatusInternalServerError, "could not update column: "+err.Error())
+		return
+	}
+	c.JSON(http.StatusOK, col)
+}
+
+// DELETE /api/events/:id/board/columns/:column_id
+// Tasks in the column are kept and become unplaced.
+func DeleteBoardColumn(c *gin.Context) {
+	userID, ok := getUserIDFromContext(c)
+	if !ok {
+		jsonError(c, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	eventID, ok := parseEventID(c)
+	if !ok {
+		return
+	}
+	ev, ok := findMemberEvent(c, eventID, userID)
+	if !ok {
+		return
+	}
+	if ev.OrganizerID != userID {
+		jsonError(c, http.StatusForbidden, "only organizer can edit the board")
+		return
+	}
+	col, ok := findColumn(c, eventID)
+	if !ok {
+		return
+	}
+
+	if err := DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&Task{}).Where("column_id = ?", col.ID).
+			Updates(map[string]interface{}{"column_id": nil, "position": ""}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&col).Error
+	}); err != nil {
+		jsonError(c, http.StatusInternalServerError, "delete failed: "+err.Error())
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "column deleted"})
+}
+
+type MoveTaskRequest struct {
+	ColumnID     *uint `json:"column_id"`      // target column; null or 0 takes the task off the board
+	AfterTaskID  *uint `json:"after_task_id"`  // drop directly after this task
+	BeforeTaskID *uint `json:"before_task_id"` // or directly before this one; neither = end of column
+}
+
+var errBadAnchor = errors.New("anchor task is not in the target column")
+
+// POST /api/events/:id/tasks/:task_id/move
+// Changes column and position in one transaction. The neighbour on the other
+// side of the anchor is read under the column lock, so clients only need to
+// name one neighbour and stale boards still produce a consistent order.
+func MoveTask(c *gin.Context) {
+	userID, ok := getUserIDFromContext(c)
+	if !ok {
+		jsonError(c, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	eventID, ok := parseEventID(c)
+	if !ok {
+		return
+	}
+	ev, ok := findMemberEvent(c, eventID, userID)
+	if !ok {
+		return
+	}
+	task, ok := findTask(c, eventID)
+	if !ok {
+		return
+	}
+	if ev.OrganizerID != userID {
+		assigned, err := isTaskAssignee(task.ID, userID)
+		if err != nil {
+			jsonError(c, http.StatusInternalServerError, "db error: "+err.Error())
+			return
+		}
+		if !assigned {
+			jsonError(c, http.StatusForbidden, "only organizer or assignees can move tasks")
+			return
+		}
+	}
+
+	var body MoveTaskRequest
+	if err := c.ShouldBindJSON(&body); err != nil {
+		jsonError(c, http.StatusBadRequest, "invalid body: "+err.Error())
+		return
+	}
+	if body.AfterTaskID != nil && body.BeforeTaskID != nil {
+		jsonError(c, http.StatusBadRequest, "use either after_task_id or before_task_id, not both")
+		return
+	}
+
+	err := DB.Transaction(func(tx *gorm.DB) error {
+		if body.ColumnID == nil || *body.ColumnID == 0 {
+			return tx.Model(&Task{}).Where("id = ?", task.ID).
+				Updates(map[string]interface{}{"column_id": nil, "position": ""}).Error
+		}
+		columnID := *body.ColumnID
+
+		if _, err := lockColumn(tx, eventID, columnID); err != nil {
+			return err
+		}
+		if err := assignMissingPositions(tx, columnID); err != nil {
+			return err
+		}
+		others, err := columnTasks(tx, columnID, task.ID)
+		if err != nil {
+			return err
+		}
+
+		lower, upper := "", ""
+		switch {
+		case body.AfterTaskID != nil:
+			i := taskIndex(others, *body.AfterTaskID)
+			if i < 0 {
+				return errBadAnchor
+			}
+			lower = others[i].Position
+			if i+1 < len(others) {
+				upper = others[i+1].Position
+			}
+		case body.BeforeTaskID != nil:
+			i := taskIndex(others, *body.BeforeTaskID)
+			if i < 0 {
+				return errBadAnchor
+			}
+			upper = others[i].Position
+			if i > 0 {
+				lower = others[i-1].Position
+			}
+		default:
+			if len(others) > 0 {
+				lower = others[len(others)-1].Position
+			}
+		}
+
+		return tx.Model(&Task{}).Where("id = ?", task.ID).
+			Updates(map[string]interface{}{"column_id": columnID, "position": rankBetween(lower, upper)}).Error
+	})
+	switch {
+	case err == errColumnNotFound:
+		jsonError(c, http.StatusBadRequest, "column_id is not a column of this event")
+		return
+	case err == errBadAnchor:
+		jsonError(c, http.StatusConflict, err.Error())
+		return
+	case err != nil:
+		jsonError(c, http.StatusInternalServerError, "could not move task: "+err.Error())
+		return
+	}
+
+	if err := DB.Preload("Assignees").First(&task, task.ID).Error; err != nil {
+		jsonError(c, http.StatusInternalServerError, "db error: "+err.Error())
+		return
+	}
+	c.JSON(http.StatusOK, task)
+}
+
+func taskIndex(tasks []Task, id uint) int {
+	for i, t := range tasks {
+		if t.ID == id {
+			return i
+		}
+	}
+	return -1
+}
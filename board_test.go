@@ -0,0 +1,161 @@
+package main
+
+import (
+	"fmt"
+	"math/rand"
+	"net/http"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestRankBetween(t *testing.T) {
+	cases := []struct{ a, b, want string }{
+		{"", "", "i"},
+		{"i", "", "r"},
+		{"", "i", "9"},
+		{"a", "b", "ai"},
+		{"a", "a1", "a0i"},
+		{"z", "", "zi"},
+		{"", "1", "0i"},
+		{"0i", "1", "0r"},
+	}
+	for _, tc := range cases {
+		if got := rankBetween(tc.a, tc.b); got != tc.want {
+			t.Errorf("rankBetween(%q, %q) = %q, want %q", tc.a, tc.b, got, tc.want)
+		}
+	}
+}
+
+// Repeated inserts at random gaps, including always at the head or the tail,
+// must keep every key strictly between its neighbours.
+func TestRankBetweenKeepsOrder(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	for _, mode := range []string{"random", "head", "tail", "same gap"} {
+		keys := []string{}
+		for n := 0; n < 500; n++ {
+			var i int
+			switch mode {
+			case "random":
+				i = rng.Intn(len(keys) + 1)
+			case "head":
+				i = 0
+			case "tail":
+				i = len(keys)
+			case "same gap":
+				if len(keys) > 0 {
+					i = 1
+				}
+			}
+			lower, upper := "", ""
+			if i > 0 {
+				lower = keys[i-1]
+			}
+			if i < len(keys) {
+				upper = keys[i]
+			}
+			key := rankBetween(lower, upper)
+			if key <= lower || (upper != "" && key >= upper) {
+				t.Fatalf("%s: rankBetween(%q, %q) = %q is not between them", mode, lower, upper, key)
+			}
+			if strings.HasSuffix(key, "0") {
+				t.Fatalf("%s: rankBetween(%q, %q) = %q ends in 0", mode, lower, upper, key)
+			}
+			keys = append(keys[:i], append([]string{key}, keys[i:]...)...)
+		}
+		if !sort.StringsAreSorted(keys) {
+			t.Fatalf("%s: keys out of order", mode)
+		}
+	}
+}
+
+type boardView struct {
+	Columns []struct {
+		ID    uint   `json:"id"`
+		Tasks []Task `json:"tasks"`
+	} `json:"columns"`
+	Unplaced []Task `json:"unplaced"`
+}
+
+func TestMoveTaskPositions(t *testing.T) {
+	useTestDB(t)
+	r := testRouter()
+	org := createTestUser(t, "BoardOrg")
+	ev := createTestEvent(t, org.ID, "Board event", false)
+
+	newColumn := func(name string) uint {
+		var col BoardColumn
+		w := apiRequest(t, r, org.ID, http.MethodPost, fmt.Sprintf("/api/events/%d/board/columns", ev.ID), gin.H{"name": name})
+		if w.Code != http.StatusCreated {
+			t.Fatalf("create column: status %d: %s", w.Code, w.Body.String())
+		}
+		decodeJSON(t, w, &col)
+		return col.ID
+	}
+	todo, done := newColumn("Todo"), newColumn("Done")
+
+	tasks := make(map[string]uint)
+	for _, title := range []string{"a", "b", "c", "d"} {
+		tasks[title] = createTestTask(t, ev.ID, title).ID
+	}
+	move := func(title string, body gin.H) int {
+		w := apiRequest(t, r, org.ID, http.MethodPost, fmt.Sprintf("/api/events/%d/tasks/%d/move", ev.ID, tasks[title]), body)
+		return w.Code
+	}
+	order := func(column uint) string {
+		var board boardView
+		w := apiRequest(t, r, org.ID, http.MethodGet, fmt.Sprintf("/api/events/%d/board", ev.ID), nil)
+		if w.Code != http.StatusOK {
+			t.Fatalf("board: status %d: %s", w.Code, w.Body.String())
+		}
+		decodeJSON(t, w, &board)
+		for _, col := range board.Columns {
+			if col.ID == column {
+				titles := make([]string, 0, len(col.Tasks))
+				for _, task := range col.Tasks {
+					titles = append(titles, task.Title)
+				}
+				return strings.Join(titles, ",")
+			}
+		}
+		t.Fatalf("column %d not on the board", column)
+		return ""
+	}
+
+	steps := []struct {
+		task   string
+		body   gin.H
+		column uint
+		want   string
+	}{
+		{"a", gin.H{"column_id": todo}, todo, "a"},                                     // into an empty column
+		{"b", gin.H{"column_id": todo}, todo, "a,b"},                                   // tail by default
+		{"c", gin.H{"column_id": todo, "before_task_id": tasks["a"]}, todo, "c,a,b"},   // head
+		{"d", gin.H{"column_id": todo, "after_task_id": tasks["b"]}, todo, "c,a,b,d"},  // tail via anchor
+		{"d", gin.H{"column_id": todo, "after_task_id": tasks["c"]}, todo, "c,d,a,b"},  // within the column
+		{"b", gin.H{"column_id": todo, "before_task_id": tasks["c"]}, todo, "b,c,d,a"}, // tail to head
+		{"a", gin.H{"column_id": done, "before_task_id": nil}, done, "a"},              // into another empty column
+		{"c", gin.H{"column_id": done, "after_task_id": tasks["a"]}, done, "a,c"},
+	}
+	for i, s := range steps {
+		if code := move(s.task, s.body); code != http.StatusOK {
+			t.Fatalf("step %d: move %s: status %d", i, s.task, code)
+		}
+		if got := order(s.column); got != s.want {
+			t.Fatalf("step %d: order = %s, want %s", i, got, s.want)
+		}
+	}
+	if got := order(todo); got != "b,d" {
+		t.Errorf("todo after moving out = %s, want b,d", got)
+	}
+
+	// Anchors must be in the target column
+	if code := move("b", gin.H{"column_id": todo, "after_task_id": tasks["a"]}); code != http.StatusConflict {
+		t.Errorf("anchor in another column: status %d, want 409", code)
+	}
+	if code := move("b", gin.H{"column_id": todo, "after_task_id": tasks["d"], "before_task_id": tasks["d"]}); code != http.StatusBadRequest {
+		t.Errorf("both anchors: status %d, want 400", code)
+	}
+}
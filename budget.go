@@ -0,0 +1,837 @@
+package main
+
+import (
+	"fmt"
+	"math/big"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// -----------------------------
+// Budget & expenses
+// -----------------------------
+//
+// Amounts are int64 minor units of their own currency (cents, or yen for
+// JPY). Reports convert everything to the event's BudgetCurrency with the
+// exchange rates stored on the event; amounts in a currency without a rate
+// are left out and listed under missing_rates.
+
+// Currencies whose minor unit is not 1/100
+var currencyExponents = map[string]int{
+	"JPY": 0, "KRW": 0, "VND": 0, "CLP": 0, "ISK": 0, "HUF": 0,
+	"BHD": 3, "KWD": 3, "OMR": 3, "JOD": 3, "TND": 3,
+}
+
+func currencyExponent(code string) int {
+	if exp, ok := currencyExponents[code]; ok {
+		return exp
+	}
+	return 2
+}
+
+// normalizeCurrency upper-cases an ISO 4217 code and checks its shape.
+func normalizeCurrency(code string) (string, bool) {
+	code = strings.ToUpper(strings.TrimSpace(code))
+	if len(code) != 3 {
+		return code, false
+	}
+	for _, ch := range code {
+		if ch < 'A' || ch > 'Z' {
+			return code, false
+		}
+	}
+	return code, true
+}
+
+// roundRat rounds half away from zero.
+func roundRat(r *big.Rat) int64 {
+	num := new(big.Int).Abs(r.Num())
+	q, rem := new(big.Int).QuoRem(num, r.Denom(), new(big.Int))
+	if rem.Mul(rem, big.NewInt(2)).Cmp(r.Denom()) >= 0 {
+		q.Add(q, big.NewInt(1))
+	}
+	if r.Sign() < 0 {
+		q.Neg(q)
+	}
+	return q.Int64()
+}
+
+type currencyConverter struct {
+	base  string
+	rates map[string]*big.Rat
+}
+
+func loadConverter(ev Event) (currencyConverter, error) {
+	cv := currencyConverter{base: ev.BudgetCurrency, rates: make(map[string]*big.Rat)}
+	var rows []ExchangeRate
+	if err := DB.Where("event_id = ?", ev.ID).Find(&rows).Error; err != nil {
+		return cv, err
+	}
+	for _, r := range rows {
+		if rate, ok := new(big.Rat).SetString(r.Rate); ok {
+			cv.rates[r.Currency] = rate
+		}
+	}
+	return cv, nil
+}
+
+// toBase converts minor units of currency into minor units of the budget currency.
+func (cv currencyConverter) toBase(amount int64, currency string) (int64, bool) {
+	if currency == cv.base {
+		return amount, true
+	}
+	rate, ok := cv.rates[currency]
+	if !ok {
+		return 0, false
+	}
+	v := new(big.Rat).Mul(new(big.Rat).SetInt64(amount), rate)
+	shift := currencyExponent(cv.base) - currencyExponent(currency)
+	scale := new(big.Rat).SetInt(new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(absInt(shift))), nil))
+	if shift >= 0 {
+		v.Mul(v, scale)
+	} else {
+		v.Quo(v, scale)
+	}
+	return roundRat(v), true
+}
+
+func absInt(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+// organizerEvent loads the :id event and requires the caller to organize it.
+func organizerEvent(c *gin.Context, action string) (Event, bool) {
+	userID, ok := getUserIDFromContext(c)
+	if !ok {
+		jsonError(c, http.StatusUnauthorized, "unauthorized")
+		return Event{}, false
+	}
+	eventID, ok := parseEventID(c)
+	if !ok {
+		return Event{}, false
+	}
+	ev, ok := findMemberEvent(c, eventID, userID)
+	if !ok {
+		return ev, false
+	}
+	if ev.OrganizerID != userID {
+		jsonError(c, http.StatusForbidden, "only organizer can "+action)
+		return ev, false
+	}
+	return ev, true
+}
+
+// ---- exchange rates ----
+
+type BudgetRatesRequest struct {
+	BudgetCurrency string            `json:"budget_currency"` // optional, changes the report currency
+	Rates          map[string]string `json:"rates"`           // currency -> units of budget currency per unit, e.g. {"EUR": "1.08"}
+}
+
+// GET /api/events/:id/budget/rates
+func GetBudgetRates(c *gin.Context) {
+	ev, ok := organizerEvent(c, "view the budget")
+	if !ok {
+		return
+	}
+	var rates []ExchangeRate
+	if err := DB.Where("event_id = ?", ev.ID).Order("currency asc").Find(&rates).Error; err != nil {
+		jsonError(c, http.StatusInternalServerError, "db error: "+err.Error())
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"budget_currency": ev.BudgetCurrency, "rates": rates})
+}
+
+// PUT /api/events/:id/budget/rates
+// Replaces the event's rate table. Rates are relative to the (new) budget currency.
+func SetBudgetRates(c *gin.Context) {
+	ev, ok := organizerEvent(c, "edit the budget")
+	if !ok {
+		return
+	}
+
+	var body BudgetRatesRequest
+	if err := c.ShouldBindJSON(&body); err != nil {
+		jsonError(c, http.StatusBadRequest, "invalid body: "+err.Error())
+		return
+	}
+	if body.BudgetCurrency != "" {
+		code, valid := normalizeCurrency(body.BudgetCurrency)
+		if !valid {
+			jsonError(c, http.StatusBadRequest, "budget_currency must be a 3-letter ISO 4217 code")
+			return
+		}
+		ev.BudgetCurrency = code
+	}
+
+	rows := make([]ExchangeRate, 0, len(body.Rates))
+	for cur, value := range body.Rates {
+		code, valid := normalizeCurrency(cur)
+		if !valid {
+			jsonError(c, http.StatusBadRequest, "invalid currency code "+cur)
+			return
+		}
+		if code == ev.BudgetCurrency {
+			continue
+		}
+		rate, ok := new(big.Rat).SetString(strings.TrimSpace(value))
+		if !ok || rate.Sign() <= 0 {
+			jsonError(c, http.StatusBadRequest, "rate for "+code+" must be a positive decimal")
+			return
+		}
+		rows = append(rows, ExchangeRate{EventID: ev.ID, Currency: code, Rate: rate.FloatString(10)})
+	}
+
+	if err := DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&ev).Update("budget_currency", ev.BudgetCurrency).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("event_id = ?", ev.ID).Delete(&ExchangeRate{}).Error; err != nil {
+			return err
+		}
+		if len(rows) == 0 {
+			return nil
+		}
+		return tx.Create(&rows).Error
+	}); err != nil {
+		jsonError(c, http.StatusInternalServerError, "could not save rates: "+err.Error())
+		return
+	}
+	sort.Slice(rows, func(i, j int) bool { return rows[i].Currency < rows[j].Currency })
+	c.JSON(http.StatusOK, gin.H{"budget_currency": ev.BudgetCurrency, "rates": rows})
+}
+
+// ---- planned line items ----
+
+type BudgetItemRequest struct {
+	Category      *string `json:"category"`
+	Description   *string `json:"description"`
+	PlannedAmount *int64  `json:"planned_amount"` // minor units
+	Currency      *string `json:"currency"`       // defaults to the budget currency
+}
+
+func applyBudgetItem(item *BudgetItem, body BudgetItemRequest) error {
+	if body.Category != nil {
+		item.Category = strings.TrimSpace(*body.Category)
+	}
+	if body.Description != nil {
+		item.Description = strings.TrimSpace(*body.Description)
+	}
+	if body.PlannedAmount != nil {
+		item.PlannedAmount = *body.PlannedAmount
+	}
+	if body.Currency != nil {
+		code, valid := normalizeCurrency(*body.Currency)
+		if !valid {
+			return fmt.Errorf("currency must be a 3-letter ISO 4217 code")
+		}
+		item.Currency = code
+	}
+	if item.Category == "" || len(item.Category) > 64 {
+		return fmt.Errorf("category is required (max 64 characters)")
+	}
+	if item.PlannedAmount < 0 {
+		return fmt.Errorf("planned_amount cannot be negative")
+	}
+	return nil
+}
+
+func findBudgetItem(c *gin.Context, eventID uint) (BudgetItem, bool) {
+	var item BudgetItem
+	itemID, err := strconv.ParseUint(c.Param("item_id"), 10, 64)
+	if err != nil {
+		jsonError(c, http.StatusBadRequest, "invalid budget item id")
+		return item, false
+	}
+	if err := DB.Where("id = ? AND event_id = ?", itemID, eventID).First(&item).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			jsonError(c, http.StatusNotFound, "budget item not found")
+			return item, false
+		}
+		jsonError(c, http.StatusInternalServerError, "db error: "+err.Error())
+		return item, false
+	}
+	return item, true
+}
+
+// POST /api/events/:id/budget/items
+func CreateBudgetItem(c *gin.Context) {
+	ev, ok := organizerEvent(c, "edit the budget")
+	if !ok {
+		return
+	}
+	var body BudgetItemRequest
+	if err := c.ShouldBindJSON(&body); err != nil {
+		jsonError(c, http.StatusBadRequest, "invalid body: "+err.Error())
+		return
+	}
+	item := BudgetItem{EventID: ev.ID, Currency: ev.BudgetCurrency}
+	if err := applyBudgetItem(&item, body); err != nil {
+		jsonError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err := DB.Create(&item).Error; err != nil {
+		jsonError(c, http.StatusInternalServerError, "could not create budget item: "+err.Error())
+		return
+	}
+	c.JSON(http.StatusCreated, item)
+}
+
+var budgetItemSorts = map[string]sortSpec{
+	"category": {column: "budget_items.category", key: "text"},
+}
+
+// GET /api/events/:id/budget/items?limit=&cursor=
+// Returns a page envelope (see pagination.go), by category.
+func GetBudgetItems(c *gin.Context) {
+	ev, ok := organizerEvent(c, "view the budget")
+	if !ok {
+		return
+	}
+	page, ok := parsePageRequest(c, budgetItemSorts, "category")
+	if !ok {
+		return
+	}
+	items, next, total, ok := pageQuery(c, page, DB.Where("event_id = ?", ev.ID), "budget_items.id", func(item BudgetItem) (interface{}, uint) {
+		return item.Category, item.ID
+	})
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, pageEnvelope(items, next, total))
+}
+
+// PATCH /api/events/:id/budget/items/:item_id
+func UpdateBudgetItem(c *gin.Context) {
+	ev, ok := organizerEvent(c, "edit the budget")
+	if !ok {
+		return
+	}
+	item, ok := findBudgetItem(c, ev.ID)
+	if !ok {
+		return
+	}
+	var body BudgetItemRequest
+	if err := c.ShouldBindJSON(&body); err != nil {
+		jsonError(c, http.StatusBadRequest, "invalid body: "+err.Error())
+		return
+	}
+	if err := applyBudgetItem(&item, body); err != nil {
+		jsonError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err := DB.Save(&item).Error; err != nil {
+		jsonError(c, http.StatusInternalServerError, "could not update budget item: "+err.Error())
+		return
+	}
+	c.JSON(http.StatusOK, item)
+}
+
+// DELETE /api/events/:id/budget/items/:item_id
+// Expenses booked against the item are kept.
+func DeleteBudgetItem(c *gin.Context) {
+	ev, ok := organizerEvent(c, "edit the budget")
+	if !ok {
+		return
+	}
+	item, ok := findBudgetItem(c, ev.ID)
+	if !ok {
+		return
+	}
+	if err := DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&Expense{}).Where("budget_item_id = ?", item.ID).Update("budget_item_id", nil).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&item).Error
+	}); err != nil {
+		jsonError(c, http.StatusInternalServerError, "delete failed: "+err.Error())
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "budget item deleted"})
+}
+
+// ---- expenses ----
+
+type ExpenseRequest struct {
+	BudgetItemID *uint   `json:"budget_item_id"` // 0 unlinks
+	TaskID       *uint   `json:"task_id"`        // 0 unlinks
+	Category     *string `json:"category"`       // defaults to the budget item's category
+	Description  *string `json:"description"`
+	Amount       *int64  `json:"amount"`   // minor units
+	Currency     *string `json:"currency"` // defaults to the budget currency
+	PaidByID     *uint   `json:"paid_by_id"`
+	SpentAt      *string `json:"spent_at"` // RFC3339 or YYYY-MM-DD, defaults to now
+}
+
+func applyExpense(exp *Expense, body ExpenseRequest) error {
+	if body.BudgetItemID != nil {
+		if *body.BudgetItemID == 0 {
+			exp.BudgetItemID = nil
+		} else {
+			var item BudgetItem
+			if err := DB.Where("id = ? AND event_id = ?", *body.BudgetItemID, exp.EventID).First(&item).Error; err != nil {
+				return fmt.Errorf("budget_item_id is not a budget item of this event")
+			}
+			exp.BudgetItemID = &item.ID
+			if exp.Category == "" && body.Category == nil {
+				exp.Category = item.Category
+			}
+		}
+	}
+	if body.TaskID != nil {
+		if *body.TaskID == 0 {
+			exp.TaskID = nil
+		} else {
+			var count int64
+			if err := DB.Model(&Task{}).Where("id = ? AND event_id = ?", *body.TaskID, exp.EventID).Count(&count).Error; err != nil || count == 0 {
+				return fmt.Errorf("task_id is not a task of this event")
+			}
+			exp.TaskID = body.TaskID
+		}
+	}
+	if body.Category != nil {
+		exp.Category = strings.TrimSpace(*body.Category)
+	}
+	if body.Description != nil {
+		exp.Description = strings.TrimSpace(*body.Description)
+	}
+	if body.Amount != nil {
+		exp.Amount = *body.Amount
+	}
+	if body.Currency != nil {
+		code, valid := normalizeCurrency(*body.Currency)
+		if !valid {
+			return fmt.Errorf("currency must be a 3-letter ISO 4217 code")
+		}
+		exp.Currency = code
+	}
+	if body.PaidByID != nil {
+		if err := checkEventMembers(exp.EventID, []uint{*body.PaidByID}); err != nil {
+			return fmt.Errorf("paid_by_id: %v", err)
+		}
+		exp.PaidByID = *body.PaidByID
+	}
+	if body.SpentAt != nil {
+		t, err := parseDateInput(strings.TrimSpace(*body.SpentAt))
+		if err != nil {
+			return fmt.Errorf("invalid spent_at format (use RFC3339 or YYYY-MM-DD)")
+		}
+		exp.SpentAt = t
+	}
+
+	if exp.Category == "" || len(exp.Category) > 64 {
+		return fmt.Errorf("category is required (max 64 characters)")
+	}
+	if exp.Amount <= 0 {
+		return fmt.Errorf("amount must be positive")
+	}
+	return nil
+}
+
+func findExpense(c *gin.Context, eventID uint) (Expense, bool) {
+	var exp Expense
+	expenseID, err := strconv.ParseUint(c.Param("expense_id"), 10, 64)
+	if err != nil {
+		jsonError(c, http.StatusBadRequest, "invalid expense id")
+		return exp, false
+	}
+	if err := DB.Where("id = ? AND event_id = ?", expenseID, eventID).First(&exp).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			jsonError(c, http.StatusNotFound, "expense not found")
+			return exp, false
+		}
+		jsonError(c, http.StatusInternalServerError, "db error: "+err.Error())
+		return exp, false
+	}
+	return exp, true
+}
+
+// POST /api/events/:id/expenses
+func CreateExpense(c *gin.Context) {
+	ev, ok := organizerEvent(c, "record expenses")
+	if !ok {
+		return
+	}
+	var body ExpenseRequest
+	if err := c.ShouldBindJSON(&body); err != nil {
+		jsonError(c, http.StatusBadRequest, "invalid body: "+err.Error())
+		return
+	}
+	exp := Expense{EventID: ev.ID, Currency: ev.BudgetCurrency, PaidByID: ev.OrganizerID, SpentAt: time.Now()}
+	if err := applyExpense(&exp, body); err != nil {
+		jsonError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err := DB.Create(&exp).Error; err != nil {
+		jsonError(c, http.StatusInternalServerError, "could not create expense: "+err.Error())
+		return
+	}
+	c.JSON(http.StatusCreated, exp)
+}
+
+var expenseSorts = map[string]sortSpec{
+	"spent_at": {column: "expenses.spent_at", desc: true, key: "time"},
+}
+
+// GET /api/events/:id/expenses?task_id=&category=&limit=&cursor=
+// Returns a page envelope (see pagination.go), most recent first.
+func GetExpenses(c *gin.Context) {
+	ev, ok := organizerEvent(c, "view the budget")
+	if !ok {
+		return
+	}
+	query := DB.Where("event_id = ?", ev.ID)
+	if v := c.Query("task_id"); v != "" {
+		taskID, err := strconv.ParseUint(v, 10, 64)
+		if err != nil {
+			jsonError(c, http.StatusBadRequest, "invalid task_id")
+			return
+		}
+		query = query.Where("task_id = ?", taskID)
+	}
+	if v := strings.TrimSpace(c.Query("category")); v != "" {
+		query = query.Where("category = ?", v)
+	}
+	page, ok := parsePageRequest(c, expenseSorts, "spent_at")
+	if !ok {
+		return
+	}
+	list, next, total, ok := pageQuery(c, page, query, "expenses.id", func(exp Expense) (interface{}, uint) {
+		return exp.SpentAt, exp.ID
+	})
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, pageEnvelope(list, next, total))
+}
+
+// PATCH /api/events/:id/expenses/:expense_id
+func UpdateExpense(c *gin.Context) {
+	ev, ok := organizerEvent(c, "record expenses")
+	if !ok {
+		return
+	}
+	exp, ok := findExpense(c, ev.ID)
+	if !ok {
+		return
+	}
+	var body ExpenseRequest
+	if err := c.ShouldBindJSON(&body); err != nil {
+		jsonError(c, http.StatusBadRequest, "invalid body: "+err.Error())
+		return
+	}
+	if err := applyExpense(&exp, body); err != nil {
+		jsonError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err := DB.Save(&exp).Error; err != nil {
+		jsonError(c, http.StatusInternalServerError, "could not update expense: "+err.Error())
+		return
+	}
+	c.JSON(http.StatusOK, exp)
+}
+
+// DELETE /api/events/:id/expenses/:expense_id
+func DeleteExpense(c *gin.Context) {
+	ev, ok := organizerEvent(c, "record expenses")
+	if !ok {
+		return
+	}
+	exp, ok := findExpense(c, ev.ID)
+	if !ok {
+		return
+	}
+	if err := DB.Delete(&exp).Error; err != nil {
+		jsonError(c, http.StatusInternalServerError, "delete failed: "+err.Error())
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "expense deleted"})
+}
+
+// ---- reports ----
+
+type CategoryReport struct {
+	Category string `json:"category"`
+	Planned  int64  `json:"planned"`
+	Actual   int64  `json:"actual"`
+	Variance int64  `json:"variance"` // planned - actual; negative means over budget
+	Items    int    `json:"items"`
+	Expenses int    `json:"expenses"`
+}
+
+type TaskCost struct {
+	TaskID uint   `json:"task_id"`
+	Title  string `json:"title"`
+	Actual int64  `json:"actual"`
+}
+
+// GET /api/events/:id/budget/report
+// Budget vs actual per category and actual cost per task, in the budget currency.
+func GetBudgetReport(c *gin.Context) {
+	ev, ok := organizerEvent(c, "view the budget")
+	if !ok {
+		return
+	}
+	cv, err := loadConverter(ev)
+	if err != nil {
+		jsonError(c, http.StatusInternalServerError, "db error: "+err.Error())
+		return
+	}
+
+	var items []BudgetItem
+	if err := DB.Where("event_id = ?", ev.ID).Find(&items).Error; err != nil {
+		jsonError(c, http.StatusInternalServerError, "db error: "+err.Error())
+		return
+	}
+	var expenses []Expense
+	if err := DB.Where("event_id = ?", ev.ID).Find(&expenses).Error; err != nil {
+		jsonError(c, http.StatusInternalServerError, "db error: "+err.Error())
+		return
+	}
+
+	missing := make(map[string]bool)
+	categories := make(map[string]*CategoryReport)
+	category := func(name string) *CategoryReport {
+		if r, ok := categories[name]; ok {
+			return r
+		}
+		r := &CategoryReport{Category: name}
+		categories[name] = r
+		return r
+	}
+
+	var totals CategoryReport
+	for _, item := range items {
+		amount, ok := cv.toBase(item.PlannedAmount, item.Currency)
+		if !ok {
+			missing[item.Currency] = true
+			continue
+		}
+		r := category(item.Category)
+		r.Planned += amount
+		r.Items++
+		totals.Planned += amount
+	}
+	taskCosts := make(map[uint]int64)
+	for _, exp := range expenses {
+		amount, ok := cv.toBase(exp.Amount, exp.Currency)
+		if !ok {
+			missing[exp.Currency] = true
+			continue
+		}
+		r := category(exp.Category)
+		r.Actual += amount
+		r.Expenses++
+		totals.Actual += amount
+		if exp.TaskID != nil {
+			taskCosts[*exp.TaskID] += amount
+		}
+	}
+
+	report := make([]CategoryReport, 0, len(categories))
+	for _, r := range categories {
+		r.Variance = r.Planned - r.Actual
+		report = append(report, *r)
+	}
+	sort.Slice(report, func(i, j int) bool { return report[i].Category < report[j].Category })
+	totals.Variance = totals.Planned - totals.Actual
+
+	tasks := make([]TaskCost, 0, len(taskCosts))
+	if len(taskCosts) > 0 {
+		ids := make([]uint, 0, len(taskCosts))
+		for id := range taskCosts {
+			ids = append(ids, id)
+		}
+		var list []Task
+		if err := DB.Select("id", "title").Where("id IN ?", ids).Find(&list).Error; err != nil {
+			jsonError(c, http.StatusInternalServerError, "db error: "+err.Error())
+			return
+		}
+		for _, t := range list {
+			tasks = append(tasks, TaskCost{TaskID: t.ID, Title: t.Title, Actual: taskCosts[t.ID]})
+		}
+		sort.Slice(tasks, func(i, j int) bool { return tasks[i].Actual > tasks[j].Actual })
+	}
+
+	missingRates := make([]string, 0, len(missing))
+	for cur := range missing {
+		missingRates = append(missingRates, cur)
+	}
+	sort.Strings(missingRates)
+
+	c.JSON(http.StatusOK, gin.H{
+		"currency":      ev.BudgetCurrency,
+		"categories":    report,
+		"tasks":         tasks,
+		"totals":        gin.H{"planned": totals.Planned, "actual": totals.Actual, "variance": totals.Variance},
+		"missing_rates": missingRates,
+	})
+}
+
+type SplitShare struct {
+	UserID  uint   `json:"user_id"`
+	Email   string `json:"email"`
+	Name    string `json:"name"`
+	Share   int64  `json:"share"`   // this person's part of the total
+	Paid    int64  `json:"paid"`    // expenses they paid
+	Balance int64  `json:"balance"` // paid - share; negative means they owe
+}
+
+type Settlement struct {
+	FromUserID uint  `json:"from_user_id"`
+	ToUserID   uint  `json:"to_user_id"`
+	Amount     int64 `json:"amount"`
+}
+
+// GET /api/events/:id/budget/split?include_organizer=true
+// Splits actual expenses evenly among Going attendees (and the organizer
+// unless include_organizer=false). Leftover minor units go one each to the
+// first participants by user ID. Any member can see the split.
+func GetBudgetSplit(c *gin.Context) {
+	userID, ok := getUserIDFromContext(c)
+	if !ok {
+		jsonError(c, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	eventID, ok := parseEventID(c)
+	if !ok {
+		return
+	}
+	ev, ok := findMemberEvent(c, eventID, userID)
+	if !ok {
+		return
+	}
+
+	cv, err := loadConverter(ev)
+	if err != nil {
+		jsonError(c, http.StatusInternalServerError, "db error: "+err.Error())
+		return
+	}
+	var expenses []Expense
+	if err := DB.Where("event_id = ?", ev.ID).Find(&expenses).Error; err != nil {
+		jsonError(c, http.StatusInternalServerError, "db error: "+err.Error())
+		return
+	}
+
+	var participants []uint
+	if err := DB.Model(&EventAttendee{}).Where("event_id = ? AND status = ?", ev.ID, "Going").
+		Pluck("user_id", &participants).Error; err != nil {
+		jsonError(c, http.StatusInternalServerError, "db error: "+err.Error())
+		return
+	}
+	if c.DefaultQuery("include_organizer", "true") != "false" && !containsID(participants, ev.OrganizerID) {
+		participants = append(participants, ev.OrganizerID)
+	}
+	sort.Slice(participants, func(i, j int) bool { return participants[i] < participants[j] })
+
+	var total int64
+	paid := make(map[uint]int64)
+	missing := make(map[string]bool)
+	for _, exp := range expenses {
+		amount, ok := cv.toBase(exp.Amount, exp.Currency)
+		if !ok {
+			missing[exp.Currency] = true
+			continue
+		}
+		total += amount
+		paid[exp.PaidByID] += amount
+	}
+
+	// Payers who are not participating still get reimbursed
+	people := append([]uint(nil), participants...)
+	for payer := range paid {
+		if !containsID(people, payer) {
+			people = append(people, payer)
+		}
+	}
+	var users []User
+	if len(people) > 0 {
+		if err := DB.Where("id IN ?", people).Find(&users).Error; err != nil {
+			jsonError(c, http.StatusInternalServerError, "db error: "+err.Error())
+			return
+		}
+	}
+	byID := make(map[uint]User, len(users))
+	for _, u := range users {
+		byID[u.ID] = u
+	}
+
+	shares := make([]SplitShare, 0, len(people))
+	n := int64(len(participants))
+	for i, uid := range people {
+		s := SplitShare{UserID: uid, Email: byID[uid].Email, Name: byID[uid].Name, Paid: paid[uid]}
+		if i < len(participants) {
+			s.Share = total / n
+			if int64(i) < total%n {
+				s.Share++
+			}
+		}
+		s.Balance = s.Paid - s.Share
+		shares = append(shares, s)
+	}
+
+	missingRates := make([]string, 0, len(missing))
+	for cur := range missing {
+		missingRates = append(missingRates, cur)
+	}
+	sort.Strings(missingRates)
+
+	c.JSON(http.StatusOK, gin.H{
+		"currency":      ev.BudgetCurrency,
+		"total":         total,
+		"participants":  len(participants),
+		"shares":        shares,
+		"settlements":   settleBalances(shares),
+		"missing_rates": missingRates,
+	})
+}
+
+// settleBalances pairs debtors with creditors, largest amounts first.
+func settleBalances(shares []SplitShare) []Settlement {
+	var debtors, creditors []SplitShare
+	for _, s := range shares {
+		if s.Balance < 0 {
+			debtors = append(debtors, s)
+		} else if s.Balance > 0 {
+			creditors = append(creditors, s)
+		}
+	}
+	sort.Slice(debtors, func(i, j int) bool { return debtors[i].Balance < debtors[j].Balance })
+	sort.Slice(creditors, func(i, j int) bool { return creditors[i].Balance > creditors[j].Balance })
+
+	out := make([]Settlement, 0)
+	for i, j := 0, 0; i < len(debtors) && j < len(creditors); {
+		amount := -debtors[i].Balance
+		if creditors[j].Balance < amount {
+			amount = creditors[j].Balance
+		}
+		out = append(out, Settlement{FromUserID: debtors[i].UserID, ToUserID: creditors[j].UserID, Amount: amount})
+		debtors[i].Balance += amount
+		creditors[j].Balance -= amount
+		if debtors[i].Balance == 0 {
+			i++
+		}
+		if creditors[j].Balance == 0 {
+			j++
+		}
+	}
+	return out
+}
+
+func containsID(ids []uint, id uint) bool {
+	for _, v := range ids {
+		if v == id {
+			return true
+		}
+	}
+	return false
+}
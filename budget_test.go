@@ -0,0 +1,159 @@
+package main
+
+import (
+	"fmt"
+	"math/big"
+	"net/http"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestCurrencyConverterToBase(t *testing.T) {
+	rat := func(s string) *big.Rat {
+		r, _ := new(big.Rat).SetString(s)
+		return r
+	}
+	cv := currencyConverter{base: "USD", rates: map[string]*big.Rat{
+		"EUR": rat("1.10"),
+		"JPY": rat("0.0067"),
+		"KWD": rat("3.25"),
+		"GBP": rat("0.5"),
+	}}
+	cases := []struct {
+		amount   int64
+		currency string
+		want     int64
+	}{
+		{12345, "USD", 12345},
+		{30000, "EUR", 33000},
+		{1000, "JPY", 670}, // yen have no minor unit
+		{1234, "KWD", 401}, // fils are thousandths: 401.05 cents
+		{3, "GBP", 2},      // half away from zero
+		{-3, "GBP", -2},
+		{1, "GBP", 1},
+	}
+	for _, tc := range cases {
+		got, ok := cv.toBase(tc.amount, tc.currency)
+		if !ok || got != tc.want {
+			t.Errorf("toBase(%d, %s) = %d, %v; want %d", tc.amount, tc.currency, got, ok, tc.want)
+		}
+	}
+	if _, ok := cv.toBase(100, "CHF"); ok {
+		t.Error("converted a currency without a rate")
+	}
+}
+
+func TestSettleBalances(t *testing.T) {
+	shares := []SplitShare{
+		{UserID: 1, Balance: -30},
+		{UserID: 2, Balance: 25},
+		{UserID: 3, Balance: -10},
+		{UserID: 4, Balance: 15},
+		{UserID: 5, Balance: 0},
+	}
+	got := fmt.Sprint(settleBalances(shares))
+	if want := "[{1 2 25} {1 4 5} {3 4 10}]"; got != want {
+		t.Errorf("settlements = %s, want %s", got, want)
+	}
+	if got := settleBalances(nil); got == nil || len(got) != 0 {
+		t.Errorf("no shares: %v, want an empty list", got)
+	}
+}
+
+func TestBudgetReportAndSplit(t *testing.T) {
+	f := newAuthFixture(t)
+	r := testRouter()
+	base := fmt.Sprintf("/api/events/%d", f.Event.ID)
+	if err := DB.Model(&EventAttendee{}).Where("event_id = ? AND user_id = ?", f.Event.ID, f.Member.ID).Update("status", "Going").Error; err != nil {
+		t.Fatal(err)
+	}
+
+	post := func(path string, body gin.H, want int) {
+		t.Helper()
+		if w := apiRequest(t, r, f.Organizer.ID, http.MethodPost, base+path, body); w.Code != want {
+			t.Fatalf("POST %s %v: status %d: %s", path, body, w.Code, w.Body.String())
+		}
+	}
+	if w := apiRequest(t, r, f.Organizer.ID, http.MethodPut, base+"/budget/rates", gin.H{"rates": gin.H{"eur": "1.10"}}); w.Code != http.StatusOK {
+		t.Fatalf("set rates: status %d: %s", w.Code, w.Body.String())
+	}
+	if w := apiRequest(t, r, f.Organizer.ID, http.MethodPut, base+"/budget/rates", gin.H{"rates": gin.H{"EUR": "-1"}}); w.Code != http.StatusBadRequest {
+		t.Errorf("negative rate: status %d, want 400", w.Code)
+	}
+	post("/budget/items", gin.H{"category": "Venue", "planned_amount": 50000}, http.StatusCreated)
+	post("/budget/items", gin.H{"category": "Catering", "planned_amount": 30000, "currency": "eur"}, http.StatusCreated)
+	post("/budget/items", gin.H{"category": "", "planned_amount": 100}, http.StatusBadRequest)
+	post("/expenses", gin.H{"category": "Venue", "amount": 52000, "task_id": f.Task.ID}, http.StatusCreated)
+	post("/expenses", gin.H{"category": "Catering", "amount": 10000, "currency": "EUR", "paid_by_id": f.Member.ID}, http.StatusCreated)
+	post("/expenses", gin.H{"category": "Gifts", "amount": 5000, "currency": "JPY"}, http.StatusCreated)
+	post("/expenses", gin.H{"category": "Venue", "amount": 0}, http.StatusBadRequest)
+	post("/expenses", gin.H{"category": "Venue", "amount": 100, "paid_by_id": f.Outsider.ID}, http.StatusBadRequest)
+
+	if w := apiRequest(t, r, f.Member.ID, http.MethodGet, base+"/budget/report", nil); w.Code != http.StatusForbidden {
+		t.Errorf("member report: status %d, want 403", w.Code)
+	}
+	var report struct {
+		Currency   string           `json:"currency"`
+		Categories []CategoryReport `json:"categories"`
+		Tasks      []TaskCost       `json:"tasks"`
+		Totals     struct {
+			Planned, Actual, Variance int64
+		} `json:"totals"`
+		MissingRates []string `json:"missing_rates"`
+	}
+	w := apiRequest(t, r, f.Organizer.ID, http.MethodGet, base+"/budget/report", nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("report: status %d: %s", w.Code, w.Body.String())
+	}
+	decodeJSON(t, w, &report)
+	want := []CategoryReport{
+		{Category: "Catering", Planned: 33000, Actual: 11000, Variance: 22000, Items: 1, Expenses: 1},
+		{Category: "Venue", Planned: 50000, Actual: 52000, Variance: -2000, Items: 1, Expenses: 1},
+	}
+	if got := fmt.Sprint(report.Categories); got != fmt.Sprint(want) {
+		t.Errorf("categories = %s, want %s", got, fmt.Sprint(want))
+	}
+	if report.Totals.Planned != 83000 || report.Totals.Actual != 63000 || report.Totals.Variance != 20000 {
+		t.Errorf("totals = %+v", report.Totals)
+	}
+	if len(report.Tasks) != 1 || report.Tasks[0].TaskID != f.Task.ID || report.Tasks[0].Actual != 52000 {
+		t.Errorf("task costs = %+v", report.Tasks)
+	}
+	if fmt.Sprint(report.MissingRates) != "[JPY]" {
+		t.Errorf("missing rates = %v", report.MissingRates)
+	}
+
+	// The organizer and the Going member share the converted total
+	var split struct {
+		Total        int64        `json:"total"`
+		Participants int          `json:"participants"`
+		Shares       []SplitShare `json:"shares"`
+		Settlements  []Settlement `json:"settlements"`
+	}
+	w = apiRequest(t, r, f.Member.ID, http.MethodGet, base+"/budget/split", nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("split: status %d: %s", w.Code, w.Body.String())
+	}
+	decodeJSON(t, w, &split)
+	if split.Total != 63000 || split.Participants != 2 {
+		t.Fatalf("split total %d among %d", split.Total, split.Participants)
+	}
+	for _, s := range split.Shares {
+		if s.Share != 31500 {
+			t.Errorf("user %d: share %d, want 31500", s.UserID, s.Share)
+		}
+	}
+	if len(split.Settlements) != 1 || split.Settlements[0] != (Settlement{FromUserID: f.Member.ID, ToUserID: f.Organizer.ID, Amount: 20500}) {
+		t.Errorf("settlements = %+v", split.Settlements)
+	}
+
+	// Without the organizer the member carries everything but is owed back what they paid
+	decodeJSON(t, apiRequest(t, r, f.Member.ID, http.MethodGet, base+"/budget/split?include_organizer=false", nil), &split)
+	if split.Participants != 1 || len(split.Settlements) != 1 || split.Settlements[0].Amount != 52000 {
+		t.Errorf("split without organizer = %+v", split)
+	}
+	if w := apiRequest(t, r, f.Outsider.ID, http.MethodGet, base+"/budget/split", nil); w.Code != http.StatusNotFound {
+		t.Errorf("outsider split: status %d, want 404", w.Code)
+	}
+}
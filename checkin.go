@@ -0,0 +1,322 @@
+package main
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// -----------------------------
+// Check-in
+// -----------------------------
+//
+// Every Going attendee gets a ticket: "<attendee id>.<expires>.<signature>",
+// where expires is the event end as a Unix timestamp and the signature is an
+// HMAC over the attendee ID, event ID and expiry, keyed with CHECKIN_SECRET.
+// The ticket is shown as a QR code and scanned at the door by the organizer or
+// a helper; once the event has ended it is refused.
+
+func checkinSignature(attendeeID, eventID uint, expires int64) string {
+	mac := hmac.New(sha256.New, []byte(os.Getenv("CHECKIN_SECRET")))
+	fmt.Fprintf(mac, "%d:%d:%d", attendeeID, eventID, expires)
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func checkinToken(att EventAttendee, ev Event) string {
+	expires := eventEnd(ev).Unix()
+	return fmt.Sprintf("%d.%d.%s", att.ID, expires, checkinSignature(att.ID, att.EventID, expires))
+}
+
+// parseCheckinToken verifies a ticket for eventID and returns the attendee ID.
+// Tickets past their expiry are invalid.
+func parseCheckinToken(token string, eventID uint, now time.Time) (uint, bool) {
+	parts := strings.SplitN(strings.TrimSpace(token), ".", 3)
+	if len(parts) != 3 {
+		return 0, false
+	}
+	id64, err := strconv.ParseUint(parts[0], 10, 64)
+	if err != nil {
+		return 0, false
+	}
+	expires, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil {
+		return 0, false
+	}
+	expected := checkinSignature(uint(id64), eventID, expires)
+	if !hmac.Equal([]byte(parts[2]), []byte(expected)) {
+		return 0, false
+	}
+	if now.Unix() > expires {
+		return 0, false
+	}
+	return uint(id64), true
+}
+
+// canCheckIn reports whether userID may scan tickets for the event.
+func canCheckIn(ev Event, userID uint) (bool, error) {
+	if ev.OrganizerID == userID {
+		return true, nil
+	}
+	var count int64
+	err := DB.Model(&EventAttendee{}).
+		Where("event_id = ? AND user_id = ? AND is_helper = ?", ev.ID, userID, true).
+		Count(&count).Error
+	return count > 0, err
+}
+
+// GET /api/events/:id/checkin/qr?user_id=
+// Returns the caller's ticket as a PNG QR code. The organizer can fetch any
+// attendee's ticket with user_id.
+func GetCheckinQR(c *gin.Context) {
+	userID, ok := getUserIDFromContext(c)
+	if !ok {
+		jsonError(c, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	eventID, ok := parseEventID(c)
+	if !ok {
+		return
+	}
+	ev, ok := findMemberEvent(c, eventID, userID)
+	if !ok {
+		return
+	}
+
+	targetID := userID
+	if uid := c.Query("user_id"); uid != "" {
+		id64, err := strconv.ParseUint(uid, 10, 64)
+		if err != nil {
+			jsonError(c, http.StatusBadRequest, "invalid user_id")
+			return
+		}
+		if uint(id64) != userID && ev.OrganizerID != userID {
+			jsonError(c, http.StatusForbidden, "only organizer can fetch other attendees' tickets")
+			return
+		}
+		targetID = uint(id64)
+	}
+
+	var att EventAttendee
+	if err := DB.Where("event_id = ? AND user_id = ?", eventID, targetID).First(&att).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			jsonError(c, http.StatusNotFound, "attendee not found")
+			return
+		}
+		jsonError(c, http.StatusInternalServerError, "db error: "+err.Error())
+		return
+	}
+	if att.Status != "Going" {
+		jsonError(c, http.StatusBadRequest, "tickets are only issued to attendees who are Going")
+		return
+	}
+
+	qr, err := EncodeQR([]byte(checkinToken(att, ev)))
+	if err != nil {
+		jsonError(c, http.StatusInternalServerError, "could not encode ticket: "+err.Error())
+		return
+	}
+	var buf bytes.Buffer
+	if err := qr.WritePNG(&buf, 8); err != nil {
+		jsonError(c, http.StatusInternalServerError, "could not render ticket: "+err.Error())
+		return
+	}
+
+	c.Header("Cache-Control", "no-store")
+	c.Data(http.StatusOK, "image/png", buf.Bytes())
+}
+
+type CheckinRequest struct {
+	Token string `json:"token" binding:"required"` // decoded QR content
+}
+
+// POST /api/events/:id/checkin
+// Scanning the same ticket twice is not an error; the response carries a
+// warning and the original check-in time instead.
+func CheckIn(c *gin.Context) {
+	userID, ok := getUserIDFromContext(c)
+	if !ok {
+		jsonError(c, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	eventID, ok := parseEventID(c)
+	if !ok {
+		return
+	}
+	ev, ok := findMemberEvent(c, eventID, userID)
+	if !ok {
+		return
+	}
+	allowed, err := canCheckIn(ev, userID)
+	if err != nil {
+		jsonError(c, http.StatusInternalServerError, "db error: "+err.Error())
+		return
+	}
+	if !allowed {
+		jsonError(c, http.StatusForbidden, "only organizer or helpers can check in attendees")
+		return
+	}
+
+	var body CheckinRequest
+	if err := c.ShouldBindJSON(&body); err != nil {
+		jsonError(c, http.StatusBadRequest, "invalid body: "+err.Error())
+		return
+	}
+
+	attendeeID, valid := parseCheckinToken(body.Token, eventID, time.Now())
+	if !valid {
+		jsonError(c, http.StatusBadRequest, "invalid or expired ticket")
+		return
+	}
+
+	var att EventAttendee
+	if err := DB.Where("id = ? AND event_id = ?", attendeeID, eventID).First(&att).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			jsonError(c, http.StatusNotFound, "attendee not found")
+			return
+		}
+		jsonError(c, http.StatusInternalServerError, "db error: "+err.Error())
+		return
+	}
+
+	// Only the first scan sets CheckedInAt, even with several helpers scanning at once
+	now := time.Now()
+	res := DB.Model(&EventAttendee{}).
+		Where("id = ? AND checked_in_at IS NULL", att.ID).
+		Updates(map[string]interface{}{"checked_in_at": now, "checked_in_by_id": userID})
+	if res.Error != nil {
+		jsonError(c, http.StatusInternalServerError, "could not check in: "+res.Error.Error())
+		return
+	}
+	if err := DB.First(&att, att.ID).Error; err != nil {
+		jsonError(c, http.StatusInternalServerError, "db error: "+err.Error())
+		return
+	}
+
+	resp := gin.H{"message": "checked in", "attendee": att}
+	warnings := make([]string, 0)
+	if res.RowsAffected == 0 {
+		resp["message"] = "already checked in"
+		warnings = append(warnings, "duplicate scan: attendee was already checked in at "+att.CheckedInAt.UTC().Format(time.RFC3339))
+	}
+	if att.Status != "Going" {
+		warnings = append(warnings, "attendee's RSVP status is '"+att.Status+"', not Going")
+	}
+	if len(warnings) > 0 {
+		resp["warnings"] = warnings
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// GET /api/events/:id/checkin/stats
+func GetCheckinStats(c *gin.Context) {
+	userID, ok := getUserIDFromContext(c)
+	if !ok {
+		jsonError(c, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	eventID, ok := parseEventID(c)
+	if !ok {
+		return
+	}
+	ev, ok := findMemberEvent(c, eventID, userID)
+	if !ok {
+		return
+	}
+	allowed, err := canCheckIn(ev, userID)
+	if err != nil {
+		jsonError(c, http.StatusInternalServerError, "db error: "+err.Error())
+		return
+	}
+	if !allowed {
+		jsonError(c, http.StatusForbidden, "only organizer or helpers can view check-in stats")
+		return
+	}
+
+	var stats struct {
+		Expected        int64      `json:"expected"`         // attendees who are Going
+		CheckedIn       int64      `json:"checked_in"`       // everyone scanned so far
+		CheckedInGoing  int64      `json:"checked_in_going"` // scanned and Going
+		WalkIns         int64      `json:"walk_ins"`         // scanned without a Going RSVP
+		StillExpected   int64      `json:"still_expected"`   // Going but not yet scanned
+		LastCheckedInAt *time.Time `json:"last_checked_in_at"`
+	}
+
+	if err := DB.Model(&EventAttendee{}).
+		Select(`COUNT(*) FILTER (WHERE status = 'Going') AS expected,
+			COUNT(*) FILTER (WHERE checked_in_at IS NOT NULL) AS checked_in,
+			COUNT(*) FILTER (WHERE checked_in_at IS NOT NULL AND status = 'Going') AS checked_in_going,
+			MAX(checked_in_at) AS last_checked_in_at`).
+		Where("event_id = ?", eventID).
+		Scan(&stats).Error; err != nil {
+		jsonError(c, http.StatusInternalServerError, "db error: "+err.Error())
+		return
+	}
+	stats.WalkIns = stats.CheckedIn - stats.CheckedInGoing
+	stats.StillExpected = stats.Expected - stats.CheckedInGoing
+
+	c.JSON(http.StatusOK, stats)
+}
+
+type HelperRequest struct {
+	UserID uint `json:"user_id" binding:"required"`
+	Helper bool `json:"helper"`
+}
+
+// PUT /api/events/:id/helpers
+// Grants or revokes check-in rights for an existing attendee.
+func SetCheckinHelper(c *gin.Context) {
+	userID, ok := getUserIDFromContext(c)
+	if !ok {
+		jsonError(c, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	eventID, ok := parseEventID(c)
+	if !ok {
+		return
+	}
+	ev, ok := findMemberEvent(c, eventID, userID)
+	if !ok {
+		return
+	}
+	if ev.OrganizerID != userID {
+		jsonError(c, http.StatusForbidden, "only organizer can manage helpers")
+		return
+	}
+
+	var body HelperRequest
+	if err := c.ShouldBindJSON(&body); err != nil {
+		jsonError(c, http.StatusBadRequest, "invalid body: "+err.Error())
+		return
+	}
+
+	var att EventAttendee
+	if err := DB.Where("event_id = ? AND user_id = ?", eventID, body.UserID).First(&att).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			jsonError(c, http.StatusNotFound, "user is not part of this event")
+			return
+		}
+		jsonError(c, http.StatusInternalServerError, "db error: "+err.Error())
+		return
+	}
+
+	att.IsHelper = body.Helper
+	if err := DB.Model(&att).Update("is_helper", body.Helper).Error; err != nil {
+		jsonError(c, http.StatusInternalServerError, "could not update helper: "+err.Error())
+		return
+	}
+	c.JSON(http.StatusOK, att)
+}
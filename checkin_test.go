@@ -0,0 +1,56 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCheckinTokenRoundTrip(t *testing.T) {
+	t.Setenv("CHECKIN_SECRET", "checkin-test-secret")
+
+	start := time.Date(2026, 6, 1, 18, 0, 0, 0, time.UTC)
+	ends := start.Add(5 * time.Hour)
+	ev := Event{ID: 7, Date: start, EndsAt: &ends}
+	att := EventAttendee{ID: 42, EventID: ev.ID}
+	token := checkinToken(att, ev)
+
+	if id, ok := parseCheckinToken(token, ev.ID, start); !ok || id != att.ID {
+		t.Fatalf("parseCheckinToken(%q) = %d, %v; want %d, true", token, id, ok, att.ID)
+	}
+	if _, ok := parseCheckinToken(token, ev.ID, ends); !ok {
+		t.Error("ticket refused at the event end")
+	}
+	if _, ok := parseCheckinToken(token, ev.ID, ends.Add(time.Second)); ok {
+		t.Error("ticket accepted after the event ended")
+	}
+	if _, ok := parseCheckinToken(token, ev.ID+1, start); ok {
+		t.Error("ticket accepted for another event")
+	}
+
+	parts := strings.Split(token, ".")
+	later := strings.Join([]string{parts[0], "4102444800", parts[2]}, ".")
+	if _, ok := parseCheckinToken(later, ev.ID, ends.Add(time.Hour)); ok {
+		t.Error("ticket accepted with an extended expiry")
+	}
+	other := strings.Join([]string{"43", parts[1], parts[2]}, ".")
+	if _, ok := parseCheckinToken(other, ev.ID, start); ok {
+		t.Error("signature accepted for another attendee")
+	}
+
+	// Without EndsAt, tickets last as long as the event is assumed to
+	open := Event{ID: 8, Date: start}
+	token = checkinToken(EventAttendee{ID: 1, EventID: open.ID}, open)
+	if _, ok := parseCheckinToken(token, open.ID, start.Add(defaultEventDuration)); !ok {
+		t.Error("ticket refused before the default event end")
+	}
+	if _, ok := parseCheckinToken(token, open.ID, start.Add(defaultEventDuration+time.Second)); ok {
+		t.Error("ticket accepted after the default event end")
+	}
+
+	// Rotating the key invalidates issued tickets
+	t.Setenv("CHECKIN_SECRET", "rotated")
+	if _, ok := parseCheckinToken(token, open.ID, start); ok {
+		t.Error("ticket accepted under a different key")
+	}
+}
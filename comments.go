@@ -0,0 +1,428 @@
+package main
+
+import (
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// -----------------------------
+// Comments
+// -----------------------------
+//
+// Event and task discussions. Only event members (organizer or anyone with an
+// EventAttendee row) can read or write; others get the same 404 as for a
+// missing event.
+
+const maxCommentLength = 5000
+
+// mentionPattern matches "@ana", "@ana.lopez" or "@ana@example.com"
+var mentionPattern = regexp.MustCompile(`(?:^|[^\w@])@([\w.+\-]+(?:@[\w\-]+(?:\.[\w\-]+)+)?)`)
+
+// resolveMentions maps @tokens in body to event members. A token matches a
+// member's email, the part of the email before the @, or the member's name
+// with spaces removed (case-insensitive). Ambiguous short forms are skipped.
+func resolveMentions(eventID uint, body string) ([]uint, []string, error) {
+	matches := mentionPattern.FindAllStringSubmatch(body, -1)
+	if len(matches) == 0 {
+		return nil, nil, nil
+	}
+
+	var ev Event
+	if err := DB.Select("id", "organizer_id").First(&ev, eventID).Error; err != nil {
+		return nil, nil, err
+	}
+	var memberIDs []uint
+	if err := DB.Model(&EventAttendee{}).Where("event_id = ?", eventID).Pluck("user_id", &memberIDs).Error; err != nil {
+		return nil, nil, err
+	}
+	memberIDs = append(memberIDs, ev.OrganizerID)
+	var members []User
+	if err := DB.Where("id IN ?", memberIDs).Find(&members).Error; err != nil {
+		return nil, nil, err
+	}
+
+	exact := make(map[string]uint)
+	short := make(map[string]uint)
+	ambiguous := make(map[string]bool)
+	addShort := func(key string, id uint) {
+		if key == "" {
+			return
+		}
+		if prev, ok := short[key]; ok && prev != id {
+			ambiguous[key] = true
+		}
+		short[key] = id
+	}
+	for _, u := range members {
+		email := strings.ToLower(u.Email)
+		exact[email] = u.ID
+		if at := strings.Index(email, "@"); at > 0 {
+			addShort(email[:at], u.ID)
+		}
+		addShort(strings.ToLower(strings.Join(strings.Fields(u.Name), "")), u.ID)
+	}
+
+	ids := make([]uint, 0, len(matches))
+	seen := make(map[uint]bool)
+	unresolved := make([]string, 0)
+	for _, m := range matches {
+		token := strings.ToLower(strings.TrimRight(m[1], ".-"))
+		id, ok := exact[token]
+		if !ok && !ambiguous[token] {
+			id, ok = short[token]
+		}
+		if !ok {
+			unresolved = append(unresolved, m[1])
+			continue
+		}
+		if !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+	return ids, unresolved, nil
+}
+
+// replaceMentions stores the mentioned users of a comment.
+func replaceMentions(tx *gorm.DB, commentID uint, userIDs []uint) error {
+	if err := tx.Where("comment_id = ?", commentID).Delete(&CommentMention{}).Error; err != nil {
+		return err
+	}
+	for _, uid := range userIDs {
+		if err := tx.Create(&CommentMention{CommentID: commentID, UserID: uid}).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// deleteComments removes the comments matched by query together with their mentions.
+func deleteComments(tx *gorm.DB, query string, args ...interface{}) error {
+	if err := tx.Where("comment_id IN (?)", tx.Model(&Comment{}).Select("id").Where(query, args...)).
+		Delete(&CommentMention{}).Error; err != nil {
+		return err
+	}
+	return tx.Where(query, args...).Delete(&Comment{}).Error
+}
+
+// findComment loads the :comment_id path param comment of an event.
+func findComment(c *gin.Context, eventID uint) (Comment, bool) {
+	var comment Comment
+	commentID, err := strconv.ParseUint(c.Param("comment_id"), 10, 64)
+	if err != nil {
+		jsonError(c, http.StatusBadRequest, "invalid comment id")
+		return comment, false
+	}
+	if err := DB.Where("id = ? AND event_id = ?", commentID, eventID).First(&comment).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			jsonError(c, http.StatusNotFound, "comment not found")
+			return comment, false
+		}
+		jsonError(c, http.StatusInternalServerError, "db error: "+err.Error())
+		return comment, false
+	}
+	return comment, true
+}
+
+type CommentThread struct {
+	Comment
+	Replies []*CommentThread `json:"replies"`
+}
+
+// commentSorts: threads are listed oldest first.
+var commentSorts = map[string]sortSpec{
+	"created_at": {column: "comments.created_at", key: "time"},
+}
+
+// GET /api/events/:id/comments?task_id=&limit=&cursor=
+// Returns the discussion as a tree, oldest first. Without task_id only
+// comments on the event itself are returned. Pages are made of top-level
+// comments, each with all of its replies (see pagination.go).
+func GetComments(c *gin.Context) {
+	userID, ok := getUserIDFromContext(c)
+	if !ok {
+		jsonError(c, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	eventID, ok := parseEventID(c)
+	if !ok {
+		return
+	}
+	if _, ok := findMemberEvent(c, eventID, userID); !ok {
+		return
+	}
+
+	query := DB.Preload("Mentions").Where("event_id = ?", eventID)
+	if v := c.Query("task_id"); v != "" {
+		taskID, err := strconv.ParseUint(v, 10, 64)
+		if err != nil {
+			jsonError(c, http.StatusBadRequest, "invalid task_id")
+			return
+		}
+		query = query.Where("task_id = ?", taskID)
+	} else {
+		query = query.Where("task_id IS NULL")
+	}
+
+	page, ok := parsePageRequest(c, commentSorts, "created_at")
+	if !ok {
+		return
+	}
+	top, next, total, ok := pageQuery(c, page, query.Where("parent_id IS NULL"), "comments.id", func(cm Comment) (interface{}, uint) {
+		return cm.CreatedAt, cm.ID
+	})
+	if !ok {
+		return
+	}
+
+	rootIDs := make([]uint, 0, len(top))
+	for _, cm := range top {
+		rootIDs = append(rootIDs, cm.ID)
+	}
+	var replies []Comment
+	if len(rootIDs) > 0 {
+		if err := DB.Preload("Mentions").
+			Where("id IN (WITH RECURSIVE thread AS ("+
+				"SELECT id FROM comments WHERE parent_id IN ? "+
+				"UNION ALL SELECT r.id FROM comments r JOIN thread ON r.parent_id = thread.id"+
+				") SELECT id FROM thread)", rootIDs).
+			Order("created_at asc, id asc").
+			Find(&replies).Error; err != nil {
+			jsonError(c, http.StatusInternalServerError, "db error: "+err.Error())
+			return
+		}
+	}
+
+	roots := make([]*CommentThread, 0, len(top))
+	nodes := make(map[uint]*CommentThread, len(top)+len(replies))
+	for _, cm := range top {
+		node := &CommentThread{Comment: cm, Replies: []*CommentThread{}}
+		nodes[cm.ID] = node
+		roots = append(roots, node)
+	}
+	for _, cm := range replies {
+		nodes[cm.ID] = &CommentThread{Comment: cm, Replies: []*CommentThread{}}
+	}
+	for _, cm := range replies {
+		if parent, ok := nodes[*cm.ParentID]; ok {
+			parent.Replies = append(parent.Replies, nodes[cm.ID])
+		}
+	}
+	c.JSON(http.StatusOK, pageEnvelope(roots, next, total))
+}
+
+type CommentRequest struct {
+	Body     string `json:"body" binding:"required"`
+	TaskID   *uint  `json:"task_id"`   // comment on a task instead of the event
+	ParentID *uint  `json:"parent_id"` // reply to a comment in the same discussion
+}
+
+// POST /api/events/:id/comments
+func CreateComment(c *gin.Context) {
+	userID, ok := getUserIDFromContext(c)
+	if !ok {
+		jsonError(c, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	eventID, ok := parseEventID(c)
+	if !ok {
+		return
+	}
+	if _, ok := findMemberEvent(c, eventID, userID); !ok {
+		return
+	}
+
+	var body CommentRequest
+	if err := c.ShouldBindJSON(&body); err != nil {
+		jsonError(c, http.StatusBadRequest, "invalid body: "+err.Error())
+		return
+	}
+	text := strings.TrimSpace(body.Body)
+	if text == "" || len(text) > maxCommentLength {
+		jsonError(c, http.StatusBadRequest, "body must be between 1 and 5000 characters")
+		return
+	}
+
+	comment := Comment{EventID: eventID, AuthorID: userID, Body: text}
+	if body.TaskID != nil {
+		var count int64
+		if err := DB.Model(&Task{}).Where("id = ? AND event_id = ?", *body.TaskID, eventID).Count(&count).Error; err != nil {
+			jsonError(c, http.StatusInternalServerError, "db error: "+err.Error())
+			return
+		}
+		if count == 0 {
+			jsonError(c, http.StatusBadRequest, "task_id is not a task of this event")
+			return
+		}
+		comment.TaskID = body.TaskID
+	}
+	if body.ParentID != nil {
+		var parent Comment
+		if err := DB.Where("id = ? AND event_id = ?", *body.ParentID, eventID).First(&parent).Error; err != nil {
+			if err == gorm.ErrRecordNotFound {
+				jsonError(c, http.StatusBadRequest, "parent comment not found")
+				return
+			}
+			jsonError(c, http.StatusInternalServerError, "db error: "+err.Error())
+			return
+		}
+		if (parent.TaskID == nil) != (comment.TaskID == nil) || (parent.TaskID != nil && *parent.TaskID != *comment.TaskID) {
+			jsonError(c, http.StatusBadRequest, "replies must stay in the parent comment's discussion")
+			return
+		}
+		comment.ParentID = &parent.ID
+	}
+
+	mentioned, unresolved, err := resolveMentions(eventID, text)
+	if err != nil {
+		jsonError(c, http.StatusInternalServerError, "db error: "+err.Error())
+		return
+	}
+
+	if err := DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&comment).Error; err != nil {
+			return err
+		}
+		return replaceMentions(tx, comment.ID, mentioned)
+	}); err != nil {
+		jsonError(c, http.StatusInternalServerError, "could not create comment: "+err.Error())
+		return
+	}
+	if err := DB.Where("comment_id = ?", comment.ID).Find(&comment.Mentions).Error; err != nil {
+		jsonError(c, http.StatusInternalServerError, "db error: "+err.Error())
+		return
+	}
+
+	resp := gin.H{"comment": comment}
+	if len(unresolved) > 0 {
+		resp["unresolved_mentions"] = unresolved
+	}
+	c.JSON(http.StatusCreated, resp)
+}
+
+type UpdateCommentRequest struct {
+	Body string `json:"body" binding:"required"`
+}
+
+// PATCH /api/events/:id/comments/:comment_id
+// Only the author can edit; mentions are resolved again.
+func UpdateComment(c *gin.Context) {
+	userID, ok := getUserIDFromContext(c)
+	if !ok {
+		jsonError(c, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	eventID, ok := parseEventID(c)
+	if !ok {
+		return
+	}
+	if _, ok := findMemberEvent(c, eventID, userID); !ok {
+		return
+	}
+	comment, ok := findComment(c, eventID)
+	if !ok {
+		return
+	}
+	if comment.AuthorID != userID {
+		jsonError(c, http.StatusForbidden, "only the author can edit a comment")
+		return
+	}
+	if comment.DeletedAt != nil {
+		jsonError(c, http.StatusConflict, "comment was deleted")
+		return
+	}
+
+	var body UpdateCommentRequest
+	if err := c.ShouldBindJSON(&body); err != nil {
+		jsonError(c, http.StatusBadRequest, "invalid body: "+err.Error())
+		return
+	}
+	text := strings.TrimSpace(body.Body)
+	if text == "" || len(text) > maxCommentLength {
+		jsonError(c, http.StatusBadRequest, "body must be between 1 and 5000 characters")
+		return
+	}
+
+	mentioned, unresolved, err := resolveMentions(eventID, text)
+	if err != nil {
+		jsonError(c, http.StatusInternalServerError, "db error: "+err.Error())
+		return
+	}
+
+	now := time.Now()
+	comment.Body = text
+	comment.EditedAt = &now
+	if err := DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&comment).Select("body", "edited_at").Updates(&comment).Error; err != nil {
+			return err
+		}
+		return replaceMentions(tx, comment.ID, mentioned)
+	}); err != nil {
+		jsonError(c, http.StatusInternalServerError, "could not update comment: "+err.Error())
+		return
+	}
+	if err := DB.Where("comment_id = ?", comment.ID).Find(&comment.Mentions).Error; err != nil {
+		jsonError(c, http.StatusInternalServerError, "db error: "+err.Error())
+		return
+	}
+
+	resp := gin.H{"comment": comment}
+	if len(unresolved) > 0 {
+		resp["unresolved_mentions"] = unresolved
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// DELETE /api/events/:id/comments/:comment_id
+// The author or the organizer (moderation) can delete. The comment is
+// blanked rather than removed so replies keep their place in the thread.
+func DeleteComment(c *gin.Context) {
+	userID, ok := getUserIDFromContext(c)
+	if !ok {
+		jsonError(c, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	eventID, ok := parseEventID(c)
+	if !ok {
+		return
+	}
+	ev, ok := findMemberEvent(c, eventID, userID)
+	if !ok {
+		return
+	}
+	comment, ok := findComment(c, eventID)
+	if !ok {
+		return
+	}
+	if comment.AuthorID != userID && ev.OrganizerID != userID {
+		jsonError(c, http.StatusForbidden, "only the author or the organizer can delete a comment")
+		return
+	}
+	if comment.DeletedAt != nil {
+		c.JSON(http.StatusOK, gin.H{"message": "comment deleted"})
+		return
+	}
+
+	now := time.Now()
+	if err := DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&Comment{}).Where("id = ?", comment.ID).
+			Updates(map[string]interface{}{"body": "", "deleted_at": now, "deleted_by_id": userID}).Error; err != nil {
+			return err
+		}
+		return replaceMentions(tx, comment.ID, nil)
+	}); err != nil {
+		jsonError(c, http.StatusInternalServerError, "delete failed: "+err.Error())
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "comment deleted"})
+}
@@ -0,0 +1,178 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+type commentResponse struct {
+	Comment            Comment  `json:"comment"`
+	UnresolvedMentions []string `json:"unresolved_mentions"`
+}
+
+type commentPage struct {
+	Items      []CommentThread `json:"items"`
+	NextCursor *string         `json:"next_cursor"`
+}
+
+func mentionedIDs(c Comment) []uint {
+	ids := make([]uint, 0, len(c.Mentions))
+	for _, m := range c.Mentions {
+		ids = append(ids, m.UserID)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	return ids
+}
+
+func TestCommentThreadsAndMentions(t *testing.T) {
+	f := newAuthFixture(t)
+	r := testRouter()
+	commentsPath := fmt.Sprintf("/api/events/%d/comments", f.Event.ID)
+
+	post := func(userID uint, body gin.H) (commentResponse, int) {
+		t.Helper()
+		var res commentResponse
+		w := apiRequest(t, r, userID, http.MethodPost, commentsPath, body)
+		if w.Code == http.StatusCreated {
+			decodeJSON(t, w, &res)
+		}
+		return res, w.Code
+	}
+	list := func(query string) commentPage {
+		t.Helper()
+		var page commentPage
+		w := apiRequest(t, r, f.Member.ID, http.MethodGet, commentsPath+query, nil)
+		if w.Code != http.StatusOK {
+			t.Fatalf("comments%s: status %d: %s", query, w.Code, w.Body.String())
+		}
+		decodeJSON(t, w, &page)
+		return page
+	}
+
+	if _, code := post(f.Outsider.ID, gin.H{"body": "Hello?"}); code != http.StatusNotFound {
+		t.Errorf("outsider comments: status %d, want 404", code)
+	}
+	if _, code := post(f.Member.ID, gin.H{"body": strings.Repeat("x", maxCommentLength+1)}); code != http.StatusBadRequest {
+		t.Errorf("too long: status %d, want 400", code)
+	}
+
+	// Mentions match the email, its local part or the name; non-members and
+	// email-like words stay unresolved
+	root, code := post(f.Member.ID, gin.H{"body": "Hi @organizer and @Member. cc @outsider, @ORGANIZER@example.com, mail me at me@member"})
+	if code != http.StatusCreated {
+		t.Fatalf("create: status %d", code)
+	}
+	if got, want := fmt.Sprint(mentionedIDs(root.Comment)), fmt.Sprint([]uint{f.Organizer.ID, f.Member.ID}); got != want {
+		t.Errorf("mentions = %s, want %s", got, want)
+	}
+	if got := fmt.Sprint(root.UnresolvedMentions); got != "[outsider]" {
+		t.Errorf("unresolved = %s", got)
+	}
+
+	reply, code := post(f.Organizer.ID, gin.H{"body": "On it", "parent_id": root.Comment.ID})
+	if code != http.StatusCreated {
+		t.Fatalf("reply: status %d", code)
+	}
+	if _, code := post(f.Member.ID, gin.H{"body": "Thanks", "parent_id": reply.Comment.ID}); code != http.StatusCreated {
+		t.Fatalf("nested reply: status %d", code)
+	}
+
+	// Task discussions are separate from the event's
+	if _, code := post(f.Member.ID, gin.H{"body": "Chips?", "task_id": f.Task.ID}); code != http.StatusCreated {
+		t.Fatalf("task comment: status %d", code)
+	}
+	if _, code := post(f.Member.ID, gin.H{"body": "Off topic", "task_id": f.Task.ID, "parent_id": root.Comment.ID}); code != http.StatusBadRequest {
+		t.Errorf("reply across discussions: status %d, want 400", code)
+	}
+
+	page := list("")
+	if len(page.Items) != 1 || len(page.Items[0].Replies) != 1 || len(page.Items[0].Replies[0].Replies) != 1 {
+		t.Fatalf("event thread = %+v", page.Items)
+	}
+	if page := list(fmt.Sprintf("?task_id=%d", f.Task.ID)); len(page.Items) != 1 || page.Items[0].Body != "Chips?" {
+		t.Errorf("task thread = %+v", page.Items)
+	}
+
+	// Only the author edits; mentions follow the new text
+	rootPath := fmt.Sprintf("%s/%d", commentsPath, root.Comment.ID)
+	if w := apiRequest(t, r, f.Organizer.ID, http.MethodPatch, rootPath, gin.H{"body": "Edited"}); w.Code != http.StatusForbidden {
+		t.Errorf("organizer edits: status %d, want 403", w.Code)
+	}
+	w := apiRequest(t, r, f.Member.ID, http.MethodPatch, rootPath, gin.H{"body": "Hi @organizer"})
+	if w.Code != http.StatusOK {
+		t.Fatalf("edit: status %d: %s", w.Code, w.Body.String())
+	}
+	var edited commentResponse
+	decodeJSON(t, w, &edited)
+	if edited.Comment.EditedAt == nil || fmt.Sprint(mentionedIDs(edited.Comment)) != fmt.Sprint([]uint{f.Organizer.ID}) {
+		t.Errorf("edited = %+v", edited.Comment)
+	}
+
+	// The organizer moderates; the thread keeps a placeholder
+	replyPath := fmt.Sprintf("%s/%d", commentsPath, reply.Comment.ID)
+	if w := apiRequest(t, r, f.Member.ID, http.MethodDelete, replyPath, nil); w.Code != http.StatusForbidden {
+		t.Errorf("member deletes organizer's reply: status %d, want 403", w.Code)
+	}
+	if w := apiRequest(t, r, f.Organizer.ID, http.MethodDelete, rootPath, nil); w.Code != http.StatusOK {
+		t.Fatalf("moderate: status %d: %s", w.Code, w.Body.String())
+	}
+	page = list("")
+	if len(page.Items) != 1 {
+		t.Fatalf("thread after delete has %d roots", len(page.Items))
+	}
+	if got := page.Items[0]; got.Body != "" || got.DeletedAt == nil || got.DeletedByID == nil || *got.DeletedByID != f.Organizer.ID ||
+		len(got.Mentions) != 0 || len(got.Replies) != 1 {
+		t.Errorf("placeholder = %+v", got.Comment)
+	}
+	if w := apiRequest(t, r, f.Member.ID, http.MethodPatch, rootPath, gin.H{"body": "Back"}); w.Code != http.StatusConflict {
+		t.Errorf("edit deleted: status %d, want 409", w.Code)
+	}
+}
+
+// Pages are made of whole threads: replies never push a root to the next page.
+func TestCommentPagesByThread(t *testing.T) {
+	f := newAuthFixture(t)
+	r := testRouter()
+	commentsPath := fmt.Sprintf("/api/events/%d/comments", f.Event.ID)
+
+	var roots []uint
+	for i := 0; i < 3; i++ {
+		var res commentResponse
+		w := apiRequest(t, r, f.Member.ID, http.MethodPost, commentsPath, gin.H{"body": fmt.Sprintf("Root %d", i)})
+		if w.Code != http.StatusCreated {
+			t.Fatalf("create: status %d: %s", w.Code, w.Body.String())
+		}
+		decodeJSON(t, w, &res)
+		roots = append(roots, res.Comment.ID)
+		for j := 0; j < 2; j++ {
+			if w := apiRequest(t, r, f.Organizer.ID, http.MethodPost, commentsPath, gin.H{"body": "Reply", "parent_id": res.Comment.ID}); w.Code != http.StatusCreated {
+				t.Fatalf("reply: status %d: %s", w.Code, w.Body.String())
+			}
+		}
+	}
+
+	var seen []uint
+	cursor := ""
+	for i := 0; i < 3; i++ {
+		var page commentPage
+		decodeJSON(t, apiRequest(t, r, f.Member.ID, http.MethodGet, commentsPath+"?limit=2"+cursor, nil), &page)
+		for _, thread := range page.Items {
+			if len(thread.Replies) != 2 {
+				t.Errorf("root %d has %d replies, want 2", thread.ID, len(thread.Replies))
+			}
+			seen = append(seen, thread.ID)
+		}
+		if page.NextCursor == nil {
+			break
+		}
+		cursor = "&cursor=" + *page.NextCursor
+	}
+	if !sameIDs(seen, roots) {
+		t.Errorf("pages listed roots %v, want %v", seen, roots)
+	}
+}
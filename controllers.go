@@ -1,6 +1,7 @@
 package main
 
 import (
+	"fmt"
 	"net/http"
 	"strconv"
 	"strings"
@@ -39,6 +40,103 @@ func getUserIDFromContext(c *gin.Context) (uint, bool) {
 	}
 }
 
+// parseDateInput accepts RFC3339 or YYYY-MM-DD.
+func parseDateInput(value string) (time.Time, error) {
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		t, err = time.Parse("2006-01-02", value)
+	}
+	return t, err
+}
+
+// splitList splits a comma separated query value, dropping empty entries.
+func splitList(value string) []string {
+	out := make([]string, 0)
+	for _, part := range strings.Split(value, ",") {
+		if part = strings.TrimSpace(part); part != "" {
+			out = append(out, part)
+		}
+	}
+	return out
+}
+
+// parseEventID reads the :id path param. Writes a 400 response if it is not a valid ID.
+func parseEventID(c *gin.Context) (uint, bool) {
+	eventID64, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		jsonError(c, http.StatusBadRequest, "invalid event id")
+		return 0, false
+	}
+	return uint(eventID64), true
+}
+
+// findEvent loads an event by ID. Writes a 404/500 response if it cannot be loaded.
+func findEvent(c *gin.Context, eventID uint) (Event, bool) {
+	var ev Event
+	if err := DB.First(&ev, eventID).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			jsonError(c, http.StatusNotFound, "event not found")
+			return ev, false
+		}
+		jsonError(c, http.StatusInternalServerError, "db error: "+err.Error())
+		return ev, false
+	}
+	return ev, true
+}
+
+// isEventMember reports whether the user organizes the event or has an EventAttendee row on it.
+func isEventMember(ev Event, userID uint) (bool, error) {
+	if ev.OrganizerID == userID {
+		return true, nil
+	}
+	var count int64
+	err := DB.Model(&EventAttendee{}).Where("event_id = ? AND user_id = ?", ev.ID, userID).Count(&count).Error
+	return count > 0, err
+}
+
+// findMemberEvent loads an event the user is a member of. Non-members get the
+// same 404 as a missing event so event IDs cannot be probed.
+func findMemberEvent(c *gin.Context, eventID, userID uint) (Event, bool) {
+	var ev Event
+	if err := DB.First(&ev, eventID).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			jsonError(c, http.StatusNotFound, "event not found")
+			return ev, false
+		}
+		jsonError(c, http.StatusInternalServerError, "db error: "+err.Error())
+		return ev, false
+	}
+	member, err := isEventMember(ev, userID)
+	if err != nil {
+		jsonError(c, http.StatusInternalServerError, "db error: "+err.Error())
+		return ev, false
+	}
+	if !member {
+		jsonError(c, http.StatusNotFound, "event not found")
+		return ev, false
+	}
+	return ev, true
+}
+
+// findVisibleEvent loads an event the user is a member of, or any public
+// event. Everyone else gets the same 404 as a missing event.
+func findVisibleEvent(c *gin.Context, eventID, userID uint) (Event, bool) {
+	ev, ok := findEvent(c, eventID)
+	if !ok || ev.IsPublic {
+		return ev, ok
+	}
+	member, err := isEventMember(ev, userID)
+	if err != nil {
+		jsonError(c, http.StatusInternalServerError, "db error: "+err.Error())
+		return ev, false
+	}
+	if !member {
+		jsonError(c, http.StatusNotFound, "event not found")
+		return ev, false
+	}
+	return ev, true
+}
+
 // -----------------------------
 // Events
 // -----------------------------
@@ -48,6 +146,18 @@ type CreateEventRequest struct {
 	Description string `json:"description"`
 	Location    string `json:"location"`
 	Date        string `json:"date" binding:"required"` // expect ISO8601 or "YYYY-MM-DD"
+
+	RSVPDeadline   string   `json:"rsvp_deadline"`    // optional, same formats as date
+	LateRSVPPolicy string   `json:"late_rsvp_policy"` // "reject" (default) or "flag"
+	IsPublic       bool     `json:"is_public"`        // listed in everyone's search results
+	Category       string   `json:"category"`         // one of GET /api/categories
+	Tags           []string `json:"tags"`             // tag names, created for you when new
+	VenueID        *uint    `json:"venue_id"`         // see GET /api/venues
+	EndsAt         string   `json:"ends_at"`          // optional, same formats as date
+
+	// TemplateID instantiates one of your templates: its tasks are created with
+	// due dates relative to Date, and it fills in an empty description/location.
+	TemplateID uint `json:"template_id"`
 }
 
 func CreateEvent(c *gin.Context) {
@@ -64,43 +174,154 @@ func CreateEvent(c *gin.Context) {
 	}
 
 	// parse date - accept RFC3339 or YYYY-MM-DD
-	var eventDate time.Time
-	var err error
-	eventDate, err = time.Parse(time.RFC3339, body.Date)
+	eventDate, err := parseDateInput(body.Date)
 	if err != nil {
-		eventDate, err = time.Parse("2006-01-02", body.Date)
-		if err != nil {
-			jsonError(c, http.StatusBadRequest, "invalid date format (use RFC3339 or YYYY-MM-DD)")
+		jsonError(c, http.StatusBadRequest, "invalid date format (use RFC3339 or YYYY-MM-DD)")
+		return
+	}
+
+	deadline, policy, err := parseRSVPRules(body.RSVPDeadline, body.LateRSVPPolicy, eventDate)
+	if err != nil {
+		jsonError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	endsAt, err := parseEndsAt(body.EndsAt, eventDate)
+	if err != nil {
+		jsonError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	category, valid := normalizeCategory(body.Category)
+	if !valid {
+		jsonError(c, http.StatusBadRequest, "category must be one of: "+strings.Join(eventCategories, ", "))
+		return
+	}
+	tagNames := make([]string, 0, len(body.Tags))
+	for _, raw := range body.Tags {
+		name := normalizeTagName(raw)
+		if name == "" {
+			jsonError(c, http.StatusBadRequest, "invalid tag: "+raw)
 			return
 		}
+		tagNames = append(tagNames, name)
 	}
 
 	ev := Event{
-		Title:       strings.TrimSpace(body.Title),
-		Description: body.Description,
-		Location:    body.Location,
-		Date:        eventDate,
-		OrganizerID: userID,
+		Title:          strings.TrimSpace(body.Title),
+		Description:    body.Description,
+		Location:       body.Location,
+		Date:           eventDate,
+		OrganizerID:    userID,
+		RSVPDeadline:   deadline,
+		LateRSVPPolicy: policy,
+		IsPublic:       body.IsPublic,
+		Category:       category,
+		VenueID:        body.VenueID,
+		EndsAt:         endsAt,
 	}
 
-	if err := DB.Create(&ev).Error; err != nil {
-		jsonError(c, http.StatusInternalServerError, "could not create event: "+err.Error())
+	// The organizer is busy for the whole event; check their other commitments
+	conflicts, err := scheduleConflicts(DB, userID, ev)
+	if err != nil {
+		jsonError(c, http.StatusInternalServerError, "db error: "+err.Error())
+		return
+	}
+	if len(conflicts) > 0 && blockConflicts() {
+		conflictError(c, conflicts)
 		return
 	}
+	ev.Conflicts = conflicts
+
+	err = DB.Transaction(func(tx *gorm.DB) error {
+		var tpl EventTemplate
+		if body.TemplateID != 0 {
+			var err error
+			if tpl, err = loadOwnTemplate(tx, body.TemplateID, userID); err != nil {
+				return err
+			}
+			if ev.Description == "" {
+				ev.Description = tpl.Description
+			}
+			if ev.Location == "" {
+				ev.Location = tpl.Location
+			}
+		}
 
-	// Ensure the creator is marked as organizer in attendees table
-	org := EventAttendee{
-		EventID: ev.ID,
-		UserID:  userID,
-		Role:    "organizer",
-		Status:  "",
+		if err := bookVenue(tx, &ev); err != nil {
+			return err
+		}
+		if len(tagNames) > 0 {
+			tags, err := ensureTags(tx, userID, tagNames)
+			if err != nil {
+				return err
+			}
+			ev.Tags = tags
+		}
+
+		if err := tx.Create(&ev).Error; err != nil {
+			return err
+		}
+
+		// Ensure the creator is marked as organizer in attendees table
+		org := EventAttendee{
+			EventID: ev.ID,
+			UserID:  userID,
+			Role:    "organizer",
+			Status:  "",
+		}
+		if err := tx.Where("event_id = ? AND user_id = ?", ev.ID, userID).FirstOrCreate(&org).Error; err != nil {
+			return err
+		}
+
+		if body.TemplateID != 0 {
+			tasks, err := instantiateTasks(tx, ev.ID, ev.Date, tpl.Tasks)
+			if err != nil {
+				return err
+			}
+			ev.Tasks = tasks
+		}
+		return nil
+	})
+	if err == errTemplateNotFound {
+		jsonError(c, http.StatusNotFound, "template not found")
+		return
+	}
+	if venueError(c, err) {
+		return
+	}
+	if err != nil {
+		jsonError(c, http.StatusInternalServerError, "could not create event: "+err.Error())
+		return
 	}
-	// Try to create but ignore duplicate errors (shouldn't exist)
-	_ = DB.Where("event_id = ? AND user_id = ?", ev.ID, userID).FirstOrCreate(&org)
 
 	c.JSON(http.StatusCreated, ev)
 }
 
+// parseRSVPRules validates an optional RSVP deadline and late policy for an event on eventDate.
+func parseRSVPRules(deadlineInput, policyInput string, eventDate time.Time) (*time.Time, string, error) {
+	policy := strings.ToLower(strings.TrimSpace(policyInput))
+	if policy == "" {
+		policy = "reject"
+	}
+	if policy != "reject" && policy != "flag" {
+		return nil, "", fmt.Errorf("late_rsvp_policy must be 'reject' or 'flag'")
+	}
+
+	if strings.TrimSpace(deadlineInput) == "" {
+		return nil, policy, nil
+	}
+	deadline, err := parseDateInput(strings.TrimSpace(deadlineInput))
+	if err != nil {
+		return nil, "", fmt.Errorf("invalid rsvp_deadline format (use RFC3339 or YYYY-MM-DD)")
+	}
+	if deadline.After(eventDate) {
+		return nil, "", fmt.Errorf("rsvp_deadline must not be after the event date")
+	}
+	return &deadline, policy, nil
+}
+
+// GET /api/events/organized?tag=&category=&limit=&sort=date|created_at|title&cursor=
 func GetOrganizedEvents(c *gin.Context) {
 	userID, ok := getUserIDFromContext(c)
 	if !ok {
@@ -108,14 +329,14 @@ func GetOrganizedEvents(c *gin.Context) {
 		return
 	}
 
-	var events []Event
-	if err := DB.Preload("Tasks").Where("organizer_id = ?", userID).Order("date asc").Find(&events).Error; err != nil {
-		jsonError(c, http.StatusInternalServerError, "db error: "+err.Error())
+	query, ok := filterByTags(c, DB.Model(&Event{}).Where("events.organizer_id = ?", userID))
+	if !ok {
 		return
 	}
-	c.JSON(http.StatusOK, events)
+	pageEvents(c, query)
 }
 
+// GET /api/events/invited?tag=&category=&limit=&sort=date|created_at|title&cursor=
 func GetInvitedEvents(c *gin.Context) {
 	userID, ok := getUserIDFromContext(c)
 	if !ok {
@@ -123,29 +344,12 @@ func GetInvitedEvents(c *gin.Context) {
 		return
 	}
 
-	var attendances []EventAttendee
-	if err := DB.Where("user_id = ? AND role = ?", userID, "attendee").Find(&attendances).Error; err != nil {
-		jsonError(c, http.StatusInternalServerError, "db error: "+err.Error())
-		return
-	}
-
-	if len(attendances) == 0 {
-		c.JSON(http.StatusOK, []Event{})
-		return
-	}
-
-	ids := make([]uint, 0, len(attendances))
-	for _, a := range attendances {
-		ids = append(ids, a.EventID)
-	}
-
-	var events []Event
-	if err := DB.Preload("Tasks").Where("id IN ?", ids).Order("date asc").Find(&events).Error; err != nil {
-		jsonError(c, http.StatusInternalServerError, "db error: "+err.Error())
+	invited := DB.Model(&EventAttendee{}).Select("event_id").Where("user_id = ? AND role = ?", userID, "attendee")
+	query, ok := filterByTags(c, DB.Model(&Event{}).Where("events.id IN (?)", invited))
+	if !ok {
 		return
 	}
-
-	c.JSON(http.StatusOK, events)
+	pageEvents(c, query)
 }
 
 func DeleteEvent(c *gin.Context) {
@@ -166,13 +370,8 @@ func DeleteEvent(c *gin.Context) {
 		return
 	}
 
-	var ev Event
-	if err := DB.First(&ev, id).Error; err != nil {
-		if err == gorm.ErrRecordNotFound {
-			jsonError(c, http.StatusNotFound, "event not found")
-			return
-		}
-		jsonError(c, http.StatusInternalServerError, "db error: "+err.Error())
+	ev, ok := findMemberEvent(c, uint(id), userID)
+	if !ok {
 		return
 	}
 
@@ -183,13 +382,70 @@ func DeleteEvent(c *gin.Context) {
 	}
 
 	// Delete tasks and attendee links and event in a transaction
+	var fileKeys []string
 	if err := DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&Attachment{}).Where("event_id = ?", ev.ID).Pluck("storage_key", &fileKeys).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("event_id = ?", ev.ID).Delete(&Attachment{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("event_attendee_id IN (?)", tx.Model(&EventAttendee{}).Select("id").Where("event_id = ?", ev.ID)).Delete(&AttendeeAnswer{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("event_id = ?", ev.ID).Delete(&QuestionnaireQuestion{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("event_id = ?", ev.ID).Delete(&RSVPChange{}).Error; err != nil {
+			return err
+		}
+		if err := deleteComments(tx, "event_id = ?", ev.ID); err != nil {
+			return err
+		}
+		pollIDs := tx.Model(&DatePoll{}).Select("id").Where("event_id = ?", ev.ID)
+		if err := tx.Where("poll_id IN (?)", pollIDs).Delete(&DatePollVote{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("poll_id IN (?)", pollIDs).Delete(&DatePollOption{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("event_id = ?", ev.ID).Delete(&DatePoll{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&Notification{}).Where("event_id = ?", ev.ID).Update("event_id", nil).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("event_id = ?", ev.ID).Delete(&SavedSearchHit{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&ev).Association("Tags").Clear(); err != nil {
+			return err
+		}
+		if err := tx.Where("event_id = ?", ev.ID).Delete(&Expense{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("event_id = ?", ev.ID).Delete(&BudgetItem{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("event_id = ?", ev.ID).Delete(&ExchangeRate{}).Error; err != nil {
+			return err
+		}
 		if err := tx.Where("event_id = ?", ev.ID).Delete(&EventAttendee{}).Error; err != nil {
 			return err
 		}
+		var tasks []Task
+		if err := tx.Where("event_id = ?", ev.ID).Find(&tasks).Error; err != nil {
+			return err
+		}
+		if err := deleteTaskLinks(tx, tasks); err != nil {
+			return err
+		}
 		if err := tx.Where("event_id = ?", ev.ID).Delete(&Task{}).Error; err != nil {
 			return err
 		}
+		if err := tx.Where("event_id = ?", ev.ID).Delete(&BoardColumn{}).Error; err != nil {
+			return err
+		}
 		if err := tx.Delete(&Event{}, ev.ID).Error; err != nil {
 			return err
 		}
@@ -198,10 +454,99 @@ func DeleteEvent(c *gin.Context) {
 		jsonError(c, http.StatusInternalServerError, "delete failed: "+err.Error())
 		return
 	}
+	deleteStoredObjects(fileKeys)
 
 	c.JSON(http.StatusOK, gin.H{"message": "event deleted"})
 }
 
+type RSVPDeadlineRequest struct {
+	RSVPDeadline   string `json:"rsvp_deadline"` // empty clears the deadline
+	LateRSVPPolicy string `json:"late_rsvp_policy"`
+}
+
+// PUT /api/events/:id/rsvp-deadline
+func SetRSVPDeadline(c *gin.Context) {
+	userID, ok := getUserIDFromContext(c)
+	if !ok {
+		jsonError(c, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	eventID, ok := parseEventID(c)
+	if !ok {
+		return
+	}
+	ev, ok := findMemberEvent(c, eventID, userID)
+	if !ok {
+		return
+	}
+	if ev.OrganizerID != userID {
+		jsonError(c, http.StatusForbidden, "only organizer can change the RSVP deadline")
+		return
+	}
+
+	var body RSVPDeadlineRequest
+	if err := c.ShouldBindJSON(&body); err != nil {
+		jsonError(c, http.StatusBadRequest, "invalid body: "+err.Error())
+		return
+	}
+
+	deadline, policy, err := parseRSVPRules(body.RSVPDeadline, body.LateRSVPPolicy, ev.Date)
+	if err != nil {
+		jsonError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	ev.RSVPDeadline = deadline
+	ev.LateRSVPPolicy = policy
+	if err := DB.Model(&ev).Select("rsvp_deadline", "late_rsvp_policy").Updates(&ev).Error; err != nil {
+		jsonError(c, http.StatusInternalServerError, "could not update event: "+err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, ev)
+}
+
+type VisibilityRequest struct {
+	IsPublic *bool `json:"is_public" binding:"required"`
+}
+
+// PUT /api/events/:id/visibility
+func SetEventVisibility(c *gin.Context) {
+	userID, ok := getUserIDFromContext(c)
+	if !ok {
+		jsonError(c, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	eventID, ok := parseEventID(c)
+	if !ok {
+		return
+	}
+	ev, ok := findMemberEvent(c, eventID, userID)
+	if !ok {
+		return
+	}
+	if ev.OrganizerID != userID {
+		jsonError(c, http.StatusForbidden, "only organizer can change event visibility")
+		return
+	}
+
+	var body VisibilityRequest
+	if err := c.ShouldBindJSON(&body); err != nil {
+		jsonError(c, http.StatusBadRequest, "invalid body: "+err.Error())
+		return
+	}
+
+	ev.IsPublic = *body.IsPublic
+	if err := DB.Model(&ev).Update("is_public", ev.IsPublic).Error; err != nil {
+		jsonError(c, http.StatusInternalServerError, "could not update event: "+err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, ev)
+}
+
 // -----------------------------
 // Invitations
 // -----------------------------
@@ -234,14 +579,9 @@ func InviteUser(c *gin.Context) {
 		return
 	}
 
-	// check event exists
-	var ev Event
-	if err := DB.First(&ev, eventID).Error; err != nil {
-		if err == gorm.ErrRecordNotFound {
-			jsonError(c, http.StatusNotFound, "event not found")
-			return
-		}
-		jsonError(c, http.StatusInternalServerError, "db error: "+err.Error())
+	// check event exists and the caller can see it
+	ev, ok := findMemberEvent(c, eventID, userID)
+	if !ok {
 		return
 	}
 
@@ -299,10 +639,27 @@ func InviteUser(c *gin.Context) {
 // -----------------------------
 
 type AttendanceRequest struct {
-	Status string `json:"status" binding:"required"` // Going / Maybe / Not Going
+	Status  string        `json:"status" binding:"required"` // Going / Maybe / Not Going
+	Answers []AnswerInput `json:"answers"`                   // questionnaire answers, if the event has one
+	Guests  *int          `json:"guests"`                    // plus-ones; omitted keeps the previous count
+	// UserID lets the organizer set the status of an existing attendee,
+	// bypassing the RSVP deadline. Defaults to the authenticated user.
+	UserID uint `json:"user_id"`
 	// EventID is in path param /events/:id/respond
 }
 
+// maxRSVPGuests caps the plus-ones a single attendee can bring.
+const maxRSVPGuests = 10
+
+// rsvpClosesAt is when an event stops taking RSVPs: when it ends. An event
+// given as a bare date (midnight UTC, no end time) lasts until the end of that day.
+func rsvpClosesAt(ev Event) time.Time {
+	if ev.EndsAt == nil && ev.Date.Equal(ev.Date.UTC().Truncate(24*time.Hour)) {
+		return ev.Date.UTC().AddDate(0, 0, 1)
+	}
+	return eventEnd(ev)
+}
+
 func SetAttendance(c *gin.Context) {
 	userID, ok := getUserIDFromContext(c)
 	if !ok {
@@ -329,50 +686,186 @@ func SetAttendance(c *gin.Context) {
 		jsonError(c, http.StatusBadRequest, "status must be one of: Going, Maybe, Not Going")
 		return
 	}
+	if body.Guests != nil && (*body.Guests < 0 || *body.Guests > maxRSVPGuests) {
+		jsonError(c, http.StatusBadRequest, fmt.Sprintf("guests must be between 0 and %d", maxRSVPGuests))
+		return
+	}
 
-	// Ensure event exists
-	var ev Event
-	if err := DB.First(&ev, eventID).Error; err != nil {
-		if err == gorm.ErrRecordNotFound {
-			jsonError(c, http.StatusNotFound, "event not found")
-			return
-		}
-		jsonError(c, http.StatusInternalServerError, "db error: "+err.Error())
+	// Only the organizer and invited users can respond, and anyone for a
+	// public event. Everyone else gets the same 404 as a missing event, so an
+	// RSVP can't be used to make yourself a member of someone else's event.
+	ev, ok := findVisibleEvent(c, eventID, userID)
+	if !ok {
 		return
 	}
 
-	// If there is no attendee row for this user, create one as attendee (self-rsvp)
-	var att EventAttendee
-	if err := DB.Where("event_id = ? AND user_id = ?", eventID, userID).First(&att).Error; err != nil {
-		if err == gorm.ErrRecordNotFound {
-			// create attendee record with status
-			att = EventAttendee{
-				EventID: eventID,
-				UserID:  userID,
-				Role:    "attendee",
-				Status:  normalized,
-			}
-			if err := DB.Create(&att).Error; err != nil {
-				jsonError(c, http.StatusInternalServerError, "could not set attendance: "+err.Error())
-				return
-			}
-			c.JSON(http.StatusOK, att)
+	// Organizer override: respond on behalf of someone else
+	targetID := userID
+	override := false
+	if body.UserID != 0 && body.UserID != userID {
+		if ev.OrganizerID != userID {
+			jsonError(c, http.StatusForbidden, "only organizer can set another user's status")
 			return
 		}
-		jsonError(c, http.StatusInternalServerError, "db error: "+err.Error())
-		return
+		targetID = body.UserID
+		override = true
 	}
 
-	// Update existing record
-	att.Status = normalized
-	if err := DB.Save(&att).Error; err != nil {
-		jsonError(c, http.StatusInternalServerError, "could not update status: "+err.Error())
+	// Lock-in rules: no RSVPs once the event is over, and the deadline either rejects
+	// or flags late changes. The organizer override skips both.
+	late := false
+	now := time.Now()
+	if !override {
+		if !rsvpClosesAt(ev).After(now) {
+			jsonError(c, http.StatusBadRequest, "event has already taken place")
+			return
+		}
+		if ev.RSVPDeadline != nil && ev.RSVPDeadline.Before(now) {
+			if ev.LateRSVPPolicy != "flag" {
+				jsonError(c, http.StatusForbidden, "RSVP deadline has passed")
+				return
+			}
+			late = true
+		}
+	}
+
+	// Validate questionnaire answers against the event's questions
+	questions, err := loadQuestionnaire(eventID)
+	if err != nil {
+		jsonError(c, http.StatusInternalServerError, "db error: "+err.Error())
+		return
+	}
+	answers, err := validateAnswers(questions, body.Answers)
+	if err != nil {
+		jsonError(c, http.StatusBadRequest, "invalid answers: "+err.Error())
+		return
+	}
+
+	// If there is no attendee row for this user, create one as attendee (self-rsvp)
+	var att EventAttendee
+	err = DB.Where("event_id = ? AND user_id = ?", eventID, targetID).First(&att).Error
+	if err != nil && err != gorm.ErrRecordNotFound {
+		jsonError(c, http.StatusInternalServerError, "db error: "+err.Error())
+		return
+	}
+	isNew := err == gorm.ErrRecordNotFound
+	if isNew && override {
+		jsonError(c, http.StatusNotFound, "attendee not found")
+		return
+	}
+
+	// Required questions must be answered unless the user declines.
+	// Answers given in an earlier response still count.
+	if normalized != "Not Going" && len(questions) > 0 {
+		merged := make(map[uint][]string)
+		if !isNew {
+			var previous []AttendeeAnswer
+			if err := DB.Where("event_attendee_id = ?", att.ID).Find(&previous).Error; err != nil {
+				jsonError(c, http.StatusInternalServerError, "db error: "+err.Error())
+				return
+			}
+			for _, a := range previous {
+				merged[a.QuestionID] = a.Values
+			}
+		}
+		for qID, values := range answers {
+			merged[qID] = values
+		}
+		if missing := missingRequired(questions, merged); len(missing) > 0 {
+			jsonError(c, http.StatusBadRequest, "missing answers for required questions: "+strings.Join(missing, ", "))
+			return
+		}
+	}
+
+	// Going or Maybe commits the user to the event's time slot. An organizer
+	// override only records what the attendee said, so it is never blocked,
+	// and the attendee's other events are none of the organizer's business.
+	var conflicts []busyInterval
+	if normalized != "Not Going" && !override {
+		if conflicts, err = scheduleConflicts(DB, targetID, ev); err != nil {
+			jsonError(c, http.StatusInternalServerError, "db error: "+err.Error())
+			return
+		}
+		if len(conflicts) > 0 && blockConflicts() {
+			conflictError(c, conflicts)
+			return
+		}
+	}
+
+	if err := DB.Transaction(func(tx *gorm.DB) error {
+		previous := att.Status
+		if isNew {
+			// create attendee record with status
+			att = EventAttendee{
+				EventID:      eventID,
+				UserID:       userID,
+				Role:         "attendee",
+				Status:       normalized,
+				LateResponse: late,
+			}
+			if body.Guests != nil {
+				att.Guests = *body.Guests
+			}
+			if normalized == "Not Going" {
+				att.Guests = 0
+			}
+			if err := tx.Create(&att).Error; err != nil {
+				return err
+			}
+		} else {
+			// Update existing record. A late change keeps the flag even if a
+			// later organizer override corrects the status.
+			att.Status = normalized
+			att.LateResponse = att.LateResponse || late
+			if body.Guests != nil {
+				att.Guests = *body.Guests
+			}
+			if normalized == "Not Going" {
+				att.Guests = 0
+			}
+			if err := tx.Save(&att).Error; err != nil {
+				return err
+			}
+		}
+
+		// History is append-only: one row per actual status change
+		if isNew || previous != normalized {
+			change := RSVPChange{
+				EventAttendeeID: att.ID,
+				EventID:         eventID,
+				UserID:          att.UserID,
+				FromStatus:      previous,
+				ToStatus:        normalized,
+				ChangedByID:     userID,
+				Late:            late,
+			}
+			if err := tx.Create(&change).Error; err != nil {
+				return err
+			}
+		}
+		return saveAnswers(tx, att.ID, answers)
+	}); err != nil {
+		jsonError(c, http.StatusInternalServerError, "could not set attendance: "+err.Error())
+		return
+	}
+
+	if err := DB.Where("event_attendee_id = ?", att.ID).Find(&att.Answers).Error; err != nil {
+		jsonError(c, http.StatusInternalServerError, "db error: "+err.Error())
 		return
 	}
+	att.Conflicts = conflicts
 
 	c.JSON(http.StatusOK, att)
 }
 
+// attendeeSorts: invitation order, or most recently changed first.
+var attendeeSorts = map[string]sortSpec{
+	"created_at": {column: "event_attendees.created_at", key: "time"},
+	"updated_at": {column: "event_attendees.updated_at", desc: true, key: "time"},
+}
+
+// GET /api/events/:id/attendees?sort=created_at|updated_at&limit=&cursor=
+// Returns a page envelope (see pagination.go).
 func GetEventAttendees(c *gin.Context) {
 	userID, ok := getUserIDFromContext(c)
 	if !ok {
@@ -389,13 +882,8 @@ func GetEventAttendees(c *gin.Context) {
 	eventID := uint(eventID64)
 
 	// Only organizer can view full attendee list
-	var ev Event
-	if err := DB.First(&ev, eventID).Error; err != nil {
-		if err == gorm.ErrRecordNotFound {
-			jsonError(c, http.StatusNotFound, "event not found")
-			return
-		}
-		jsonError(c, http.StatusInternalServerError, "db error: "+err.Error())
+	ev, ok := findMemberEvent(c, eventID, userID)
+	if !ok {
 		return
 	}
 
@@ -404,25 +892,57 @@ func GetEventAttendees(c *gin.Context) {
 		return
 	}
 
-	var attendees []EventAttendee
-	if err := DB.Where("event_id = ?", eventID).Find(&attendees).Error; err != nil {
-		jsonError(c, http.StatusInternalServerError, "db error: "+err.Error())
+	page, ok := parsePageRequest(c, attendeeSorts, "created_at")
+	if !ok {
+		return
+	}
+	attendees, next, total, ok := pageQuery(c, page, DB.Where("event_id = ?", eventID), "event_attendees.id", func(a EventAttendee) (interface{}, uint) {
+		if page.Sort == "updated_at" {
+			return a.UpdatedAt, a.ID
+		}
+		return a.CreatedAt, a.ID
+	})
+	if !ok {
 		return
 	}
 
-	c.JSON(http.StatusOK, attendees)
+	c.JSON(http.StatusOK, pageEnvelope(attendees, next, total))
 }
 
 // -----------------------------
 // Tasks
 // -----------------------------
 
+var taskStatuses = map[string]bool{"todo": true, "in-progress": true, "done": true, "blocked": true}
+
+var taskPriorities = map[string]bool{"low": true, "medium": true, "high": true, "urgent": true}
+
 type CreateTaskRequest struct {
 	Title       string `json:"title" binding:"required"`
 	Description string `json:"description"`
+	Status      string `json:"status"`   // defaults to todo
+	Priority    string `json:"priority"` // defaults to medium
+	DueAt       string `json:"due_at"`   // RFC3339 or YYYY-MM-DD
+	AssigneeIDs []uint `json:"assignee_ids"`
+	ParentID    *uint  `json:"parent_id"` // makes this a subtask of another task of the event
+	ColumnID    *uint  `json:"column_id"` // puts the task at the end of a board column
 	// EventID will come from url param :id
 }
 
+// setTaskStatus changes the status and keeps CompletedAt in sync with it.
+func setTaskStatus(task *Task, status string) {
+	if status == task.Status {
+		return
+	}
+	task.Status = status
+	if status == "done" {
+		now := time.Now()
+		task.CompletedAt = &now
+	} else {
+		task.CompletedAt = nil
+	}
+}
+
 func CreateTask(c *gin.Context) {
 	userID, ok := getUserIDFromContext(c)
 	if !ok {
@@ -440,13 +960,8 @@ func CreateTask(c *gin.Context) {
 	eventID := uint(eventID64)
 
 	// check event exists and user is allowed to create tasks (organizer only)
-	var ev Event
-	if err := DB.First(&ev, eventID).Error; err != nil {
-		if err == gorm.ErrRecordNotFound {
-			jsonError(c, http.StatusNotFound, "event not found")
-			return
-		}
-		jsonError(c, http.StatusInternalServerError, "db error: "+err.Error())
+	ev, ok := findMemberEvent(c, eventID, userID)
+	if !ok {
 		return
 	}
 	if ev.OrganizerID != userID {
@@ -464,18 +979,97 @@ func CreateTask(c *gin.Context) {
 		EventID:     eventID,
 		Title:       strings.TrimSpace(body.Title),
 		Description: body.Description,
+		Status:      "todo",
+		Priority:    "medium",
 	}
 
-	if err := DB.Create(&task).Error; err != nil {
+	if body.Status != "" {
+		status := strings.ToLower(strings.TrimSpace(body.Status))
+		if !taskStatuses[status] {
+			jsonError(c, http.StatusBadRequest, "status must be one of: todo, in-progress, done, blocked")
+			return
+		}
+		setTaskStatus(&task, status)
+	}
+	if body.Priority != "" {
+		priority := strings.ToLower(strings.TrimSpace(body.Priority))
+		if !taskPriorities[priority] {
+			jsonError(c, http.StatusBadRequest, "priority must be one of: low, medium, high, urgent")
+			return
+		}
+		task.Priority = priority
+	}
+	if body.DueAt != "" {
+		due, err := parseDateInput(body.DueAt)
+		if err != nil {
+			jsonError(c, http.StatusBadRequest, "invalid due_at format (use RFC3339 or YYYY-MM-DD)")
+			return
+		}
+		task.DueAt = &due
+	}
+	if err := checkEventMembers(eventID, body.AssigneeIDs); err != nil {
+		jsonError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	if body.ParentID != nil {
+		var count int64
+		if err := DB.Model(&Task{}).Where("id = ? AND event_id = ?", *body.ParentID, eventID).Count(&count).Error; err != nil {
+			jsonError(c, http.StatusInternalServerError, "db error: "+err.Error())
+			return
+		}
+		if count == 0 {
+			jsonError(c, http.StatusBadRequest, "parent must be a task of the same event")
+			return
+		}
+		task.ParentID = body.ParentID
+	}
+
+	if err := DB.Transaction(func(tx *gorm.DB) error {
+		if body.ColumnID != nil && *body.ColumnID != 0 {
+			pos, err := appendToColumn(tx, eventID, *body.ColumnID)
+			if err != nil {
+				return err
+			}
+			task.ColumnID = body.ColumnID
+			task.Position = pos
+		}
+		if err := tx.Create(&task).Error; err != nil {
+			return err
+		}
+		return replaceAssignees(tx, task.ID, body.AssigneeIDs)
+	}); err != nil {
+		if err == errColumnNotFound {
+			jsonError(c, http.StatusBadRequest, "column_id is not a column of this event")
+			return
+		}
 		jsonError(c, http.StatusInternalServerError, "could not create task: "+err.Error())
 		return
 	}
+	if err := DB.Where("task_id = ?", task.ID).Find(&task.Assignees).Error; err != nil {
+		jsonError(c, http.StatusInternalServerError, "db error: "+err.Error())
+		return
+	}
 
 	c.JSON(http.StatusCreated, task)
 }
 
+// GET /api/events/:id/tasks?status=&priority=&due_before=&due_after=&sort=&order=&limit=&cursor=
+//
+// - status / priority accept a comma separated list
+// - due_before / due_after filter on due_at (RFC3339 or YYYY-MM-DD)
+// - assignee_id only returns tasks assigned to that user
+// - parent_id only returns subtasks of that task ("none" for top-level tasks)
+// - sort is one of created_at (default), due_at, priority, status, title, position; order asc|desc
+// - sort=position returns tasks in board order, column by column, unplaced tasks last
+// - returns a page envelope (see pagination.go)
 func GetTasksByEvent(c *gin.Context) {
-	// returns tasks for a given event (any authenticated user)
+	// returns tasks for a given event (event members only)
+	userID, ok := getUserIDFromContext(c)
+	if !ok {
+		jsonError(c, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
 	idParam := c.Param("id")
 	eventID64, err := strconv.ParseUint(idParam, 10, 64)
 	if err != nil {
@@ -484,177 +1078,379 @@ func GetTasksByEvent(c *gin.Context) {
 	}
 	eventID := uint(eventID64)
 
-	var tasks []Task
-	if err := DB.Where("event_id = ?", eventID).Find(&tasks).Error; err != nil {
-		jsonError(c, http.StatusInternalServerError, "db error: "+err.Error())
+	if _, ok := findMemberEvent(c, eventID, userID); !ok {
 		return
 	}
-	c.JSON(http.StatusOK, tasks)
-}
 
-// -----------------------------
-// Search (events and tasks)
-// -----------------------------
-//
-// GET /api/events/search?keyword=&start_date=&end_date=&role=organizer|attendee&type=event|task|both
-//
-// - keyword searches event.title, event.description, task.title, task.description (depending on type)
-// - date filters event.date
-// - role filters results where user is organizer or attendee (based on the authenticated user)
-// - returns [] of { type: "event"/"task", event: {...} } or { type: "task", task: {...}, event: {...} }
-//
-type SearchRequest struct {
-	Keyword   string `form:"keyword" json:"keyword"`
-	StartDate string `form:"start_date" json:"start_date"`
-	EndDate   string `form:"end_date" json:"end_date"`
-	Role      string `form:"role" json:"role"`
-	Type      string `form:"type" json:"type"`
-}
-
-func SearchHandler(c *gin.Context) {
-	userID, ok := getUserIDFromContext(c)
-	if !ok {
-		jsonError(c, http.StatusUnauthorized, "unauthorized")
-		return
-	}
+	query := DB.Preload("Assignees").Preload("Checklist", func(db *gorm.DB) *gorm.DB {
+		return db.Order("position asc, id asc")
+	}).Where("event_id = ?", eventID)
 
-	var req SearchRequest
-	// support query params (GET) and JSON (POST)
-	if c.Request.Method == http.MethodGet {
-		if err := c.ShouldBindQuery(&req); err != nil {
-			// ignore, we'll validate later
-			_ = err
-		}
-	} else {
-		if err := c.ShouldBindJSON(&req); err != nil {
-			_ = err
-			_ = c.ShouldBindQuery(&req)
+	if v := c.Query("parent_id"); v == "none" {
+		query = query.Where("parent_id IS NULL")
+	} else if v != "" {
+		parentID, err := strconv.ParseUint(v, 10, 64)
+		if err != nil {
+			jsonError(c, http.StatusBadRequest, "invalid parent_id")
+			return
 		}
+		query = query.Where("parent_id = ?", parentID)
 	}
 
-	// default type
-	if req.Type == "" {
-		req.Type = "both"
+	if v := c.Query("assignee_id"); v != "" {
+		assigneeID, err := strconv.ParseUint(v, 10, 64)
+		if err != nil {
+			jsonError(c, http.StatusBadRequest, "invalid assignee_id")
+			return
+		}
+		query = query.Where("id IN (?)", DB.Model(&TaskAssignee{}).Select("task_id").Where("user_id = ?", assigneeID))
 	}
 
-	// parse dates (accept RFC3339 or YYYY-MM-DD)
-	var start, end time.Time
-	var err error
-	if req.StartDate != "" {
-		start, err = time.Parse(time.RFC3339, req.StartDate)
-		if err != nil {
-			start, err = time.Parse("2006-01-02", req.StartDate)
-			if err != nil {
-				jsonError(c, http.StatusBadRequest, "invalid start_date format")
+	if v := c.Query("status"); v != "" {
+		statuses := splitList(strings.ToLower(v))
+		for _, s := range statuses {
+			if !taskStatuses[s] {
+				jsonError(c, http.StatusBadRequest, "status must be one of: todo, in-progress, done, blocked")
 				return
 			}
 		}
+		query = query.Where("status IN ?", statuses)
 	}
-	if req.EndDate != "" {
-		end, err = time.Parse(time.RFC3339, req.EndDate)
-		if err != nil {
-			end, err = time.Parse("2006-01-02", req.EndDate)
-			if err != nil {
-				jsonError(c, http.StatusBadRequest, "invalid end_date format")
+	if v := c.Query("priority"); v != "" {
+		priorities := splitList(strings.ToLower(v))
+		for _, p := range priorities {
+			if !taskPriorities[p] {
+				jsonError(c, http.StatusBadRequest, "priority must be one of: low, medium, high, urgent")
 				return
 			}
 		}
-		// include whole day
-		end = end.Add(23*time.Hour + 59*time.Minute + 59*time.Second)
+		query = query.Where("priority IN ?", priorities)
+	}
+	if v := c.Query("due_before"); v != "" {
+		t, err := parseDateInput(v)
+		if err != nil {
+			jsonError(c, http.StatusBadRequest, "invalid due_before format")
+			return
+		}
+		query = query.Where("due_at IS NOT NULL AND due_at <= ?", t)
+	}
+	if v := c.Query("due_after"); v != "" {
+		t, err := parseDateInput(v)
+		if err != nil {
+			jsonError(c, http.StatusBadRequest, "invalid due_after format")
+			return
+		}
+		query = query.Where("due_at IS NOT NULL AND due_at >= ?", t)
+	}
+
+	order := strings.ToLower(c.DefaultQuery("order", "asc"))
+	if order != "asc" && order != "desc" {
+		jsonError(c, http.StatusBadRequest, "order must be 'asc' or 'desc'")
+		return
+	}
+	desc := order == "desc"
+	page, ok := parsePageRequest(c, taskListSorts(desc), "created_at")
+	if !ok {
+		return
+	}
+
+	positions := make(map[uint]string)
+	if page.Sort == "position" {
+		var columns []BoardColumn
+		if err := DB.Where("event_id = ?", eventID).Find(&columns).Error; err != nil {
+			jsonError(c, http.StatusInternalServerError, "db error: "+err.Error())
+			return
+		}
+		for _, col := range columns {
+			positions[col.ID] = col.Position
+		}
 	}
 
-	keyword := strings.TrimSpace(req.Keyword)
-	kw := "%" + keyword + "%"
+	tasks, next, total, ok := pageQuery(c, page, query, "tasks.id", func(t Task) (interface{}, uint) {
+		return taskSortKey(page.Sort, desc, t, positions), t.ID
+	})
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, pageEnvelope(tasks, next, total))
+}
+
+// noDueDate stands in for a missing due date so tasks without one sort last
+// in either direction; noDueDateSQL must stay in step.
+var noDueDate = map[bool]time.Time{
+	false: time.Date(9999, 12, 31, 0, 0, 0, 0, time.UTC),
+	true:  time.Date(1, 1, 1, 0, 0, 0, 0, time.UTC),
+}
 
-	results := make([]interface{}, 0)
+var noDueDateSQL = map[bool]string{
+	false: "'9999-12-31 00:00:00+00'::timestamptz",
+	true:  "'0001-01-01 00:00:00+00'::timestamptz",
+}
 
-	// Helper: check role filtering condition for events/tasks
-	// If req.Role == "organizer" -> only events where OrganizerID = userID
-	// If req.Role == "attendee" -> only events where user is attendee
-	// If empty -> no role filtering
+const (
+	taskPriorityRank = "CASE tasks.priority WHEN 'low' THEN 0 WHEN 'medium' THEN 1 WHEN 'high' THEN 2 WHEN 'urgent' THEN 3 ELSE 4 END"
+	taskStatusRank   = "CASE tasks.status WHEN 'todo' THEN 0 WHEN 'in-progress' THEN 1 WHEN 'blocked' THEN 2 WHEN 'done' THEN 3 ELSE 4 END"
+)
 
-	// Search events
-	if req.Type == "both" || req.Type == "event" {
-		query := DB.Model(&Event{}).Preload("Tasks")
+var taskPriorityOrder = map[string]float64{"low": 0, "medium": 1, "high": 2, "urgent": 3}
+var taskStatusOrder = map[string]float64{"todo": 0, "in-progress": 1, "blocked": 2, "done": 3}
+
+// taskListSorts are the sort options of task lists in the given direction.
+// Every key is a single non-null expression so it works as a keyset: tasks
+// without a due date or a board column sort last, priority and status sort
+// in their natural order, and position sorts by board column, then position
+// in the column (see rank.go; ' ' sorts before every rank digit).
+func taskListSorts(desc bool) map[string]sortSpec {
+	noColumn := "'~'"
+	if desc {
+		noColumn = "''"
+	}
+	return map[string]sortSpec{
+		"created_at": {column: "tasks.created_at", desc: desc, key: "time"},
+		"due_at":     {column: "coalesce(tasks.due_at, " + noDueDateSQL[desc] + ")", desc: desc, key: "time"},
+		"priority":   {column: taskPriorityRank, desc: desc, key: "float"},
+		"status":     {column: taskStatusRank, desc: desc, key: "float"},
+		"title":      {column: "tasks.title", desc: desc, key: "text"},
+		"position": {column: "((coalesce((SELECT bc.position FROM board_columns bc WHERE bc.id = tasks.column_id), " + noColumn +
+			") || ' ' || tasks.position) COLLATE \"C\")", desc: desc, key: "text"},
+	}
+}
 
-		if keyword != "" {
-			query = query.Where("title ILIKE ? OR description ILIKE ?", kw, kw)
+// taskSortKey is the Go counterpart of the taskListSorts expressions.
+// positions maps board column IDs to their positions.
+func taskSortKey(sort string, desc bool, t Task, positions map[uint]string) interface{} {
+	switch sort {
+	case "due_at":
+		if t.DueAt == nil {
+			return noDueDate[desc]
 		}
-		if !start.IsZero() {
-			query = query.Where("date >= ?", start)
+		return *t.DueAt
+	case "priority":
+		if rank, ok := taskPriorityOrder[t.Priority]; ok {
+			return rank
 		}
-		if !end.IsZero() {
-			query = query.Where("date <= ?", end)
+		return 4.0
+	case "status":
+		if rank, ok := taskStatusOrder[t.Status]; ok {
+			return rank
 		}
-
-		if req.Role != "" {
-			if req.Role == "organizer" {
-				query = query.Where("organizer_id = ?", userID)
-			} else if req.Role == "attendee" {
-				// join with attendees table
-				query = query.Joins("JOIN event_attendees ea ON ea.event_id = events.id").
-					Where("ea.user_id = ? AND ea.role = ?", userID, "attendee")
-			} else {
-				jsonError(c, http.StatusBadRequest, "role must be 'organizer' or 'attendee'")
-				return
+		return 4.0
+	case "title":
+		return t.Title
+	case "position":
+		column := "~"
+		if desc {
+			column = ""
+		}
+		if t.ColumnID != nil {
+			if p, ok := positions[*t.ColumnID]; ok {
+				column = p
 			}
 		}
+		return column + " " + t.Position
+	default:
+		return t.CreatedAt
+	}
+}
+
+// findTask loads the :task_id path param task of an event. Writes a 400/404/500 response on failure.
+func findTask(c *gin.Context, eventID uint) (Task, bool) {
+	var task Task
+	taskID64, err := strconv.ParseUint(c.Param("task_id"), 10, 64)
+	if err != nil {
+		jsonError(c, http.StatusBadRequest, "invalid task id")
+		return task, false
+	}
+	if err := DB.Where("id = ? AND event_id = ?", taskID64, eventID).First(&task).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			jsonError(c, http.StatusNotFound, "task not found")
+			return task, false
+		}
+		jsonError(c, http.StatusInternalServerError, "db error: "+err.Error())
+		return task, false
+	}
+	return task, true
+}
+
+// UpdateTaskRequest only changes the fields that are present.
+type UpdateTaskRequest struct {
+	Title       *string `json:"title"`
+	Description *string `json:"description"`
+	Status      *string `json:"status"`
+	Priority    *string `json:"priority"`
+	DueAt       *string `json:"due_at"`    // empty string clears the due date
+	ParentID    *uint   `json:"parent_id"` // 0 turns a subtask back into a top-level task
+}
+
+// PATCH /api/events/:id/tasks/:task_id
+// The organizer can change any field; assignees can only change the status.
+func UpdateTask(c *gin.Context) {
+	userID, ok := getUserIDFromContext(c)
+	if !ok {
+		jsonError(c, http.StatusUnauthorized, "unauthorized")
+		return
+	}
 
-		var events []Event
-		if err := query.Order("date asc").Find(&events).Error; err != nil {
+	eventID, ok := parseEventID(c)
+	if !ok {
+		return
+	}
+	ev, ok := findMemberEvent(c, eventID, userID)
+	if !ok {
+		return
+	}
+	task, ok := findTask(c, eventID)
+	if !ok {
+		return
+	}
+
+	var body UpdateTaskRequest
+	if err := c.ShouldBindJSON(&body); err != nil {
+		jsonError(c, http.StatusBadRequest, "invalid body: "+err.Error())
+		return
+	}
+
+	if ev.OrganizerID != userID {
+		assigned, err := isTaskAssignee(task.ID, userID)
+		if err != nil {
 			jsonError(c, http.StatusInternalServerError, "db error: "+err.Error())
 			return
 		}
-		for _, e := range events {
-			results = append(results, gin.H{"type": "event", "event": e})
+		if !assigned {
+			jsonError(c, http.StatusForbidden, "only organizer or assignees can update tasks")
+			return
+		}
+		if body.Title != nil || body.Description != nil || body.Priority != nil || body.DueAt != nil || body.ParentID != nil {
+			jsonError(c, http.StatusForbidden, "assignees can only change the task status")
+			return
 		}
 	}
 
-	// Search tasks (and attach event info)
-	if req.Type == "both" || req.Type == "task" {
-		// We'll find tasks joining with events to apply date filters and role constraints
-		taskQuery := DB.Model(&Task{}).Joins("JOIN events ON events.id = tasks.event_id")
-
-		if keyword != "" {
-			// search task title/description or parent event title/description
-			taskQuery = taskQuery.Where("tasks.title ILIKE ? OR tasks.description ILIKE ? OR events.title ILIKE ? OR events.description ILIKE ?", kw, kw, kw, kw)
-		}
-		if !start.IsZero() {
-			taskQuery = taskQuery.Where("events.date >= ?", start)
+	if body.Title != nil {
+		title := strings.TrimSpace(*body.Title)
+		if title == "" {
+			jsonError(c, http.StatusBadRequest, "title cannot be empty")
+			return
 		}
-		if !end.IsZero() {
-			taskQuery = taskQuery.Where("events.date <= ?", end)
+		task.Title = title
+	}
+	if body.Description != nil {
+		task.Description = *body.Description
+	}
+	if body.Status != nil {
+		status := strings.ToLower(strings.TrimSpace(*body.Status))
+		if !taskStatuses[status] {
+			jsonError(c, http.StatusBadRequest, "status must be one of: todo, in-progress, done, blocked")
+			return
 		}
-		if req.Role != "" {
-			if req.Role == "organizer" {
-				taskQuery = taskQuery.Where("events.organizer_id = ?", userID)
-			} else if req.Role == "attendee" {
-				// ensure user is attendee in event_attendees
-				taskQuery = taskQuery.Joins("JOIN event_attendees ea ON ea.event_id = events.id").
-					Where("ea.user_id = ? AND ea.role = ?", userID, "attendee")
-			} else {
-				jsonError(c, http.StatusBadRequest, "role must be 'organizer' or 'attendee'")
+		// prerequisites have to be finished first
+		if status == "done" && task.Status != "done" {
+			open, err := openPrerequisites(task.ID)
+			if err != nil {
+				jsonError(c, http.StatusInternalServerError, "db error: "+err.Error())
+				return
+			}
+			if len(open) > 0 {
+				titles := make([]string, 0, len(open))
+				for _, t := range open {
+					titles = append(titles, t.Title)
+				}
+				jsonError(c, http.StatusConflict, "prerequisites are not done yet: "+strings.Join(titles, ", "))
 				return
 			}
 		}
-
-		// fetch matching tasks
-		var tasks []Task
-		if err := taskQuery.Select("tasks.*").Order("events.date asc").Find(&tasks).Error; err != nil {
-			jsonError(c, http.StatusInternalServerError, "db error: "+err.Error())
+		setTaskStatus(&task, status)
+	}
+	if body.Priority != nil {
+		priority := strings.ToLower(strings.TrimSpace(*body.Priority))
+		if !taskPriorities[priority] {
+			jsonError(c, http.StatusBadRequest, "priority must be one of: low, medium, high, urgent")
 			return
 		}
-
-		// attach event data for each task
-		for _, t := range tasks {
-			var ev Event
-			if err := DB.Where("id = ?", t.EventID).First(&ev).Error; err != nil {
-				// skip if cannot find parent event
-				continue
+		task.Priority = priority
+	}
+	if body.ParentID != nil {
+		if *body.ParentID == 0 {
+			task.ParentID = nil
+		} else {
+			var parent Task
+			if err := DB.Where("id = ? AND event_id = ?", *body.ParentID, eventID).First(&parent).Error; err != nil {
+				if err == gorm.ErrRecordNotFound {
+					jsonError(c, http.StatusBadRequest, "parent must be a task of the same event")
+					return
+				}
+				jsonError(c, http.StatusInternalServerError, "db error: "+err.Error())
+				return
 			}
-			results = append(results, gin.H{"type": "task", "task": t, "event": ev})
+			cycle, err := parentCreatesCycle(task.ID, parent.ID)
+			if err != nil {
+				jsonError(c, http.StatusInternalServerError, "db error: "+err.Error())
+				return
+			}
+			if cycle {
+				jsonError(c, http.StatusConflict, "a task cannot be nested under itself or its subtasks")
+				return
+			}
+			task.ParentID = &parent.ID
 		}
 	}
+	if body.DueAt != nil {
+		if *body.DueAt == "" {
+			task.DueAt = nil
+		} else {
+			due, err := parseDateInput(*body.DueAt)
+			if err != nil {
+				jsonError(c, http.StatusBadRequest, "invalid due_at format (use RFC3339 or YYYY-MM-DD)")
+				return
+			}
+			task.DueAt = &due
+		}
+	}
+
+	if err := DB.Omit("Assignees", "Checklist").Save(&task).Error; err != nil {
+		jsonError(c, http.StatusInternalServerError, "could not update task: "+err.Error())
+		return
+	}
+	if err := DB.Where("task_id = ?", task.ID).Find(&task.Assignees).Error; err != nil {
+		jsonError(c, http.StatusInternalServerError, "db error: "+err.Error())
+		return
+	}
+	c.JSON(http.StatusOK, task)
+}
 
-	c.JSON(http.StatusOK, results)
+// DELETE /api/events/:id/tasks/:task_id
+func DeleteTask(c *gin.Context) {
+	userID, ok := getUserIDFromContext(c)
+	if !ok {
+		jsonError(c, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	eventID, ok := parseEventID(c)
+	if !ok {
+		return
+	}
+	ev, ok := findMemberEvent(c, eventID, userID)
+	if !ok {
+		return
+	}
+	if ev.OrganizerID != userID {
+		jsonError(c, http.StatusForbidden, "only organizer can delete tasks")
+		return
+	}
+	task, ok := findTask(c, eventID)
+	if !ok {
+		return
+	}
+
+	if err := DB.Transaction(func(tx *gorm.DB) error {
+		if err := deleteTaskLinks(tx, []Task{task}); err != nil {
+			return err
+		}
+		return tx.Delete(&task).Error
+	}); err != nil {
+		jsonError(c, http.StatusInternalServerError, "delete failed: "+err.Error())
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "task deleted"})
 }
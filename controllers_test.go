@@ -0,0 +1,456 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// authFixture is a private event organized by Organizer, with Member invited
+// and Outsider unrelated to it.
+type authFixture struct {
+	Organizer, Member, Outsider User
+	Event                       Event
+	Task                        Task
+}
+
+func newAuthFixture(t *testing.T) authFixture {
+	t.Helper()
+	useTestDB(t)
+	f := authFixture{
+		Organizer: createTestUser(t, "Organizer"),
+		Member:    createTestUser(t, "Member"),
+		Outsider:  createTestUser(t, "Outsider"),
+	}
+	f.Event = createTestEvent(t, f.Organizer.ID, "Private party", false)
+	inviteTestUser(t, f.Event.ID, f.Member.ID, "attendee")
+	f.Task = createTestTask(t, f.Event.ID, "Buy snacks")
+	return f
+}
+
+// Every handler in controllers.go that takes an event ID must answer a
+// non-member exactly like a missing event, and a member who is not the
+// organizer with 403 where the action is organizer-only.
+func TestEventHandlersAuthorization(t *testing.T) {
+	cases := []struct {
+		handler   string
+		method    string
+		path      string // formatted with the event ID and the task ID
+		body      func(f authFixture) gin.H
+		member    int // expected status for the invited member
+		organizer int // expected status for the organizer
+	}{
+		{"DeleteEvent", http.MethodDelete, "/api/events/%d", nil, http.StatusForbidden, http.StatusOK},
+		{"SetRSVPDeadline", http.MethodPut, "/api/events/%d/rsvp-deadline",
+			func(authFixture) gin.H { return gin.H{} }, http.StatusForbidden, http.StatusOK},
+		{"SetEventVisibility", http.MethodPut, "/api/events/%d/visibility",
+			func(authFixture) gin.H { return gin.H{"is_public": true} }, http.StatusForbidden, http.StatusOK},
+		{"InviteUser", http.MethodPost, "/api/events/%d/invite",
+			func(f authFixture) gin.H { return gin.H{"user_id": f.Outsider.ID} }, http.StatusForbidden, http.StatusOK},
+		{"SetAttendance", http.MethodPost, "/api/events/%d/respond",
+			func(authFixture) gin.H { return gin.H{"status": "Going"} }, http.StatusOK, http.StatusOK},
+		{"SetAttendance (on behalf)", http.MethodPost, "/api/events/%d/respond",
+			func(f authFixture) gin.H { return gin.H{"status": "Maybe", "user_id": f.Member.ID} }, http.StatusOK, http.StatusOK},
+		{"GetEventAttendees", http.MethodGet, "/api/events/%d/attendees", nil, http.StatusForbidden, http.StatusOK},
+		{"CreateTask", http.MethodPost, "/api/events/%d/tasks",
+			func(authFixture) gin.H { return gin.H{"title": "Book DJ"} }, http.StatusForbidden, http.StatusCreated},
+		{"GetTasksByEvent", http.MethodGet, "/api/events/%d/tasks", nil, http.StatusOK, http.StatusOK},
+		{"UpdateTask", http.MethodPatch, "/api/events/%d/tasks/%d",
+			func(authFixture) gin.H { return gin.H{"status": "done"} }, http.StatusForbidden, http.StatusOK},
+		{"DeleteTask", http.MethodDelete, "/api/events/%d/tasks/%d", nil, http.StatusForbidden, http.StatusOK},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.handler, func(t *testing.T) {
+			f := newAuthFixture(t)
+			r := testRouter()
+			var body gin.H
+			if tc.body != nil {
+				body = tc.body(f)
+			}
+			path := func(eventID uint) string {
+				return fmt.Sprintf(tc.path, eventID, f.Task.ID)
+			}
+
+			missing := apiRequest(t, r, f.Outsider.ID, tc.method, path(f.Event.ID+1000), body)
+			hidden := apiRequest(t, r, f.Outsider.ID, tc.method, path(f.Event.ID), body)
+			if missing.Code != http.StatusNotFound {
+				t.Fatalf("missing event: got %d %s, want 404", missing.Code, missing.Body)
+			}
+			if hidden.Code != missing.Code || hidden.Body.String() != missing.Body.String() {
+				t.Fatalf("non-member got %d %s, want the missing-event response %d %s",
+					hidden.Code, hidden.Body, missing.Code, missing.Body)
+			}
+
+			if w := apiRequest(t, r, f.Member.ID, tc.method, path(f.Event.ID), body); w.Code != tc.member {
+				t.Fatalf("member: got %d %s, want %d", w.Code, w.Body, tc.member)
+			}
+			if w := apiRequest(t, r, f.Organizer.ID, tc.method, path(f.Event.ID), body); w.Code != tc.organizer {
+				t.Fatalf("organizer: got %d %s, want %d", w.Code, w.Body, tc.organizer)
+			}
+		})
+	}
+}
+
+// The event routes outside controllers.go follow the same rules. Paths are
+// formatted with the event ID and the task ID; other sub-resource IDs are
+// checked after the event, so any value does. A status of 0 is not checked,
+// because the answer depends on data the fixture does not create.
+func TestEventSubresourceAuthorization(t *testing.T) {
+	cases := []struct {
+		method    string
+		path      string
+		body      func(eventID uint) gin.H
+		member    int
+		organizer int
+	}{
+		{http.MethodPut, "/api/events/%d/category", nil, http.StatusForbidden, 0},
+		{http.MethodPut, "/api/events/%d/tags", nil, http.StatusForbidden, 0},
+		{http.MethodPut, "/api/events/%d/venue", nil, http.StatusForbidden, 0},
+		{http.MethodPost, "/api/events/%d/clone", nil, http.StatusForbidden, 0},
+		{http.MethodPost, "/api/templates", func(eventID uint) gin.H { return gin.H{"name": "Party", "from_event_id": eventID} },
+			http.StatusForbidden, http.StatusCreated},
+		{http.MethodGet, "/api/events/%d/attendees/export", nil, http.StatusForbidden, 0},
+		{http.MethodGet, "/api/events/%d/rsvp-history", nil, http.StatusForbidden, 0},
+		{http.MethodGet, "/api/events/%d/analytics", nil, http.StatusForbidden, 0},
+		{http.MethodGet, "/api/events/%d/availability", nil, http.StatusForbidden, 0},
+		{http.MethodGet, "/api/events/%d/checkin/qr", nil, http.StatusOK, http.StatusOK},
+		{http.MethodPost, "/api/events/%d/checkin", nil, http.StatusForbidden, 0},
+		{http.MethodGet, "/api/events/%d/checkin/stats", nil, http.StatusForbidden, http.StatusOK},
+		{http.MethodPut, "/api/events/%d/helpers", nil, http.StatusForbidden, 0},
+		{http.MethodPut, "/api/events/%d/questionnaire", nil, http.StatusForbidden, 0},
+		{http.MethodGet, "/api/events/%d/questionnaire", nil, http.StatusOK, http.StatusOK},
+		{http.MethodGet, "/api/events/%d/questionnaire/summary", nil, http.StatusForbidden, http.StatusOK},
+		{http.MethodGet, "/api/events/%d/questionnaire/export", nil, http.StatusForbidden, http.StatusOK},
+		{http.MethodPut, "/api/events/%d/tasks/%d/assignees", nil, http.StatusForbidden, 0},
+		{http.MethodGet, "/api/events/%d/tasks/graph", nil, http.StatusOK, http.StatusOK},
+		{http.MethodPost, "/api/events/%d/tasks/%d/dependencies", nil, http.StatusForbidden, 0},
+		{http.MethodDelete, "/api/events/%d/tasks/%d/dependencies/1", nil, http.StatusForbidden, 0},
+		{http.MethodPost, "/api/events/%d/tasks/%d/checklist", nil, http.StatusForbidden, 0},
+		{http.MethodPatch, "/api/events/%d/tasks/%d/checklist/1", nil, 0, 0},
+		{http.MethodDelete, "/api/events/%d/tasks/%d/checklist/1", nil, http.StatusForbidden, 0},
+		{http.MethodPost, "/api/events/%d/tasks/%d/move", nil, http.StatusForbidden, 0},
+		{http.MethodGet, "/api/events/%d/board", nil, http.StatusOK, http.StatusOK},
+		{http.MethodPost, "/api/events/%d/board/columns", nil, http.StatusForbidden, 0},
+		{http.MethodPatch, "/api/events/%d/board/columns/1", nil, http.StatusForbidden, 0},
+		{http.MethodDelete, "/api/events/%d/board/columns/1", nil, http.StatusForbidden, 0},
+		{http.MethodGet, "/api/events/%d/comments", nil, http.StatusOK, http.StatusOK},
+		{http.MethodGet, "/api/events/%d/attachments", nil, http.StatusOK, http.StatusOK},
+		{http.MethodPost, "/api/events/%d/polls", nil, http.StatusForbidden, 0},
+		{http.MethodGet, "/api/events/%d/polls", nil, http.StatusOK, http.StatusOK},
+		{http.MethodPost, "/api/events/%d/polls/1/finalize", nil, http.StatusForbidden, 0},
+		{http.MethodGet, "/api/events/%d/budget/rates", nil, http.StatusForbidden, http.StatusOK},
+		{http.MethodPut, "/api/events/%d/budget/rates", nil, http.StatusForbidden, 0},
+		{http.MethodPost, "/api/events/%d/budget/items", nil, http.StatusForbidden, 0},
+		{http.MethodGet, "/api/events/%d/budget/items", nil, http.StatusForbidden, http.StatusOK},
+		{http.MethodPatch, "/api/events/%d/budget/items/1", nil, http.StatusForbidden, 0},
+		{http.MethodDelete, "/api/events/%d/budget/items/1", nil, http.StatusForbidden, 0},
+		{http.MethodGet, "/api/events/%d/budget/report", nil, http.StatusForbidden, 0},
+		{http.MethodPost, "/api/events/%d/expenses", nil, http.StatusForbidden, 0},
+		{http.MethodGet, "/api/events/%d/expenses", nil, http.StatusForbidden, http.StatusOK},
+		{http.MethodPatch, "/api/events/%d/expenses/1", nil, http.StatusForbidden, 0},
+		{http.MethodDelete, "/api/events/%d/expenses/1", nil, http.StatusForbidden, 0},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.method+" "+tc.path, func(t *testing.T) {
+			f := newAuthFixture(t)
+			r := testRouter()
+			request := func(userID, eventID uint) *httptest.ResponseRecorder {
+				path := tc.path
+				if strings.Contains(path, "%d") {
+					if strings.Count(path, "%d") == 2 {
+						path = fmt.Sprintf(path, eventID, f.Task.ID)
+					} else {
+						path = fmt.Sprintf(path, eventID)
+					}
+				}
+				var body gin.H
+				if tc.body != nil {
+					body = tc.body(eventID)
+				}
+				return apiRequest(t, r, userID, tc.method, path, body)
+			}
+
+			missing := request(f.Outsider.ID, f.Event.ID+1000)
+			hidden := request(f.Outsider.ID, f.Event.ID)
+			if missing.Code != http.StatusNotFound {
+				t.Fatalf("missing event: got %d %s, want 404", missing.Code, missing.Body)
+			}
+			if hidden.Code != missing.Code || hidden.Body.String() != missing.Body.String() {
+				t.Fatalf("non-member got %d %s, want the missing-event response %d %s",
+					hidden.Code, hidden.Body, missing.Code, missing.Body)
+			}
+			if tc.member != 0 {
+				if w := request(f.Member.ID, f.Event.ID); w.Code != tc.member {
+					t.Fatalf("member: got %d %s, want %d", w.Code, w.Body, tc.member)
+				}
+			}
+			if tc.organizer != 0 {
+				if w := request(f.Organizer.ID, f.Event.ID); w.Code != tc.organizer {
+					t.Fatalf("organizer: got %d %s, want %d", w.Code, w.Body, tc.organizer)
+				}
+			}
+		})
+	}
+}
+
+func TestSetAttendanceOnBehalfNeedsOrganizer(t *testing.T) {
+	f := newAuthFixture(t)
+	r := testRouter()
+
+	w := apiRequest(t, r, f.Member.ID, http.MethodPost, fmt.Sprintf("/api/events/%d/respond", f.Event.ID),
+		gin.H{"status": "Going", "user_id": f.Organizer.ID})
+	if w.Code != http.StatusForbidden {
+		t.Fatalf("got %d %s, want 403", w.Code, w.Body)
+	}
+}
+
+// Answering for an invitee must not show the organizer the invitee's
+// other events.
+func TestSetAttendanceOnBehalfHidesConflicts(t *testing.T) {
+	f := newAuthFixture(t)
+	r := testRouter()
+	other := createTestEvent(t, f.Outsider.ID, "Outsider's dinner", false)
+	inviteTestUser(t, other.ID, f.Member.ID, "attendee")
+	if err := DB.Model(&EventAttendee{}).Where("event_id = ? AND user_id = ?", other.ID, f.Member.ID).
+		Update("status", "Going").Error; err != nil {
+		t.Fatal(err)
+	}
+
+	w := apiRequest(t, r, f.Organizer.ID, http.MethodPost, fmt.Sprintf("/api/events/%d/respond", f.Event.ID),
+		gin.H{"status": "Going", "user_id": f.Member.ID})
+	if w.Code != http.StatusOK {
+		t.Fatalf("got %d %s, want 200", w.Code, w.Body)
+	}
+	var att EventAttendee
+	decodeJSON(t, w, &att)
+	if len(att.Conflicts) != 0 {
+		t.Fatalf("organizer saw the invitee's conflicts: %s", w.Body)
+	}
+
+	w = apiRequest(t, r, f.Member.ID, http.MethodPost, fmt.Sprintf("/api/events/%d/respond", f.Event.ID),
+		gin.H{"status": "Maybe"})
+	decodeJSON(t, w, &att)
+	if w.Code != http.StatusOK || len(att.Conflicts) != 1 || att.Conflicts[0].EventID != other.ID {
+		t.Fatalf("own RSVP: got %d %s, want 200 with the dinner as conflict", w.Code, w.Body)
+	}
+}
+
+func TestSetAttendanceDoesNotMakeOutsidersMembers(t *testing.T) {
+	f := newAuthFixture(t)
+	r := testRouter()
+
+	w := apiRequest(t, r, f.Outsider.ID, http.MethodPost, fmt.Sprintf("/api/events/%d/respond", f.Event.ID),
+		gin.H{"status": "Not Going"})
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("respond: got %d %s, want 404", w.Code, w.Body)
+	}
+	var count int64
+	DB.Model(&EventAttendee{}).Where("event_id = ? AND user_id = ?", f.Event.ID, f.Outsider.ID).Count(&count)
+	if count != 0 {
+		t.Fatalf("outsider got an attendee row")
+	}
+	if w := apiRequest(t, r, f.Outsider.ID, http.MethodGet, fmt.Sprintf("/api/events/%d/tasks", f.Event.ID), nil); w.Code != http.StatusNotFound {
+		t.Fatalf("tasks after respond: got %d %s, want 404", w.Code, w.Body)
+	}
+}
+
+func TestSetAttendanceOnPublicEvent(t *testing.T) {
+	f := newAuthFixture(t)
+	r := testRouter()
+	public := createTestEvent(t, f.Organizer.ID, "Street fair", true)
+
+	w := apiRequest(t, r, f.Outsider.ID, http.MethodPost, fmt.Sprintf("/api/events/%d/respond", public.ID),
+		gin.H{"status": "Going"})
+	if w.Code != http.StatusOK {
+		t.Fatalf("got %d %s, want 200", w.Code, w.Body)
+	}
+}
+
+// GetOrganizedEvents, GetInvitedEvents and CreateEvent only ever show the
+// caller's own events.
+func TestEventListsAreScopedToCaller(t *testing.T) {
+	f := newAuthFixture(t)
+	r := testRouter()
+
+	w := apiRequest(t, r, f.Outsider.ID, http.MethodPost, "/api/events",
+		gin.H{"title": "Outsider meetup", "date": "2030-01-02T18:00:00Z"})
+	if w.Code != http.StatusCreated {
+		t.Fatalf("create: got %d %s", w.Code, w.Body)
+	}
+	var created Event
+	decodeJSON(t, w, &created)
+	if created.OrganizerID != f.Outsider.ID {
+		t.Fatalf("created event organizer = %d, want %d", created.OrganizerID, f.Outsider.ID)
+	}
+
+	listIDs := func(userID uint, path string) []uint {
+		t.Helper()
+		w := apiRequest(t, r, userID, http.MethodGet, path, nil)
+		if w.Code != http.StatusOK {
+			t.Fatalf("%s: got %d %s", path, w.Code, w.Body)
+		}
+		var page struct {
+			Items []Event `json:"items"`
+		}
+		decodeJSON(t, w, &page)
+		ids := make([]uint, 0, len(page.Items))
+		for _, ev := range page.Items {
+			ids = append(ids, ev.ID)
+		}
+		return ids
+	}
+
+	expect := func(name string, got []uint, want ...uint) {
+		t.Helper()
+		if !sameIDs(got, want) {
+			t.Fatalf("%s: got events %v, want %v", name, got, want)
+		}
+	}
+	expect("organizer organized", listIDs(f.Organizer.ID, "/api/events/organized"), f.Event.ID)
+	expect("member invited", listIDs(f.Member.ID, "/api/events/invited"), f.Event.ID)
+	expect("member organized", listIDs(f.Member.ID, "/api/events/organized"))
+	expect("outsider organized", listIDs(f.Outsider.ID, "/api/events/organized"), created.ID)
+	expect("outsider invited", listIDs(f.Outsider.ID, "/api/events/invited"))
+}
+
+type taskPage struct {
+	Items      []Task  `json:"items"`
+	NextCursor *string `json:"next_cursor"`
+}
+
+func taskTitles(tasks []Task) string {
+	titles := make([]string, 0, len(tasks))
+	for _, t := range tasks {
+		titles = append(titles, t.Title)
+	}
+	return strings.Join(titles, ",")
+}
+
+func TestTaskLifecycle(t *testing.T) {
+	f := newAuthFixture(t)
+	r := testRouter()
+	tasksPath := fmt.Sprintf("/api/events/%d/tasks", f.Event.ID)
+
+	if w := apiRequest(t, r, f.Organizer.ID, http.MethodPost, tasksPath, gin.H{"title": "Bad", "status": "finished"}); w.Code != http.StatusBadRequest {
+		t.Errorf("invalid status: got %d, want 400", w.Code)
+	}
+	if w := apiRequest(t, r, f.Member.ID, http.MethodPost, tasksPath, gin.H{"title": "Sneaky"}); w.Code != http.StatusForbidden {
+		t.Errorf("member create: got %d, want 403", w.Code)
+	}
+
+	w := apiRequest(t, r, f.Organizer.ID, http.MethodPost, tasksPath,
+		gin.H{"title": "Book band", "priority": "HIGH", "due_at": "2030-01-01"})
+	if w.Code != http.StatusCreated {
+		t.Fatalf("create: got %d %s", w.Code, w.Body)
+	}
+	var task Task
+	decodeJSON(t, w, &task)
+	if task.Status != "todo" || task.Priority != "high" || task.DueAt == nil || task.CompletedAt != nil {
+		t.Fatalf("created task = %+v", task)
+	}
+
+	patch := func(userID uint, body gin.H) (Task, int) {
+		t.Helper()
+		var out Task
+		w := apiRequest(t, r, userID, http.MethodPatch, fmt.Sprintf("%s/%d", tasksPath, task.ID), body)
+		if w.Code == http.StatusOK {
+			decodeJSON(t, w, &out)
+		}
+		return out, w.Code
+	}
+	if got, code := patch(f.Organizer.ID, gin.H{"status": "done"}); code != http.StatusOK || got.CompletedAt == nil {
+		t.Errorf("done: got %d, completed_at %v", code, got.CompletedAt)
+	}
+	if got, code := patch(f.Organizer.ID, gin.H{"status": "in-progress", "due_at": ""}); code != http.StatusOK || got.CompletedAt != nil || got.DueAt != nil {
+		t.Errorf("reopen: got %d, completed_at %v, due_at %v", code, got.CompletedAt, got.DueAt)
+	}
+	if _, code := patch(f.Organizer.ID, gin.H{"title": "  "}); code != http.StatusBadRequest {
+		t.Errorf("empty title: got %d, want 400", code)
+	}
+	if _, code := patch(f.Member.ID, gin.H{"status": "done"}); code != http.StatusForbidden {
+		t.Errorf("unassigned member: got %d, want 403", code)
+	}
+
+	if w := apiRequest(t, r, f.Member.ID, http.MethodDelete, fmt.Sprintf("%s/%d", tasksPath, task.ID), nil); w.Code != http.StatusForbidden {
+		t.Errorf("member delete: got %d, want 403", w.Code)
+	}
+	if w := apiRequest(t, r, f.Organizer.ID, http.MethodDelete, fmt.Sprintf("%s/%d", tasksPath, task.ID), nil); w.Code != http.StatusOK {
+		t.Fatalf("delete: got %d %s", w.Code, w.Body)
+	}
+	var page taskPage
+	decodeJSON(t, apiRequest(t, r, f.Organizer.ID, http.MethodGet, tasksPath, nil), &page)
+	if got := taskTitles(page.Items); got != "Buy snacks" {
+		t.Errorf("tasks after delete = %s", got)
+	}
+}
+
+func TestGetTasksByEventFiltersAndSorts(t *testing.T) {
+	f := newAuthFixture(t)
+	r := testRouter()
+	tasksPath := fmt.Sprintf("/api/events/%d/tasks", f.Event.ID)
+
+	for _, body := range []gin.H{
+		{"title": "Venue", "priority": "urgent", "due_at": "2030-03-01", "status": "done"},
+		{"title": "Catering", "priority": "low", "due_at": "2030-01-01"},
+		{"title": "Music", "priority": "high", "status": "blocked"},
+		{"title": "Flyers", "priority": "medium", "due_at": "2030-02-01", "status": "in-progress"},
+	} {
+		if w := apiRequest(t, r, f.Organizer.ID, http.MethodPost, tasksPath, body); w.Code != http.StatusCreated {
+			t.Fatalf("create %v: got %d %s", body["title"], w.Code, w.Body)
+		}
+	}
+
+	cases := []struct {
+		query string
+		want  string
+	}{
+		{"?status=todo,done&sort=title", "Buy snacks,Catering,Venue"},
+		{"?priority=high,urgent&sort=title&order=desc", "Venue,Music"},
+		{"?due_before=2030-02-01&sort=due_at", "Catering,Flyers"},
+		{"?due_after=2030-01-15&sort=due_at&order=desc", "Venue,Flyers"},
+		{"?sort=priority&order=desc", "Venue,Music,Buy snacks,Flyers,Catering"},
+		{"?sort=status", "Buy snacks,Catering,Flyers,Music,Venue"},
+		// tasks without a due date come last either way
+		{"?sort=due_at", "Catering,Flyers,Venue,Buy snacks,Music"},
+		{"?sort=due_at&order=desc", "Venue,Flyers,Catering,Buy snacks,Music"},
+	}
+	for _, tc := range cases {
+		w := apiRequest(t, r, f.Member.ID, http.MethodGet, tasksPath+tc.query, nil)
+		if w.Code != http.StatusOK {
+			t.Fatalf("%s: got %d %s", tc.query, w.Code, w.Body)
+		}
+		var page taskPage
+		decodeJSON(t, w, &page)
+		if got := taskTitles(page.Items); got != tc.want {
+			t.Errorf("%s: got %s, want %s", tc.query, got, tc.want)
+		}
+	}
+
+	// Paging by due date visits every task once, nulls included
+	var titles []string
+	next := ""
+	for i := 0; i < 5; i++ {
+		var page taskPage
+		decodeJSON(t, apiRequest(t, r, f.Member.ID, http.MethodGet, tasksPath+"?sort=due_at&limit=2"+next, nil), &page)
+		for _, task := range page.Items {
+			titles = append(titles, task.Title)
+		}
+		if page.NextCursor == nil {
+			break
+		}
+		next = "&cursor=" + *page.NextCursor
+	}
+	if got := strings.Join(titles, ","); got != "Catering,Flyers,Venue,Buy snacks,Music" {
+		t.Errorf("paged by due date: %s", got)
+	}
+
+	if w := apiRequest(t, r, f.Member.ID, http.MethodGet, tasksPath+"?priority=critical", nil); w.Code != http.StatusBadRequest {
+		t.Errorf("invalid priority filter: got %d, want 400", w.Code)
+	}
+}
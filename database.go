@@ -37,11 +37,34 @@ func InitDB() {
 
 	DB = db
 
-	// Migrate all models
-	err = DB.AutoMigrate(&User{}, &Event{}, &Task{}, &EventAttendee{})
-	if err != nil {
+	if err := migrateDB(DB); err != nil {
 		log.Fatalf("❌ Migration failed: %v", err)
 	}
 
 	fmt.Println("✅ Database connected and migrated successfully")
 }
+
+// migrateDB creates or updates the schema, including the search columns and
+// indexes AutoMigrate doesn't manage.
+func migrateDB(db *gorm.DB) error {
+	err := db.AutoMigrate(
+		&User{}, &Event{}, &Task{}, &EventAttendee{},
+		&QuestionnaireQuestion{}, &AttendeeAnswer{}, &RSVPChange{},
+		&TaskAssignee{}, &ChecklistItem{}, &TaskDependency{}, &BoardColumn{},
+		&EventTemplate{}, &Comment{}, &CommentMention{}, &Attachment{},
+		&DatePoll{}, &DatePollOption{}, &DatePollVote{}, &Notification{},
+		&BudgetItem{}, &Expense{}, &ExchangeRate{}, &SavedSearch{}, &SavedSearchHit{}, &Tag{},
+		&Venue{},
+	)
+	if err != nil {
+		return err
+	}
+
+	if err := setupFullTextSearch(db); err != nil {
+		return fmt.Errorf("full-text search setup: %w", err)
+	}
+	if err := setupFuzzySearch(db); err != nil {
+		return fmt.Errorf("fuzzy search setup: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,277 @@
+package main
+
+import (
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// -----------------------------
+// Free/busy and scheduling conflicts
+// -----------------------------
+//
+// A user is busy during the events they organize and the events they
+// answered Going or Maybe to (Maybe is reported as tentative). Events last
+// from Date to EndsAt, or defaultEventDuration (see venues.go).
+//
+// SetAttendance and CreateEvent check the new commitment against the
+// user's other ones. With SCHEDULE_CONFLICT_POLICY=warn (default) the
+// overlapping commitments are returned in "conflicts"; with =block the
+// request fails with 409.
+
+const maxFreeBusyRange = 92 * 24 * time.Hour
+
+type busyInterval struct {
+	UserID  uint      `json:"-"`
+	EventID uint      `json:"event_id,omitempty"`
+	Title   string    `json:"title,omitempty"`
+	Start   time.Time `json:"start" gorm:"column:starts_at"`
+	End     time.Time `json:"end" gorm:"column:ends_at"`
+	Status  string    `json:"status"` // organizer / Going / Maybe
+}
+
+// blockConflicts reports whether scheduling conflicts reject the request.
+func blockConflicts() bool {
+	return strings.ToLower(strings.TrimSpace(os.Getenv("SCHEDULE_CONFLICT_POLICY"))) == "block"
+}
+
+// busyIntervals returns the commitments of the given users overlapping
+// [from, to), leaving out exceptEventID, ordered by start.
+func busyIntervals(tx *gorm.DB, userIDs []uint, from, to time.Time, exceptEventID uint) ([]busyInterval, error) {
+	out := make([]busyInterval, 0)
+	if len(userIDs) == 0 {
+		return out, nil
+	}
+	organized := tx.Model(&Event{}).
+		Select("id AS event_id, organizer_id AS user_id, 'organizer' AS status").
+		Where("organizer_id IN ?", userIDs)
+	answered := tx.Model(&EventAttendee{}).
+		Select("event_id, user_id, status").
+		Where("user_id IN ? AND role = ? AND status IN ?", userIDs, "attendee", []string{"Going", "Maybe"})
+
+	err := tx.Table("events").
+		Select("b.user_id, events.id AS event_id, events.title, events.date AS starts_at, "+eventEndSQL+" AS ends_at, b.status").
+		Joins("JOIN (? UNION ALL ?) AS b ON b.event_id = events.id", organized, answered).
+		Where("events.id <> ?", exceptEventID).
+		Where("events.date < ? AND "+eventEndSQL+" > ?", to, from).
+		Order("events.date asc, events.id asc").
+		Scan(&out).Error
+	return out, err
+}
+
+// scheduleConflicts returns userID's commitments overlapping ev.
+func scheduleConflicts(tx *gorm.DB, userID uint, ev Event) ([]busyInterval, error) {
+	return busyIntervals(tx, []uint{userID}, ev.Date, eventEnd(ev), ev.ID)
+}
+
+// conflictError writes the 409 used when conflicts block a request.
+func conflictError(c *gin.Context, conflicts []busyInterval) {
+	c.JSON(http.StatusConflict, gin.H{"error": "this overlaps other events you are committed to", "conflicts": conflicts})
+}
+
+// parseRange reads ?from=&to= (default: the next 14 days), at most
+// maxFreeBusyRange apart.
+func parseRange(c *gin.Context) (time.Time, time.Time, bool) {
+	from := time.Now().UTC().Truncate(time.Minute)
+	if v := strings.TrimSpace(c.Query("from")); v != "" {
+		t, err := parseDateInput(v)
+		if err != nil {
+			jsonError(c, http.StatusBadRequest, "invalid from format (use RFC3339 or YYYY-MM-DD)")
+			return from, from, false
+		}
+		from = t
+	}
+	to := from.Add(14 * 24 * time.Hour)
+	if v := strings.TrimSpace(c.Query("to")); v != "" {
+		t, err := parseDateInput(v)
+		if err != nil {
+			jsonError(c, http.StatusBadRequest, "invalid to format (use RFC3339 or YYYY-MM-DD)")
+			return from, to, false
+		}
+		to = t
+	}
+	if !to.After(from) {
+		jsonError(c, http.StatusBadRequest, "to must be after from")
+		return from, to, false
+	}
+	if to.Sub(from) > maxFreeBusyRange {
+		jsonError(c, http.StatusBadRequest, "range must not exceed 92 days")
+		return from, to, false
+	}
+	return from, to, true
+}
+
+// busySegment is a stretch of time during which the same users are busy.
+type busySegment struct {
+	Start     time.Time `json:"start"`
+	End       time.Time `json:"end"`
+	UserIDs   []uint    `json:"user_ids,omitempty"`
+	Tentative bool      `json:"tentative"` // everyone busy is only a Maybe
+}
+
+type timeRange struct {
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+}
+
+// busyTimeline splits [from, to) at every interval boundary and returns the
+// busy stretches (with who is busy) and the free ones, merging neighbours
+// with the same users.
+func busyTimeline(intervals []busyInterval, from, to time.Time) ([]busySegment, []timeRange) {
+	points := []time.Time{from, to}
+	for _, iv := range intervals {
+		if iv.Start.After(from) && iv.Start.Before(to) {
+			points = append(points, iv.Start)
+		}
+		if iv.End.After(from) && iv.End.Before(to) {
+			points = append(points, iv.End)
+		}
+	}
+	sort.Slice(points, func(i, j int) bool { return points[i].Before(points[j]) })
+
+	busy := make([]busySegment, 0)
+	free := make([]timeRange, 0)
+	for i := 0; i+1 < len(points); i++ {
+		start, end := points[i], points[i+1]
+		if !end.After(start) {
+			continue
+		}
+		firm := make(map[uint]bool)
+		users := make([]uint, 0)
+		for _, iv := range intervals {
+			if iv.Start.After(start) || iv.End.Before(end) {
+				continue
+			}
+			if !containsID(users, iv.UserID) {
+				users = append(users, iv.UserID)
+			}
+			if iv.Status != "Maybe" {
+				firm[iv.UserID] = true
+			}
+		}
+
+		if len(users) == 0 {
+			if n := len(free); n > 0 && free[n-1].End.Equal(start) {
+				free[n-1].End = end
+			} else {
+				free = append(free, timeRange{Start: start, End: end})
+			}
+			continue
+		}
+		sort.Slice(users, func(a, b int) bool { return users[a] < users[b] })
+		tentative := len(firm) == 0
+		if n := len(busy); n > 0 && busy[n-1].End.Equal(start) && sameIDs(busy[n-1].UserIDs, users) && busy[n-1].Tentative == tentative {
+			busy[n-1].End = end
+		} else {
+			busy = append(busy, busySegment{Start: start, End: end, UserIDs: users, Tentative: tentative})
+		}
+	}
+	return busy, free
+}
+
+func sameIDs(a, b []uint) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// GET /api/freebusy?from=&to=
+// The caller's own commitments in the range, plus the free gaps between them.
+func GetFreeBusy(c *gin.Context) {
+	userID, ok := getUserIDFromContext(c)
+	if !ok {
+		jsonError(c, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	from, to, ok := parseRange(c)
+	if !ok {
+		return
+	}
+	intervals, err := busyIntervals(DB, []uint{userID}, from, to, 0)
+	if err != nil {
+		jsonError(c, http.StatusInternalServerError, "db error: "+err.Error())
+		return
+	}
+	_, free := busyTimeline(intervals, from, to)
+	c.JSON(http.StatusOK, gin.H{"from": from, "to": to, "busy": intervals, "free": free})
+}
+
+// GET /api/events/:id/availability?from=&to=
+// For the organizer picking a date: when the invitees who have not declined
+// are busy in the range, leaving out this event. Only times are shared, not
+// which events the invitees are committed to.
+//
+// Returns per-invitee busy times, the combined busy stretches with who is
+// busy, and the stretches where everyone is free.
+func GetEventAvailability(c *gin.Context) {
+	userID, ok := getUserIDFromContext(c)
+	if !ok {
+		jsonError(c, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	eventID, ok := parseEventID(c)
+	if !ok {
+		return
+	}
+	ev, ok := findMemberEvent(c, eventID, userID)
+	if !ok {
+		return
+	}
+	if ev.OrganizerID != userID {
+		jsonError(c, http.StatusForbidden, "only organizer can view invitee availability")
+		return
+	}
+	from, to, ok := parseRange(c)
+	if !ok {
+		return
+	}
+
+	var invitees []uint
+	if err := DB.Model(&EventAttendee{}).
+		Where("event_id = ? AND role = ? AND (status IS NULL OR status <> ?)", ev.ID, "attendee", "Not Going").
+		Order("user_id asc").
+		Pluck("user_id", &invitees).Error; err != nil {
+		jsonError(c, http.StatusInternalServerError, "db error: "+err.Error())
+		return
+	}
+
+	intervals, err := busyIntervals(DB, invitees, from, to, ev.ID)
+	if err != nil {
+		jsonError(c, http.StatusInternalServerError, "db error: "+err.Error())
+		return
+	}
+
+	perUser := make(map[uint][]timeRange, len(invitees))
+	for _, id := range invitees {
+		perUser[id] = make([]timeRange, 0)
+	}
+	for _, iv := range intervals {
+		perUser[iv.UserID] = append(perUser[iv.UserID], timeRange{Start: iv.Start, End: iv.End})
+	}
+	people := make([]gin.H, 0, len(invitees))
+	for _, id := range invitees {
+		people = append(people, gin.H{"user_id": id, "busy": perUser[id]})
+	}
+
+	busy, free := busyTimeline(intervals, from, to)
+	c.JSON(http.StatusOK, gin.H{
+		"from":     from,
+		"to":       to,
+		"invitees": people,
+		"busy":     busy,
+		"all_free": free,
+	})
+}
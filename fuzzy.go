@@ -0,0 +1,178 @@
+package main
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// -----------------------------
+// Fuzzy matching (pg_trgm)
+// -----------------------------
+//
+// Full-text search only finds correctly spelled words. Search additionally
+// matches event titles, locations and task titles by trigram word
+// similarity, so "confrence" still finds "Tech Conference". Matching uses the
+// <% operator, which the trigram indexes can serve; its threshold is set per
+// transaction with setWordSimilarity.
+
+const (
+	defaultSimilarity = 0.5
+
+	// suggestionSimilarity is the lowest similarity offered as "did you mean"
+	suggestionSimilarity = 0.2
+)
+
+func setupFuzzySearch(db *gorm.DB) error {
+	if err := db.Exec("CREATE EXTENSION IF NOT EXISTS pg_trgm").Error; err != nil {
+		return err
+	}
+	for _, stmt := range []string{
+		"CREATE INDEX IF NOT EXISTS idx_events_title_trgm ON events USING GIN (title gin_trgm_ops)",
+		"CREATE INDEX IF NOT EXISTS idx_events_location_trgm ON events USING GIN (location gin_trgm_ops)",
+		"CREATE INDEX IF NOT EXISTS idx_tasks_title_trgm ON tasks USING GIN (title gin_trgm_ops)",
+	} {
+		if err := db.Exec(stmt).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// fuzzyText strips search syntax (quotes, -, *, OR) from a keyword, leaving
+// the words to compare by similarity.
+func fuzzyText(keyword string) string {
+	words := make([]string, 0)
+	for _, field := range strings.Fields(keyword) {
+		if field == "OR" || strings.HasPrefix(field, "-") {
+			continue
+		}
+		word := strings.Map(func(r rune) rune {
+			if unicode.IsLetter(r) || unicode.IsDigit(r) {
+				return unicode.ToLower(r)
+			}
+			return -1
+		}, field)
+		if word != "" {
+			words = append(words, word)
+		}
+	}
+	return strings.Join(words, " ")
+}
+
+// parseSimilarity reads the ?similarity= threshold (0 < s <= 1).
+func parseSimilarity(value string) (float64, bool) {
+	if strings.TrimSpace(value) == "" {
+		return defaultSimilarity, true
+	}
+	s, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+	if err != nil || !(s > 0 && s <= 1) { // also rejects NaN
+		return 0, false
+	}
+	return s, true
+}
+
+// setWordSimilarity sets the threshold of the <% operator for the rest of
+// transaction tx.
+func setWordSimilarity(tx *gorm.DB, similarity float64) error {
+	return tx.Exec("SET LOCAL pg_trgm.word_similarity_threshold = " + strconv.FormatFloat(similarity, 'f', -1, 64)).Error
+}
+
+// escapeLike escapes LIKE wildcards so value matches literally.
+func escapeLike(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(value)
+}
+
+// didYouMean returns up to five event titles, locations or task titles,
+// from events visible to userID, that are most similar to text.
+func didYouMean(userID uint, text string) ([]string, error) {
+	if text == "" {
+		return []string{}, nil
+	}
+	candidates := DB.Table("events").
+		Select("events.title AS value, similarity(?, events.title) AS score", text).
+		Scopes(visibleEvents(userID))
+	locations := DB.Table("events").
+		Select("events.location AS value, similarity(?, events.location) AS score", text).
+		Scopes(visibleEvents(userID))
+	taskTitles := DB.Table("tasks").Joins("JOIN events ON events.id = tasks.event_id").
+		Select("tasks.title AS value, similarity(?, tasks.title) AS score", text).
+		Scopes(visibleEvents(userID))
+
+	suggestions := make([]string, 0)
+	err := DB.Table("(? UNION ALL ? UNION ALL ?) AS s", candidates, locations, taskTitles).
+		Select("s.value").
+		Where("s.score >= ? AND s.value <> ''", suggestionSimilarity).
+		Group("s.value").
+		Order("max(s.score) DESC, s.value").
+		Limit(5).
+		Pluck("s.value", &suggestions).Error
+	return suggestions, err
+}
+
+// -----------------------------
+// Autocomplete
+// -----------------------------
+
+type completion struct {
+	Value string `json:"value"`
+	Kind  string `json:"kind"` // title / location
+	Count int64  `json:"count"`
+}
+
+// GET /api/events/autocomplete?prefix=&limit=
+// Completions from the titles and locations of events visible to the caller.
+// Values starting with the prefix come first, then values with a word
+// starting with it; ties go to the more frequent value.
+func Autocomplete(c *gin.Context) {
+	userID, ok := getUserIDFromContext(c)
+	if !ok {
+		jsonError(c, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	prefix := strings.TrimSpace(c.Query("prefix"))
+	if prefix == "" {
+		jsonError(c, http.StatusBadRequest, "prefix is required")
+		return
+	}
+	limit := 10
+	if v := c.Query("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			jsonError(c, http.StatusBadRequest, "limit must be a positive integer")
+			return
+		}
+		if n > 20 {
+			n = 20
+		}
+		limit = n
+	}
+
+	starts := escapeLike(prefix) + "%"
+	words := "% " + escapeLike(prefix) + "%"
+	branch := func(col, kind string) *gorm.DB {
+		return DB.Table("events").
+			Select("events."+col+" AS value, '"+kind+"' AS kind, "+
+				"CASE WHEN events."+col+" ILIKE ? THEN 0 ELSE 1 END AS tier", starts).
+			Where("events."+col+" ILIKE ? OR events."+col+" ILIKE ?", starts, words).
+			Scopes(visibleEvents(userID))
+	}
+
+	results := make([]completion, 0, limit)
+	err := DB.Table("(? UNION ALL ?) AS m", branch("title", "title"), branch("location", "location")).
+		Select("m.value, m.kind, count(*) AS count").
+		Group("m.value, m.kind").
+		Order("min(m.tier), count(*) DESC, m.value").
+		Limit(limit).
+		Scan(&results).Error
+	if err != nil {
+		jsonError(c, http.StatusInternalServerError, "db error: "+err.Error())
+		return
+	}
+	c.JSON(http.StatusOK, results)
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"testing"
+)
+
+func TestFuzzyText(t *testing.T) {
+	cases := map[string]string{
+		`Tech "Confrence" Berlin!`: "tech confrence berlin",
+		"gala -cheap OR party*":    "gala party",
+		"  Café   Zürich  ":        "café zürich",
+		`"-" OR * --`:              "",
+		"new-year's eve 2026":      "newyears eve 2026",
+	}
+	for in, want := range cases {
+		if got := fuzzyText(in); got != want {
+			t.Errorf("fuzzyText(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestParseSimilarity(t *testing.T) {
+	for in, want := range map[string]float64{"": defaultSimilarity, " 0.3 ": 0.3, "1": 1} {
+		if got, ok := parseSimilarity(in); !ok || got != want {
+			t.Errorf("parseSimilarity(%q) = %v, %v; want %v", in, got, ok, want)
+		}
+	}
+	for _, in := range []string{"0", "-0.2", "1.01", "high", "NaN"} {
+		if _, ok := parseSimilarity(in); ok {
+			t.Errorf("parseSimilarity(%q) accepted", in)
+		}
+	}
+}
+
+// A misspelled keyword matches a title exactly when its word similarity
+// reaches ?similarity=; below it the search only suggests the title.
+func TestFuzzySearchMatchesAtSimilarity(t *testing.T) {
+	useTestDB(t)
+	r := testRouter()
+	org := createTestUser(t, "FuzzyOrg")
+	ev := createTestEvent(t, org.ID, "Tech Conference", false)
+
+	var score float64
+	if err := DB.Raw("SELECT word_similarity(?, ?)::float8", "confrence", ev.Title).Scan(&score).Error; err != nil {
+		t.Fatal(err)
+	}
+	if score <= suggestionSimilarity || score >= 0.99 {
+		t.Fatalf("word_similarity = %v, want a score to test against", score)
+	}
+
+	search := func(similarity float64) searchPage {
+		t.Helper()
+		q := url.Values{"keyword": {"confrence"}, "type": {"event"}, "similarity": {strconv.FormatFloat(similarity, 'f', -1, 64)}}
+		w := apiRequest(t, r, org.ID, http.MethodGet, "/api/events/search?"+q.Encode(), nil)
+		if w.Code != http.StatusOK {
+			t.Fatalf("similarity %v: status %d: %s", similarity, w.Code, w.Body.String())
+		}
+		var page searchPage
+		decodeJSON(t, w, &page)
+		return page
+	}
+
+	if page := search(score); len(page.Items) != 1 || page.Items[0].Event.ID != ev.ID {
+		t.Errorf("at similarity %v: %d items, want the conference", score, len(page.Items))
+	}
+	page := search(score + 0.01)
+	if len(page.Items) != 0 {
+		t.Errorf("above similarity %v: %d items, want none", score, len(page.Items))
+	}
+	if fmt.Sprint(page.DidYouMean) != "[Tech Conference]" {
+		t.Errorf("did_you_mean = %v", page.DidYouMean)
+	}
+
+	if w := apiRequest(t, r, org.ID, http.MethodGet, "/api/events/search?keyword=confrence&similarity=0", nil); w.Code != http.StatusBadRequest {
+		t.Errorf("similarity 0: status %d, want 400", w.Code)
+	}
+}
+
+// Values starting with the prefix come before values with a later word
+// starting with it, then the more frequent value wins.
+func TestAutocompleteTiers(t *testing.T) {
+	useTestDB(t)
+	r := testRouter()
+	org := createTestUser(t, "CompleteOrg")
+	other := createTestUser(t, "CompleteOther")
+
+	for _, e := range []struct{ title, location string }{
+		{"Berlin Marathon", "Hamburg"},
+		{"Berlin Marathon", "Hamburg"},
+		{"Berlin Marathon", "Berlin Mitte"},
+		{"Berlinale party", "Berlin Mitte"},
+		{"Summer in Berlin", "Potsdam"},
+		{"Xberlin meetup", "Hamburg"}, // no word starts with the prefix
+		{"100% fun", "Hamburg"},
+		{"1000 guests", "Hamburg"},
+	} {
+		ev := createTestEvent(t, org.ID, e.title, false)
+		if err := DB.Model(&ev).Update("location", e.location).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	createTestEvent(t, other.ID, "Berlin secret party", false)
+
+	complete := func(query string) string {
+		t.Helper()
+		w := apiRequest(t, r, org.ID, http.MethodGet, "/api/events/autocomplete?"+query, nil)
+		if w.Code != http.StatusOK {
+			t.Fatalf("%s: status %d: %s", query, w.Code, w.Body.String())
+		}
+		var list []completion
+		decodeJSON(t, w, &list)
+		return fmt.Sprint(list)
+	}
+
+	want := "[{Berlin Marathon title 3} {Berlin Mitte location 2} {Berlinale party title 1} {Summer in Berlin title 1}]"
+	if got := complete("prefix=berl"); got != want {
+		t.Errorf("berl = %s, want %s", got, want)
+	}
+	if got := complete("prefix=BERLIN&limit=2"); got != "[{Berlin Marathon title 3} {Berlin Mitte location 2}]" {
+		t.Errorf("BERLIN, limit 2 = %s", got)
+	}
+	// LIKE wildcards in the prefix match literally
+	if got := complete("prefix=" + url.QueryEscape("100%")); got != "[{100% fun title 1}]" {
+		t.Errorf("100%% = %s", got)
+	}
+	if w := apiRequest(t, r, org.ID, http.MethodGet, "/api/events/autocomplete?prefix=+", nil); w.Code != http.StatusBadRequest {
+		t.Errorf("blank prefix: status %d, want 400", w.Code)
+	}
+}
@@ -24,10 +24,19 @@ func main() {
 		log.Fatal("❌ JWT_SECRET is missing in .env")
 	}
 	log.Println("🔐 JWT_SECRET loaded successfully")
+	if os.Getenv("CHECKIN_SECRET") == "" {
+		log.Fatal("❌ CHECKIN_SECRET is missing in .env")
+	}
 
 	// Connect DB
 	InitDB()
 
+	// Attachment storage
+	InitStorage()
+
+	// Saved search notifications
+	StartSavedSearchScheduler()
+
 	// Start Gin
 	r := gin.Default()
 
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// Tests that need a database run against the Postgres named by
+// TEST_DATABASE_DSN, for example
+//
+//	TEST_DATABASE_DSN="host=localhost user=postgres password=postgres dbname=eventplanner_test port=5432 sslmode=disable" go test ./...
+//
+// Every such test wipes all tables first, so never point it at real data.
+// The role needs to be able to CREATE EXTENSION pg_trgm. Without
+// TEST_DATABASE_DSN those tests are skipped.
+
+func TestMain(m *testing.M) {
+	gin.SetMode(gin.TestMode)
+
+	dir, err := os.MkdirTemp("", "eventplanner-test-")
+	if err != nil {
+		log.Fatalf("temp dir: %v", err)
+	}
+	if FileStore, err = NewLocalStorage(dir); err != nil {
+		log.Fatalf("test storage: %v", err)
+	}
+
+	if dsn := os.Getenv("TEST_DATABASE_DSN"); dsn != "" {
+		db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+		if err != nil {
+			log.Fatalf("test database: %v", err)
+		}
+		if err := migrateDB(db); err != nil {
+			log.Fatalf("test database migration: %v", err)
+		}
+		DB = db
+	}
+
+	code := m.Run()
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
+
+// useTestDB skips the test without a test database, and empties it otherwise.
+func useTestDB(tb testing.TB) {
+	tb.Helper()
+	if DB == nil {
+		tb.Skip("TEST_DATABASE_DSN not set")
+	}
+	var tables []string
+	if err := DB.Raw("SELECT quote_ident(tablename) FROM pg_tables WHERE schemaname = current_schema()").
+		Scan(&tables).Error; err != nil {
+		tb.Fatalf("list tables: %v", err)
+	}
+	if len(tables) == 0 {
+		return
+	}
+	if err := DB.Exec("TRUNCATE " + strings.Join(tables, ", ") + " RESTART IDENTITY CASCADE").Error; err != nil {
+		tb.Fatalf("truncate: %v", err)
+	}
+}
+
+func testRouter() *gin.Engine {
+	r := gin.New()
+	SetupRoutes(r)
+	return r
+}
+
+// apiRequest sends an authenticated JSON request as userID.
+func apiRequest(tb testing.TB, r http.Handler, userID uint, method, path string, body interface{}) *httptest.ResponseRecorder {
+	tb.Helper()
+	var buf bytes.Buffer
+	if body != nil {
+		if err := json.NewEncoder(&buf).Encode(body); err != nil {
+			tb.Fatalf("encode body: %v", err)
+		}
+	}
+	req := httptest.NewRequest(method, path, &buf)
+	req.Header.Set("Content-Type", "application/json")
+	token, err := GenerateToken(userID)
+	if err != nil {
+		tb.Fatalf("token: %v", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func decodeJSON(tb testing.TB, w *httptest.ResponseRecorder, v interface{}) {
+	tb.Helper()
+	if err := json.Unmarshal(w.Body.Bytes(), v); err != nil {
+		tb.Fatalf("decode %s: %v", w.Body.String(), err)
+	}
+}
+
+func createTestUser(tb testing.TB, name string) User {
+	tb.Helper()
+	u := User{Name: name, Email: strings.ToLower(name) + "@example.com", Password: "secret"}
+	if err := DB.Create(&u).Error; err != nil {
+		tb.Fatalf("create user: %v", err)
+	}
+	return u
+}
+
+// createTestEvent creates an event a week from now, organized by organizerID
+// (with the organizer attendee row CreateEvent adds).
+func createTestEvent(tb testing.TB, organizerID uint, title string, public bool) Event {
+	tb.Helper()
+	ev := Event{
+		Title:          title,
+		Description:    "description of " + title,
+		Location:       "Berlin",
+		Date:           time.Now().UTC().Add(7 * 24 * time.Hour).Truncate(time.Hour),
+		OrganizerID:    organizerID,
+		LateRSVPPolicy: "reject",
+		IsPublic:       public,
+	}
+	if err := DB.Create(&ev).Error; err != nil {
+		tb.Fatalf("create event: %v", err)
+	}
+	inviteTestUser(tb, ev.ID, organizerID, "organizer")
+	return ev
+}
+
+func inviteTestUser(tb testing.TB, eventID, userID uint, role string) {
+	tb.Helper()
+	att := EventAttendee{EventID: eventID, UserID: userID, Role: role}
+	if err := DB.Create(&att).Error; err != nil {
+		tb.Fatalf("create attendee: %v", err)
+	}
+}
+
+func createTestTask(tb testing.TB, eventID uint, title string) Task {
+	tb.Helper()
+	task := Task{EventID: eventID, Title: title, Description: "description of " + title, Status: "todo", Priority: "medium"}
+	if err := DB.Create(&task).Error; err != nil {
+		tb.Fatalf("create task: %v", err)
+	}
+	return task
+}
@@ -10,6 +10,7 @@ type User struct {
 	gorm.Model
 	ID        uint      `json:"id" gorm:"primaryKey"`
 	Email     string    `json:"email" gorm:"uniqueIndex;not null"`
+	Name      string    `json:"name"`
 	Password  string    `json:"password,omitempty"` // FIXED: bind JSON but do not return in responses
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
@@ -31,8 +32,33 @@ type Event struct {
 	CreatedAt   time.Time `json:"created_at"`
 	UpdatedAt   time.Time `json:"updated_at"`
 
+	// RSVPDeadline is optional; after it passes status changes are rejected
+	// or flagged as late depending on LateRSVPPolicy ("reject" / "flag").
+	RSVPDeadline   *time.Time `json:"rsvp_deadline"`
+	LateRSVPPolicy string     `json:"late_rsvp_policy" gorm:"type:varchar(16);not null;default:reject"`
+
+	// BudgetCurrency is the ISO 4217 code budget reports are converted to
+	BudgetCurrency string `json:"budget_currency" gorm:"type:varchar(3);not null;default:USD"`
+
+	// IsPublic makes the event visible in search to users who are not members
+	IsPublic bool `json:"is_public" gorm:"not null;default:false"`
+
+	// Category is one of eventCategories (see tags.go), empty when unset
+	Category string `json:"category" gorm:"type:varchar(32);not null;default:'';index"`
+
+	// VenueID links a shared Venue; EndsAt is optional, events without it
+	// are taken to last defaultEventDuration (see venues.go)
+	VenueID *uint      `json:"venue_id" gorm:"index"`
+	EndsAt  *time.Time `json:"ends_at"`
+
 	Organizer User   `gorm:"foreignKey:OrganizerID" json:"organizer,omitempty"`
 	Tasks     []Task `gorm:"foreignKey:EventID" json:"tasks,omitempty"`
+	Tags      []Tag  `gorm:"many2many:event_tags" json:"tags,omitempty"`
+	Venue     *Venue `gorm:"foreignKey:VenueID" json:"venue,omitempty"`
+
+	// Conflicts is filled in when creating an event that overlaps the
+	// organizer's other commitments (see freebusy.go); it is not stored
+	Conflicts []busyInterval `gorm:"-" json:"conflicts,omitempty"`
 }
 
 type Task struct {
@@ -42,6 +68,47 @@ type Task struct {
 	Description string    `json:"description"`
 	CreatedAt   time.Time `json:"created_at"`
 	UpdatedAt   time.Time `json:"updated_at"`
+
+	Status      string     `json:"status" gorm:"type:varchar(16);not null;default:todo"`     // todo / in-progress / done / blocked
+	Priority    string     `json:"priority" gorm:"type:varchar(16);not null;default:medium"` // low / medium / high / urgent
+	DueAt       *time.Time `json:"due_at"`
+	CompletedAt *time.Time `json:"completed_at"`           // set when Status becomes done
+	ParentID    *uint      `json:"parent_id" gorm:"index"` // set for subtasks
+
+	// Kanban placement: Position is a fractional rank (see rank.go) within the column
+	ColumnID *uint  `json:"column_id" gorm:"index"`
+	Position string `json:"position" gorm:"type:text;not null;default:''"`
+
+	Assignees []TaskAssignee  `gorm:"foreignKey:TaskID" json:"assignees,omitempty"`
+	Checklist []ChecklistItem `gorm:"foreignKey:TaskID" json:"checklist,omitempty"`
+}
+
+// ChecklistItem is a lightweight to-do line inside a task
+type ChecklistItem struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	TaskID    uint      `json:"task_id" gorm:"index;not null"`
+	Title     string    `json:"title" gorm:"not null"`
+	Done      bool      `json:"done"`
+	Position  int       `json:"position"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// TaskDependency means TaskID cannot be completed before DependsOnID is done.
+// Both tasks belong to the same event.
+type TaskDependency struct {
+	ID          uint      `json:"id" gorm:"primaryKey"`
+	TaskID      uint      `json:"task_id" gorm:"uniqueIndex:idx_task_dependency;not null"`
+	DependsOnID uint      `json:"depends_on_id" gorm:"uniqueIndex:idx_task_dependency;index;not null"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// TaskAssignee links a task to an event member responsible for it
+type TaskAssignee struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	TaskID    uint      `json:"task_id" gorm:"uniqueIndex:idx_task_assignee;not null"`
+	UserID    uint      `json:"user_id" gorm:"uniqueIndex:idx_task_assignee;index;not null"`
+	CreatedAt time.Time `json:"created_at"`
 }
 
 type EventAttendee struct {
@@ -52,4 +119,267 @@ type EventAttendee struct {
 	Status    string    `json:"status" gorm:"type:varchar(32)"`
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
+
+	// LateResponse is set when the status was changed after the RSVP deadline
+	LateResponse bool `json:"late_response"`
+
+	// Guests is how many people the attendee brings along
+	Guests int `json:"guests" gorm:"not null;default:0"`
+
+	// Venue check-in. Helpers may scan tickets alongside the organizer.
+	IsHelper      bool       `json:"is_helper"`
+	CheckedInAt   *time.Time `json:"checked_in_at"`
+	CheckedInByID *uint      `json:"checked_in_by_id"`
+
+	Answers []AttendeeAnswer `gorm:"foreignKey:EventAttendeeID" json:"answers,omitempty"`
+
+	// Conflicts is filled in responses when the user's own RSVP overlaps
+	// their other commitments (see freebusy.go); it is not stored
+	Conflicts []busyInterval `gorm:"-" json:"conflicts,omitempty"`
+}
+
+// QuestionnaireQuestion is one field of an event's RSVP questionnaire
+type QuestionnaireQuestion struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	EventID   uint      `json:"event_id" gorm:"index;not null"`
+	Label     string    `json:"label" gorm:"not null"`
+	Type      string    `json:"type" gorm:"type:varchar(32);not null"` // text / single_choice / multi_choice / number
+	Options   []string  `json:"options,omitempty" gorm:"serializer:json"`
+	Required  bool      `json:"required"`
+	Position  int       `json:"position"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// AttendeeAnswer is an attendee's answer to one questionnaire question.
+// Values holds a single entry except for multi_choice questions.
+type AttendeeAnswer struct {
+	ID              uint      `json:"id" gorm:"primaryKey"`
+	EventAttendeeID uint      `json:"event_attendee_id" gorm:"uniqueIndex:idx_answer_attendee_question;not null"`
+	QuestionID      uint      `json:"question_id" gorm:"uniqueIndex:idx_answer_attendee_question;not null"`
+	Values          []string  `json:"values" gorm:"column:answer_values;serializer:json"`
+	CreatedAt       time.Time `json:"created_at"`
+	UpdatedAt       time.Time `json:"updated_at"`
+}
+
+// RSVPChange is an append-only log entry written every time an attendee's status changes
+type RSVPChange struct {
+	ID              uint      `json:"id" gorm:"primaryKey"`
+	EventAttendeeID uint      `json:"event_attendee_id" gorm:"index;not null"`
+	EventID         uint      `json:"event_id" gorm:"index;not null"`
+	UserID          uint      `json:"user_id" gorm:"not null"`
+	FromStatus      string    `json:"from_status" gorm:"type:varchar(32)"`
+	ToStatus        string    `json:"to_status" gorm:"type:varchar(32);not null"`
+	ChangedByID     uint      `json:"changed_by_id" gorm:"not null"`
+	Late            bool      `json:"late"`
+	CreatedAt       time.Time `json:"created_at"`
+}
+
+// BoardColumn is a Kanban column of an event's task board
+type BoardColumn struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	EventID   uint      `json:"event_id" gorm:"index;not null"`
+	Name      string    `json:"name" gorm:"not null"`
+	Position  string    `json:"position" gorm:"type:text;not null"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// EventTemplate is a reusable blueprint for events that are hosted repeatedly
+type EventTemplate struct {
+	ID          uint           `json:"id" gorm:"primaryKey"`
+	OwnerID     uint           `json:"owner_id" gorm:"uniqueIndex:idx_template_owner_name;not null"`
+	Name        string         `json:"name" gorm:"uniqueIndex:idx_template_owner_name;not null"`
+	Description string         `json:"description"`
+	Location    string         `json:"location"`
+	Tasks       []TemplateTask `json:"tasks" gorm:"serializer:json"`
+	CreatedAt   time.Time      `json:"created_at"`
+	UpdatedAt   time.Time      `json:"updated_at"`
+}
+
+// TemplateTask is a task blueprint. Due dates are kept relative to the event
+// date; Parent and DependsOn refer to other tasks by their index in the list.
+type TemplateTask struct {
+	Title            string   `json:"title"`
+	Description      string   `json:"description,omitempty"`
+	Priority         string   `json:"priority,omitempty"`
+	DueOffsetMinutes *int64   `json:"due_offset_minutes,omitempty"` // negative = before the event
+	Parent           *int     `json:"parent,omitempty"`
+	DependsOn        []int    `json:"depends_on,omitempty"`
+	Checklist        []string `json:"checklist,omitempty"`
+}
+
+// Comment is a discussion message on an event, or on one of its tasks when
+// TaskID is set. Replies point at their parent comment. Deleted comments stay
+// as placeholders (empty body, DeletedAt set) so their replies keep a thread.
+type Comment struct {
+	ID          uint       `json:"id" gorm:"primaryKey"`
+	EventID     uint       `json:"event_id" gorm:"index;not null"`
+	TaskID      *uint      `json:"task_id" gorm:"index"`
+	ParentID    *uint      `json:"parent_id" gorm:"index"`
+	AuthorID    uint       `json:"author_id" gorm:"not null"`
+	Body        string     `json:"body" gorm:"type:text;not null"`
+	EditedAt    *time.Time `json:"edited_at"`
+	DeletedAt   *time.Time `json:"deleted_at"`
+	DeletedByID *uint      `json:"deleted_by_id"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+
+	Mentions []CommentMention `gorm:"foreignKey:CommentID" json:"mentions"`
+}
+
+// CommentMention records an event member @mentioned in a comment
+type CommentMention struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	CommentID uint      `json:"comment_id" gorm:"uniqueIndex:idx_comment_mention;not null"`
+	UserID    uint      `json:"user_id" gorm:"uniqueIndex:idx_comment_mention;index;not null"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Attachment is the metadata of a file attached to an event, or to one of
+// its tasks when TaskID is set. The content lives in FileStore under StorageKey.
+type Attachment struct {
+	ID          uint      `json:"id" gorm:"primaryKey"`
+	EventID     uint      `json:"event_id" gorm:"index;not null"`
+	TaskID      *uint     `json:"task_id" gorm:"index"`
+	UploaderID  uint      `json:"uploader_id" gorm:"not null"`
+	FileName    string    `json:"file_name" gorm:"not null"`
+	ContentType string    `json:"content_type" gorm:"type:varchar(128);not null"`
+	Size        int64     `json:"size" gorm:"not null"`
+	StorageKey  string    `json:"-" gorm:"uniqueIndex;not null"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// DatePoll asks event members which candidate times work before Event.Date
+// is fixed. Only one poll per event can be open at a time.
+type DatePoll struct {
+	ID            uint       `json:"id" gorm:"primaryKey"`
+	EventID       uint       `json:"event_id" gorm:"index;not null"`
+	Status        string     `json:"status" gorm:"type:varchar(16);not null;default:open"` // open / finalized
+	ClosesAt      *time.Time `json:"closes_at"`                                            // voting stops after this
+	FinalOptionID *uint      `json:"final_option_id"`
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+
+	Options []DatePollOption `gorm:"foreignKey:PollID" json:"options,omitempty"`
+}
+
+type DatePollOption struct {
+	ID       uint      `json:"id" gorm:"primaryKey"`
+	PollID   uint      `json:"poll_id" gorm:"index;not null"`
+	StartsAt time.Time `json:"starts_at" gorm:"not null"`
+	Note     string    `json:"note"`
+}
+
+// DatePollVote is one member's answer for one option
+type DatePollVote struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	PollID    uint      `json:"poll_id" gorm:"index;not null"`
+	OptionID  uint      `json:"option_id" gorm:"uniqueIndex:idx_poll_vote;not null"`
+	UserID    uint      `json:"user_id" gorm:"uniqueIndex:idx_poll_vote;not null"`
+	Choice    string    `json:"choice" gorm:"type:varchar(8);not null"` // yes / maybe / no
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Notification is an in-app message for a user
+type Notification struct {
+	ID        uint       `json:"id" gorm:"primaryKey"`
+	UserID    uint       `json:"user_id" gorm:"index;not null"`
+	Type      string     `json:"type" gorm:"type:varchar(32);not null"`
+	EventID   *uint      `json:"event_id" gorm:"index"`
+	Message   string     `json:"message" gorm:"not null"`
+	ReadAt    *time.Time `json:"read_at"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// BudgetItem is a planned cost of an event. Amounts are integer minor units
+// (cents) of Currency.
+type BudgetItem struct {
+	ID            uint      `json:"id" gorm:"primaryKey"`
+	EventID       uint      `json:"event_id" gorm:"index;not null"`
+	Category      string    `json:"category" gorm:"type:varchar(64);not null"`
+	Description   string    `json:"description"`
+	PlannedAmount int64     `json:"planned_amount" gorm:"not null"`
+	Currency      string    `json:"currency" gorm:"type:varchar(3);not null"`
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
+}
+
+// Expense is money actually spent, optionally against a budget item and/or a task
+type Expense struct {
+	ID           uint      `json:"id" gorm:"primaryKey"`
+	EventID      uint      `json:"event_id" gorm:"index;not null"`
+	BudgetItemID *uint     `json:"budget_item_id" gorm:"index"`
+	TaskID       *uint     `json:"task_id" gorm:"index"`
+	Category     string    `json:"category" gorm:"type:varchar(64);not null"`
+	Description  string    `json:"description"`
+	Amount       int64     `json:"amount" gorm:"not null"`
+	Currency     string    `json:"currency" gorm:"type:varchar(3);not null"`
+	PaidByID     uint      `json:"paid_by_id" gorm:"not null"`
+	SpentAt      time.Time `json:"spent_at" gorm:"not null"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+// ExchangeRate converts Currency into the event's BudgetCurrency:
+// 1 unit of Currency = Rate units of the budget currency.
+type ExchangeRate struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	EventID   uint      `json:"event_id" gorm:"uniqueIndex:idx_event_rate;not null"`
+	Currency  string    `json:"currency" gorm:"type:varchar(3);uniqueIndex:idx_event_rate;not null"`
+	Rate      string    `json:"rate" gorm:"type:numeric(20,10);not null"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// SavedSearch is a search a user wants to be told about: events that start
+// matching it after LastCheckedAt are recorded as hits and notified.
+type SavedSearch struct {
+	ID        uint   `json:"id" gorm:"primaryKey"`
+	UserID    uint   `json:"user_id" gorm:"index;not null"`
+	Name      string `json:"name" gorm:"not null"`
+	Keyword   string `json:"keyword"`
+	StartDate string `json:"start_date" gorm:"type:varchar(32)"`
+	EndDate   string `json:"end_date" gorm:"type:varchar(32)"`
+	Role      string `json:"role" gorm:"type:varchar(64)"` // comma separated, as in search
+	Type      string `json:"type" gorm:"type:varchar(8);not null;default:both"`
+	Tag       string `json:"tag" gorm:"type:text"` // comma separated tag names, any of which must be on the event
+
+	LastCheckedAt time.Time `json:"last_checked_at"`
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
+}
+
+// SavedSearchHit records that an event was reported for a saved search, so
+// it is notified only once.
+type SavedSearchHit struct {
+	ID            uint      `json:"id" gorm:"primaryKey"`
+	SavedSearchID uint      `json:"saved_search_id" gorm:"uniqueIndex:idx_saved_search_hit;not null"`
+	EventID       uint      `json:"event_id" gorm:"uniqueIndex:idx_saved_search_hit;index;not null"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+// Tag is a user-defined label; organizers attach their own tags to their
+// events. Names are stored normalized (see normalizeTagName).
+type Tag struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	UserID    uint      `json:"user_id" gorm:"uniqueIndex:idx_user_tag;not null"`
+	Name      string    `json:"name" gorm:"type:varchar(32);uniqueIndex:idx_user_tag;not null"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Venue is a place events can be held at, shared by all users. Coordinates
+// stay empty until the venue is geocoded.
+type Venue struct {
+	ID                 uint     `json:"id" gorm:"primaryKey"`
+	OwnerID            uint     `json:"owner_id" gorm:"index;not null"`
+	Name               string   `json:"name" gorm:"not null"`
+	Address            string   `json:"address"`
+	Latitude           *float64 `json:"latitude" gorm:"index:idx_venue_coords"`
+	Longitude          *float64 `json:"longitude" gorm:"index:idx_venue_coords"`
+	Capacity           int      `json:"capacity"` // 0 = unknown
+	AccessibilityNotes string   `json:"accessibility_notes"`
+
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
 }
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// -----------------------------
+// Notifications
+// -----------------------------
+
+// notifyUsers queues the same in-app notification for several users.
+func notifyUsers(tx *gorm.DB, userIDs []uint, kind string, eventID *uint, message string) error {
+	if len(userIDs) == 0 {
+		return nil
+	}
+	rows := make([]Notification, 0, len(userIDs))
+	seen := make(map[uint]bool, len(userIDs))
+	for _, uid := range userIDs {
+		if seen[uid] {
+			continue
+		}
+		seen[uid] = true
+		rows = append(rows, Notification{UserID: uid, Type: kind, EventID: eventID, Message: message})
+	}
+	return tx.Create(&rows).Error
+}
+
+// eventMemberIDs returns the organizer and everyone with an EventAttendee row, minus exceptID.
+func eventMemberIDs(tx *gorm.DB, ev Event, exceptID uint) ([]uint, error) {
+	var ids []uint
+	if err := tx.Model(&EventAttendee{}).Where("event_id = ?", ev.ID).Pluck("user_id", &ids).Error; err != nil {
+		return nil, err
+	}
+	ids = append(ids, ev.OrganizerID)
+	out := make([]uint, 0, len(ids))
+	seen := make(map[uint]bool, len(ids))
+	for _, id := range ids {
+		if id == exceptID || seen[id] {
+			continue
+		}
+		seen[id] = true
+		out = append(out, id)
+	}
+	return out, nil
+}
+
+var notificationSorts = map[string]sortSpec{
+	"created_at": {column: "notifications.created_at", desc: true, key: "time"},
+}
+
+// GET /api/notifications?unread=true&limit=&cursor=
+// Newest first, as a page envelope (see pagination.go) that also carries the
+// caller's unread count.
+func GetNotifications(c *gin.Context) {
+	userID, ok := getUserIDFromContext(c)
+	if !ok {
+		jsonError(c, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	page, ok := parsePageRequest(c, notificationSorts, "created_at")
+	if !ok {
+		return
+	}
+	query := DB.Where("user_id = ?", userID)
+	if c.Query("unread") == "true" {
+		query = query.Where("read_at IS NULL")
+	}
+	list, next, total, ok := pageQuery(c, page, query, "notifications.id", func(n Notification) (interface{}, uint) {
+		return n.CreatedAt, n.ID
+	})
+	if !ok {
+		return
+	}
+
+	var unread int64
+	if err := DB.Model(&Notification{}).Where("user_id = ? AND read_at IS NULL", userID).Count(&unread).Error; err != nil {
+		jsonError(c, http.StatusInternalServerError, "db error: "+err.Error())
+		return
+	}
+	resp := pageEnvelope(list, next, total)
+	resp["unread"] = unread
+	c.JSON(http.StatusOK, resp)
+}
+
+// POST /api/notifications/:notification_id/read
+func MarkNotificationRead(c *gin.Context) {
+	userID, ok := getUserIDFromContext(c)
+	if !ok {
+		jsonError(c, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("notification_id"), 10, 64)
+	if err != nil {
+		jsonError(c, http.StatusBadRequest, "invalid notification id")
+		return
+	}
+	var n Notification
+	if err := DB.Where("id = ? AND user_id = ?", id, userID).First(&n).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			jsonError(c, http.StatusNotFound, "notification not found")
+			return
+		}
+		jsonError(c, http.StatusInternalServerError, "db error: "+err.Error())
+		return
+	}
+	if n.ReadAt == nil {
+		now := time.Now()
+		n.ReadAt = &now
+		if err := DB.Model(&n).Update("read_at", now).Error; err != nil {
+			jsonError(c, http.StatusInternalServerError, "db error: "+err.Error())
+			return
+		}
+	}
+	c.JSON(http.StatusOK, n)
+}
+
+// POST /api/notifications/read-all
+func MarkAllNotificationsRead(c *gin.Context) {
+	userID, ok := getUserIDFromContext(c)
+	if !ok {
+		jsonError(c, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	res := DB.Model(&Notification{}).Where("user_id = ? AND read_at IS NULL", userID).Update("read_at", time.Now())
+	if res.Error != nil {
+		jsonError(c, http.StatusInternalServerError, "db error: "+res.Error.Error())
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"marked_read": res.RowsAffected})
+}
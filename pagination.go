@@ -0,0 +1,298 @@
+package main
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// -----------------------------
+// Keyset pagination
+// -----------------------------
+//
+// List endpoints take ?limit=&sort=&cursor= and answer with
+//
+//	{ "items": [...], "next_cursor": "..." | null, "total_estimate": n }
+//
+// The cursor is opaque to clients: it encodes the sort key and id of the
+// last item served, so pages stay stable while rows are inserted or
+// removed elsewhere in the list.
+//
+// Every list endpoint answers this way except two, which are not lists to
+// page through: autocomplete returns its top suggestions (at most 20), and
+// venue conflicts returns every current double booking of one venue at
+// once, since they are resolved together.
+
+const (
+	defaultPageSize = 20
+	maxPageSize     = 100
+
+	// exactCountLimit is how many rows are counted exactly before
+	// total_estimate falls back to the query planner's estimate.
+	exactCountLimit = 1000
+)
+
+// sortSpec describes one ?sort= option: the column it orders by, the
+// direction, and how the key is stored in a cursor ("time", "text" or "float").
+type sortSpec struct {
+	column string
+	desc   bool
+	key    string
+}
+
+// pageCursor is the decoded form of a cursor. Kind breaks ties between
+// result types in mixed lists (0 = event, 1 = task). Desc records the
+// direction for lists that take ?order=.
+type pageCursor struct {
+	Sort string `json:"s"`
+	Desc bool   `json:"d,omitempty"`
+	Key  string `json:"k"`
+	Kind int    `json:"t,omitempty"`
+	ID   uint   `json:"i"`
+}
+
+type pageRequest struct {
+	Limit int
+	Sort  string
+	spec  sortSpec
+	After *pageCursor
+}
+
+var errBadCursor = errors.New("invalid cursor")
+
+func encodeCursor(cur pageCursor) string {
+	raw, _ := json.Marshal(cur)
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
+
+func decodeCursor(value string) (pageCursor, error) {
+	var cur pageCursor
+	raw, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return cur, errBadCursor
+	}
+	if err := json.Unmarshal(raw, &cur); err != nil || cur.ID == 0 {
+		return cur, errBadCursor
+	}
+	return cur, nil
+}
+
+// parsePageRequest reads limit, sort and cursor from the query string,
+// writing a 400 and returning false when they are invalid.
+func parsePageRequest(c *gin.Context, sorts map[string]sortSpec, defaultSort string) (pageRequest, bool) {
+	page := pageRequest{Limit: defaultPageSize, Sort: defaultSort}
+
+	if v := strings.TrimSpace(c.Query("limit")); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			jsonError(c, http.StatusBadRequest, "limit must be a positive integer")
+			return page, false
+		}
+		if n > maxPageSize {
+			n = maxPageSize
+		}
+		page.Limit = n
+	}
+
+	if v := strings.TrimSpace(c.Query("sort")); v != "" {
+		page.Sort = v
+	}
+	spec, ok := sorts[page.Sort]
+	if !ok {
+		names := make([]string, 0, len(sorts))
+		for name := range sorts {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		jsonError(c, http.StatusBadRequest, "sort must be one of: "+strings.Join(names, ", "))
+		return page, false
+	}
+	page.spec = spec
+
+	if v := strings.TrimSpace(c.Query("cursor")); v != "" {
+		cur, err := decodeCursor(v)
+		if err != nil {
+			jsonError(c, http.StatusBadRequest, err.Error())
+			return page, false
+		}
+		if cur.Sort != page.Sort || cur.Desc != spec.desc {
+			jsonError(c, http.StatusBadRequest, "cursor does not match sort")
+			return page, false
+		}
+		if _, err := page.cursorKey(cur); err != nil {
+			jsonError(c, http.StatusBadRequest, err.Error())
+			return page, false
+		}
+		page.After = &cur
+	}
+	return page, true
+}
+
+// cursorKey converts the cursor's sort key back to a typed query argument.
+func (p pageRequest) cursorKey(cur pageCursor) (interface{}, error) {
+	switch p.spec.key {
+	case "time":
+		t, err := time.Parse(time.RFC3339Nano, cur.Key)
+		if err != nil {
+			return nil, errBadCursor
+		}
+		return t, nil
+	case "float":
+		f, err := strconv.ParseFloat(cur.Key, 64)
+		if err != nil {
+			return nil, errBadCursor
+		}
+		return f, nil
+	default:
+		return cur.Key, nil
+	}
+}
+
+// cursorAfter builds the cursor pointing past an item with the given key.
+func (p pageRequest) cursorAfter(key interface{}, kind int, id uint) string {
+	cur := pageCursor{Sort: p.Sort, Desc: p.spec.desc, Kind: kind, ID: id}
+	switch v := key.(type) {
+	case time.Time:
+		cur.Key = v.UTC().Format(time.RFC3339Nano)
+	case float64:
+		cur.Key = strconv.FormatFloat(v, 'g', -1, 64)
+	case string:
+		cur.Key = v
+	}
+	return encodeCursor(cur)
+}
+
+// seek orders q by keyCol in the requested direction, then by tieCols
+// ascending, and skips everything up to and including the cursor.
+// tieArgs are the cursor's values for tieCols.
+func (p pageRequest) seek(q *gorm.DB, keyCol, tieCols string, tieArgs ...interface{}) *gorm.DB {
+	dir, op := "asc", ">"
+	if p.spec.desc {
+		dir, op = "desc", "<"
+	}
+	order := keyCol + " " + dir
+	for _, col := range strings.Split(tieCols, ",") {
+		order += ", " + strings.TrimSpace(col) + " asc"
+	}
+	q = q.Order(order)
+
+	if p.After != nil {
+		key, _ := p.cursorKey(*p.After)
+		args := []interface{}{key, key}
+		args = append(args, tieArgs...)
+		placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(tieArgs)), ", ")
+		q = q.Where("("+keyCol+" "+op+" ? OR ("+keyCol+" = ? AND ("+tieCols+") > ("+placeholders+")))", args...)
+	}
+	return q.Limit(p.Limit + 1)
+}
+
+// estimateTotal counts the rows of q exactly up to exactCountLimit and uses
+// the planner's row estimate beyond that. It runs on q's connection, so
+// inside q's transaction if it has one.
+func estimateTotal(q *gorm.DB) (int64, error) {
+	db := q.Session(&gorm.Session{NewDB: true})
+	var n int64
+	if err := db.Table("(?) AS counted", q.Limit(exactCountLimit+1)).Count(&n).Error; err != nil {
+		return 0, err
+	}
+	if n <= exactCountLimit {
+		return n, nil
+	}
+
+	var raw string
+	if err := db.Raw("EXPLAIN (FORMAT JSON) ?", q).Row().Scan(&raw); err != nil {
+		return n, nil
+	}
+	var plan []struct {
+		Plan struct {
+			Rows float64 `json:"Plan Rows"`
+		} `json:"Plan"`
+	}
+	if err := json.Unmarshal([]byte(raw), &plan); err != nil || len(plan) == 0 || int64(plan[0].Plan.Rows) < n {
+		return n, nil
+	}
+	return int64(plan[0].Plan.Rows), nil
+}
+
+func pageEnvelope(items interface{}, nextCursor string, total int64) gin.H {
+	var next interface{}
+	if nextCursor != "" {
+		next = nextCursor
+	}
+	return gin.H{"items": items, "next_cursor": next, "total_estimate": total}
+}
+
+// pageQuery fetches one page of base, which must not be ordered or limited
+// yet, sorted by page's sort and then by idCol. key returns an item's sort key
+// and ID for the next cursor. Writes a 500 and returns false on failure.
+func pageQuery[T any](c *gin.Context, page pageRequest, base *gorm.DB, idCol string, key func(T) (interface{}, uint)) ([]T, string, int64, bool) {
+	// the model gives the count subquery its table when base has none
+	base = base.Model(new(T)).Session(&gorm.Session{})
+
+	total, err := estimateTotal(base)
+	if err != nil {
+		jsonError(c, http.StatusInternalServerError, "db error: "+err.Error())
+		return nil, "", 0, false
+	}
+
+	var afterID uint
+	if page.After != nil {
+		afterID = page.After.ID
+	}
+	items := make([]T, 0, page.Limit+1)
+	if err := page.seek(base, page.spec.column, idCol, afterID).Find(&items).Error; err != nil {
+		jsonError(c, http.StatusInternalServerError, "db error: "+err.Error())
+		return nil, "", 0, false
+	}
+
+	next := ""
+	if len(items) > page.Limit {
+		items = items[:page.Limit]
+		k, id := key(items[len(items)-1])
+		next = page.cursorAfter(k, 0, id)
+	}
+	return items, next, total, true
+}
+
+// eventListSorts are the sort options of plain event lists.
+var eventListSorts = map[string]sortSpec{
+	"date":       {column: "events.date", key: "time"},
+	"created_at": {column: "events.created_at", desc: true, key: "time"},
+	"title":      {column: "events.title", key: "text"},
+}
+
+// eventSortKey returns the value of ev that the given sort orders by.
+func eventSortKey(sort string, ev Event) interface{} {
+	switch sort {
+	case "created_at":
+		return ev.CreatedAt
+	case "title":
+		return ev.Title
+	default:
+		return ev.Date
+	}
+}
+
+// pageEvents serves one page of the events matched by base, which must not
+// be ordered or limited yet.
+func pageEvents(c *gin.Context, base *gorm.DB) {
+	page, ok := parsePageRequest(c, eventListSorts, "date")
+	if !ok {
+		return
+	}
+	events, next, total, ok := pageQuery(c, page, base.Preload("Tasks").Preload("Tags"), "events.id", func(ev Event) (interface{}, uint) {
+		return eventSortKey(page.Sort, ev), ev.ID
+	})
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, pageEnvelope(events, next, total))
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+)
+
+func TestDecodeCursorRejectsGarbage(t *testing.T) {
+	cur := pageCursor{Sort: "date", Desc: true, Key: "2026-01-02T03:04:05.123456Z", Kind: 1, ID: 42}
+	got, err := decodeCursor(encodeCursor(cur))
+	if err != nil || got != cur {
+		t.Fatalf("round trip = %+v, %v; want %+v", got, err, cur)
+	}
+	for _, value := range []string{
+		"not base64!",
+		"bm90IGpzb24",                          // "not json"
+		encodeCursor(pageCursor{Sort: "date"}), // no id
+	} {
+		if _, err := decodeCursor(value); err != errBadCursor {
+			t.Errorf("decodeCursor(%q) = %v, want errBadCursor", value, err)
+		}
+	}
+}
+
+// Paging must neither repeat nor skip rows when rows are inserted or removed
+// between requests: rows before the cursor stay behind it, rows after it
+// show up in later pages.
+func TestCursorStableAcrossInserts(t *testing.T) {
+	useTestDB(t)
+	r := testRouter()
+	org := createTestUser(t, "PagerOrg")
+	start := time.Now().UTC().Add(30 * 24 * time.Hour).Truncate(time.Hour)
+
+	newEvent := func(title string, day int) Event {
+		t.Helper()
+		ev := Event{Title: title, Date: start.Add(time.Duration(day) * 24 * time.Hour), OrganizerID: org.ID, LateRSVPPolicy: "reject"}
+		if err := DB.Create(&ev).Error; err != nil {
+			t.Fatal(err)
+		}
+		return ev
+	}
+	// Two pairs share a date so the id breaks ties across page boundaries
+	for i, day := range []int{1, 2, 2, 3, 4, 4, 5} {
+		newEvent(fmt.Sprintf("E%d", i), day)
+	}
+
+	type page struct {
+		Items []struct {
+			ID    uint   `json:"id"`
+			Title string `json:"title"`
+		} `json:"items"`
+		NextCursor *string `json:"next_cursor"`
+	}
+	fetch := func(query, cursor string) page {
+		t.Helper()
+		if cursor != "" {
+			query += "&cursor=" + url.QueryEscape(cursor)
+		}
+		w := apiRequest(t, r, org.ID, http.MethodGet, "/api/events/organized?limit=3&"+query, nil)
+		if w.Code != http.StatusOK {
+			t.Fatalf("%s: status %d: %s", query, w.Code, w.Body.String())
+		}
+		var p page
+		decodeJSON(t, w, &p)
+		return p
+	}
+
+	for _, tc := range []struct {
+		query  string
+		change func()
+		want   string
+	}{
+		{
+			query: "sort=date",
+			change: func() {
+				newEvent("Before", 0)                        // sorts before the cursor: skipped
+				newEvent("Tied late", 2)                     // same date as the last row served, larger id
+				newEvent("After", 6)                         // sorts after the cursor: served
+				DB.Where("title = ?", "E3").Delete(&Event{}) // not served yet
+			},
+			want: "[E0 E1 E2] [Tied late E4 E5] [E6 After]",
+		},
+		{
+			query: "sort=title",
+			change: func() {
+				newEvent("A first", 9)
+				newEvent("Z last", 9)
+				DB.Where("title = ?", "E0").Delete(&Event{}) // already served
+			},
+			want: "[After Before E0] [E1 E2 E4] [E5 E6 Tied late] [Z last]",
+		},
+		{
+			query: "sort=created_at",
+			change: func() {
+				newEvent("Newest", 9) // newest first, so before the cursor: skipped
+			},
+			want: "[Z last A first After] [Tied late Before E6] [E5 E4 E2] [E1]",
+		},
+	} {
+		first := fetch(tc.query, "")
+		if first.NextCursor == nil {
+			t.Fatalf("%s: no next cursor", tc.query)
+		}
+		pages := []page{first}
+		tc.change()
+		for cursor := first.NextCursor; cursor != nil; {
+			p := fetch(tc.query, *cursor)
+			pages = append(pages, p)
+			cursor = p.NextCursor
+		}
+		var got []string
+		for _, p := range pages {
+			var titles []string
+			for _, item := range p.Items {
+				titles = append(titles, item.Title)
+			}
+			got = append(got, fmt.Sprint(titles))
+		}
+		if s := fmt.Sprint(got); s != "["+tc.want+"]" {
+			t.Errorf("%s: pages %s, want [%s]", tc.query, s, tc.want)
+		}
+	}
+
+	// A cursor only fits the sort it came from
+	cursor := fetch("sort=date", "").NextCursor
+	if w := apiRequest(t, r, org.ID, http.MethodGet, "/api/events/organized?sort=title&cursor="+url.QueryEscape(*cursor), nil); w.Code != http.StatusBadRequest {
+		t.Errorf("cursor from another sort: status %d, want 400", w.Code)
+	}
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Badge is one printable name badge
+type Badge struct {
+	Name     string
+	Subtitle string // email or role
+	Event    string
+}
+
+// Page and badge geometry in PDF points (A4, 2 x 4 badges per page)
+const (
+	badgePageWidth  = 595.0
+	badgePageHeight = 842.0
+	badgeColumns    = 2
+	badgeRows       = 4
+	badgeWidth      = 260.0
+	badgeHeight     = 180.0
+)
+
+// writeBadgesPDF renders badges as a minimal PDF using the built-in Helvetica fonts,
+// so no font files or third-party libraries are needed.
+func writeBadgesPDF(w io.Writer, badges []Badge) error {
+	perPage := badgeColumns * badgeRows
+	pageCount := (len(badges) + perPage - 1) / perPage
+	if pageCount == 0 {
+		pageCount = 1
+	}
+
+	// Object layout: 1 catalog, 2 page tree, 3-4 fonts, then a page + content pair per page
+	var objects []string
+	objects = append(objects, "<< /Type /Catalog /Pages 2 0 R >>")
+
+	kids := make([]string, 0, pageCount)
+	for p := 0; p < pageCount; p++ {
+		kids = append(kids, fmt.Sprintf("%d 0 R", 5+p*2))
+	}
+	objects = append(objects, fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), pageCount))
+	objects = append(objects, "<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>")
+	objects = append(objects, "<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica-Bold /Encoding /WinAnsiEncoding >>")
+
+	for p := 0; p < pageCount; p++ {
+		start := p * perPage
+		end := start + perPage
+		if end > len(badges) {
+			end = len(badges)
+		}
+		content := badgePageContent(badges[start:end])
+
+		objects = append(objects, fmt.Sprintf(
+			"<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %.0f %.0f] /Resources << /Font << /F1 3 0 R /F2 4 0 R >> >> /Contents %d 0 R >>",
+			badgePageWidth, badgePageHeight, 6+p*2))
+		objects = append(objects, fmt.Sprintf("<< /Length %d >>\nstream\n%s\nendstream", len(content), content))
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+	offsets := make([]int, len(objects))
+	for i, obj := range objects {
+		offsets[i] = buf.Len()
+		fmt.Fprintf(&buf, "%d 0 obj\n%s\nendobj\n", i+1, obj)
+	}
+
+	xref := buf.Len()
+	fmt.Fprintf(&buf, "xref\n0 %d\n0000000000 65535 f \n", len(objects)+1)
+	for _, off := range offsets {
+		fmt.Fprintf(&buf, "%010d 00000 n \n", off)
+	}
+	fmt.Fprintf(&buf, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(objects)+1, xref)
+
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// badgePageContent draws up to badgeColumns*badgeRows badges with cut lines.
+func badgePageContent(badges []Badge) string {
+	marginX := (badgePageWidth - badgeColumns*badgeWidth) / 2
+	marginY := (badgePageHeight - badgeRows*badgeHeight) / 2
+
+	var b strings.Builder
+	b.WriteString("0.6 G 0.5 w\n")
+	for i, badge := range badges {
+		col := i % badgeColumns
+		row := i / badgeColumns
+		x := marginX + float64(col)*badgeWidth
+		y := badgePageHeight - marginY - float64(row+1)*badgeHeight
+
+		fmt.Fprintf(&b, "%.2f %.2f %.2f %.2f re S\n", x, y, badgeWidth, badgeHeight)
+
+		centerX := x + badgeWidth/2
+		writeCenteredText(&b, "F1", 11, centerX, y+badgeHeight-30, badge.Event, badgeWidth-20)
+		writeCenteredText(&b, "F2", 22, centerX, y+badgeHeight/2, badge.Name, badgeWidth-20)
+		writeCenteredText(&b, "F1", 10, centerX, y+badgeHeight/2-24, badge.Subtitle, badgeWidth-20)
+	}
+	return b.String()
+}
+
+// writeCenteredText emits a text object centred on x, shrinking the font until the
+// estimated width fits maxWidth. Helvetica glyphs average roughly 0.55em.
+func writeCenteredText(b *strings.Builder, font string, size, x, y float64, text string, maxWidth float64) {
+	encoded := pdfLatin1(text)
+	if len(encoded) == 0 {
+		return
+	}
+	for size > 6 && float64(len(encoded))*size*0.55 > maxWidth {
+		size--
+	}
+	if max := int(maxWidth / (size * 0.55)); len(encoded) > max {
+		encoded = encoded[:max-1] + "."
+	}
+	width := float64(len(encoded)) * size * 0.55
+
+	fmt.Fprintf(b, "0 g BT /%s %.0f Tf %.2f %.2f Td (%s) Tj ET\n", font, size, x-width/2, y, pdfEscape(encoded))
+}
+
+// pdfLatin1 converts text to the single-byte WinAnsi range, replacing anything else with '?'.
+func pdfLatin1(s string) string {
+	out := make([]byte, 0, len(s))
+	for _, r := range s {
+		if r < 32 {
+			continue
+		}
+		if r > 255 {
+			r = '?'
+		}
+		out = append(out, byte(r))
+	}
+	return string(out)
+}
+
+func pdfEscape(s string) string {
+	r := strings.NewReplacer(`\`, `\\`, `(`, `\(`, `)`, `\)`)
+	return r.Replace(s)
+}
@@ -0,0 +1,492 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// -----------------------------
+// Date polls
+// -----------------------------
+
+var pollChoices = map[string]bool{"yes": true, "maybe": true, "no": true}
+
+var errPollNotOpen = errors.New("poll is not open")
+
+type PollVoter struct {
+	UserID uint   `json:"user_id"`
+	Choice string `json:"choice"`
+}
+
+type PollOptionResult struct {
+	DatePollOption
+	Yes                int         `json:"yes"`
+	Maybe              int         `json:"maybe"`
+	No                 int         `json:"no"`
+	PossibleAttendance int         `json:"possible_attendance"` // yes + maybe
+	MyChoice           string      `json:"my_choice,omitempty"`
+	Votes              []PollVoter `json:"votes"`
+}
+
+type PollResults struct {
+	DatePoll
+	Results      []PollOptionResult `json:"results"`        // best option first
+	BestOptionID *uint              `json:"best_option_id"` // most yes, then most maybe, then earliest
+	Members      int                `json:"members"`
+	Respondents  int                `json:"respondents"`
+}
+
+// tallyPoll counts votes per option for the poll, from userID's point of view.
+func tallyPoll(ev Event, poll DatePoll, userID uint) (PollResults, error) {
+	var votes []DatePollVote
+	if err := DB.Where("poll_id = ?", poll.ID).Order("id asc").Find(&votes).Error; err != nil {
+		return PollResults{}, err
+	}
+	members, err := eventMemberIDs(DB, ev, 0)
+	if err != nil {
+		return PollResults{}, err
+	}
+
+	byOption := make(map[uint]*PollOptionResult, len(poll.Options))
+	results := make([]PollOptionResult, len(poll.Options))
+	for i, opt := range poll.Options {
+		results[i] = PollOptionResult{DatePollOption: opt, Votes: []PollVoter{}}
+		byOption[opt.ID] = &results[i]
+	}
+	respondents := make(map[uint]bool)
+	for _, v := range votes {
+		r, ok := byOption[v.OptionID]
+		if !ok {
+			continue
+		}
+		respondents[v.UserID] = true
+		switch v.Choice {
+		case "yes":
+			r.Yes++
+		case "maybe":
+			r.Maybe++
+		case "no":
+			r.No++
+		}
+		if v.UserID == userID {
+			r.MyChoice = v.Choice
+		}
+		r.Votes = append(r.Votes, PollVoter{UserID: v.UserID, Choice: v.Choice})
+	}
+	for i := range results {
+		results[i].PossibleAttendance = results[i].Yes + results[i].Maybe
+	}
+
+	sort.SliceStable(results, func(i, j int) bool {
+		a, b := results[i], results[j]
+		if a.Yes != b.Yes {
+			return a.Yes > b.Yes
+		}
+		if a.Maybe != b.Maybe {
+			return a.Maybe > b.Maybe
+		}
+		return a.StartsAt.Before(b.StartsAt)
+	})
+
+	out := PollResults{DatePoll: poll, Results: results, Members: len(members), Respondents: len(respondents)}
+	out.Options = nil // listed in Results
+	if len(results) > 0 && results[0].Yes+results[0].Maybe > 0 {
+		out.BestOptionID = &results[0].ID
+	}
+	return out, nil
+}
+
+// findPoll loads the :poll_id path param poll of an event with its options.
+func findPoll(c *gin.Context, eventID uint) (DatePoll, bool) {
+	var poll DatePoll
+	pollID, err := strconv.ParseUint(c.Param("poll_id"), 10, 64)
+	if err != nil {
+		jsonError(c, http.StatusBadRequest, "invalid poll id")
+		return poll, false
+	}
+	if err := DB.Preload("Options", func(db *gorm.DB) *gorm.DB {
+		return db.Order("starts_at asc, id asc")
+	}).Where("id = ? AND event_id = ?", pollID, eventID).First(&poll).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			jsonError(c, http.StatusNotFound, "poll not found")
+			return poll, false
+		}
+		jsonError(c, http.StatusInternalServerError, "db error: "+err.Error())
+		return poll, false
+	}
+	return poll, true
+}
+
+type PollOptionInput struct {
+	StartsAt string `json:"starts_at" binding:"required"` // RFC3339 or YYYY-MM-DD
+	Note     string `json:"note"`
+}
+
+type CreatePollRequest struct {
+	Options  []PollOptionInput `json:"options" binding:"required"`
+	ClosesAt string            `json:"closes_at"` // optional voting deadline
+}
+
+// POST /api/events/:id/polls
+// Opens a date poll and notifies the invitees.
+func CreatePoll(c *gin.Context) {
+	userID, ok := getUserIDFromContext(c)
+	if !ok {
+		jsonError(c, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	eventID, ok := parseEventID(c)
+	if !ok {
+		return
+	}
+	ev, ok := findMemberEvent(c, eventID, userID)
+	if !ok {
+		return
+	}
+	if ev.OrganizerID != userID {
+		jsonError(c, http.StatusForbidden, "only organizer can create date polls")
+		return
+	}
+
+	var body CreatePollRequest
+	if err := c.ShouldBindJSON(&body); err != nil {
+		jsonError(c, http.StatusBadRequest, "invalid body: "+err.Error())
+		return
+	}
+	if len(body.Options) < 2 || len(body.Options) > 30 {
+		jsonError(c, http.StatusBadRequest, "a poll needs between 2 and 30 options")
+		return
+	}
+
+	poll := DatePoll{EventID: eventID, Status: "open"}
+	seen := make(map[int64]bool)
+	for i, in := range body.Options {
+		startsAt, err := parseDateInput(strings.TrimSpace(in.StartsAt))
+		if err != nil {
+			jsonError(c, http.StatusBadRequest, fmt.Sprintf("option %d: invalid starts_at format (use RFC3339 or YYYY-MM-DD)", i))
+			return
+		}
+		if seen[startsAt.Unix()] {
+			jsonError(c, http.StatusBadRequest, fmt.Sprintf("option %d: duplicate time", i))
+			return
+		}
+		seen[startsAt.Unix()] = true
+		poll.Options = append(poll.Options, DatePollOption{StartsAt: startsAt, Note: strings.TrimSpace(in.Note)})
+	}
+	if body.ClosesAt != "" {
+		closesAt, err := parseDateInput(body.ClosesAt)
+		if err != nil {
+			jsonError(c, http.StatusBadRequest, "invalid closes_at format (use RFC3339 or YYYY-MM-DD)")
+			return
+		}
+		if closesAt.Before(time.Now()) {
+			jsonError(c, http.StatusBadRequest, "closes_at must be in the future")
+			return
+		}
+		poll.ClosesAt = &closesAt
+	}
+
+	err := DB.Transaction(func(tx *gorm.DB) error {
+		// Serialize poll creation per event so two polls cannot be open at once
+		var locked Event
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id").First(&locked, eventID).Error; err != nil {
+			return err
+		}
+		var open int64
+		if err := tx.Model(&DatePoll{}).Where("event_id = ? AND status = ?", eventID, "open").Count(&open).Error; err != nil {
+			return err
+		}
+		if open > 0 {
+			return errPollNotOpen
+		}
+		if err := tx.Create(&poll).Error; err != nil {
+			return err
+		}
+		members, err := eventMemberIDs(tx, ev, userID)
+		if err != nil {
+			return err
+		}
+		return notifyUsers(tx, members, "date_poll", &ev.ID, fmt.Sprintf("Vote on a date for \"%s\"", ev.Title))
+	})
+	if err == errPollNotOpen {
+		jsonError(c, http.StatusConflict, "this event already has an open date poll")
+		return
+	}
+	if err != nil {
+		jsonError(c, http.StatusInternalServerError, "could not create poll: "+err.Error())
+		return
+	}
+
+	results, err := tallyPoll(ev, poll, userID)
+	if err != nil {
+		jsonError(c, http.StatusInternalServerError, "db error: "+err.Error())
+		return
+	}
+	c.JSON(http.StatusCreated, results)
+}
+
+var pollSorts = map[string]sortSpec{
+	"created_at": {column: "date_polls.created_at", desc: true, key: "time"},
+}
+
+// GET /api/events/:id/polls?limit=&cursor=
+// Newest first, as a page envelope (see pagination.go).
+func GetPolls(c *gin.Context) {
+	userID, ok := getUserIDFromContext(c)
+	if !ok {
+		jsonError(c, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	eventID, ok := parseEventID(c)
+	if !ok {
+		return
+	}
+	ev, ok := findMemberEvent(c, eventID, userID)
+	if !ok {
+		return
+	}
+
+	page, ok := parsePageRequest(c, pollSorts, "created_at")
+	if !ok {
+		return
+	}
+	base := DB.Preload("Options", func(db *gorm.DB) *gorm.DB {
+		return db.Order("starts_at asc, id asc")
+	}).Where("event_id = ?", eventID)
+	polls, next, total, ok := pageQuery(c, page, base, "date_polls.id", func(p DatePoll) (interface{}, uint) {
+		return p.CreatedAt, p.ID
+	})
+	if !ok {
+		return
+	}
+
+	out := make([]PollResults, 0, len(polls))
+	for _, p := range polls {
+		results, err := tallyPoll(ev, p, userID)
+		if err != nil {
+			jsonError(c, http.StatusInternalServerError, "db error: "+err.Error())
+			return
+		}
+		out = append(out, results)
+	}
+	c.JSON(http.StatusOK, pageEnvelope(out, next, total))
+}
+
+// GET /api/events/:id/polls/:poll_id
+func GetPoll(c *gin.Context) {
+	userID, ok := getUserIDFromContext(c)
+	if !ok {
+		jsonError(c, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	eventID, ok := parseEventID(c)
+	if !ok {
+		return
+	}
+	ev, ok := findMemberEvent(c, eventID, userID)
+	if !ok {
+		return
+	}
+	poll, ok := findPoll(c, eventID)
+	if !ok {
+		return
+	}
+
+	results, err := tallyPoll(ev, poll, userID)
+	if err != nil {
+		jsonError(c, http.StatusInternalServerError, "db error: "+err.Error())
+		return
+	}
+	c.JSON(http.StatusOK, results)
+}
+
+type PollVoteInput struct {
+	OptionID uint   `json:"option_id" binding:"required"`
+	Choice   string `json:"choice" binding:"required"` // yes / maybe / no
+}
+
+type PollVoteRequest struct {
+	Votes []PollVoteInput `json:"votes" binding:"required"`
+}
+
+// PUT /api/events/:id/polls/:poll_id/votes
+// Records or changes the caller's choice for the listed options.
+func VotePoll(c *gin.Context) {
+	userID, ok := getUserIDFromContext(c)
+	if !ok {
+		jsonError(c, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	eventID, ok := parseEventID(c)
+	if !ok {
+		return
+	}
+	ev, ok := findMemberEvent(c, eventID, userID)
+	if !ok {
+		return
+	}
+	poll, ok := findPoll(c, eventID)
+	if !ok {
+		return
+	}
+	if poll.Status != "open" {
+		jsonError(c, http.StatusConflict, "poll is already finalized")
+		return
+	}
+	if poll.ClosesAt != nil && time.Now().After(*poll.ClosesAt) {
+		jsonError(c, http.StatusConflict, "voting for this poll has closed")
+		return
+	}
+
+	var body PollVoteRequest
+	if err := c.ShouldBindJSON(&body); err != nil {
+		jsonError(c, http.StatusBadRequest, "invalid body: "+err.Error())
+		return
+	}
+	options := make(map[uint]bool, len(poll.Options))
+	for _, opt := range poll.Options {
+		options[opt.ID] = true
+	}
+	votes := make([]DatePollVote, 0, len(body.Votes))
+	for _, v := range body.Votes {
+		choice := strings.ToLower(strings.TrimSpace(v.Choice))
+		if !pollChoices[choice] {
+			jsonError(c, http.StatusBadRequest, "choice must be one of: yes, maybe, no")
+			return
+		}
+		if !options[v.OptionID] {
+			jsonError(c, http.StatusBadRequest, fmt.Sprintf("option %d is not part of this poll", v.OptionID))
+			return
+		}
+		votes = append(votes, DatePollVote{PollID: poll.ID, OptionID: v.OptionID, UserID: userID, Choice: choice})
+	}
+
+	if len(votes) > 0 {
+		if err := DB.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "option_id"}, {Name: "user_id"}},
+			DoUpdates: clause.AssignmentColumns([]string{"choice", "updated_at"}),
+		}).Create(&votes).Error; err != nil {
+			jsonError(c, http.StatusInternalServerError, "could not save votes: "+err.Error())
+			return
+		}
+	}
+
+	results, err := tallyPoll(ev, poll, userID)
+	if err != nil {
+		jsonError(c, http.StatusInternalServerError, "db error: "+err.Error())
+		return
+	}
+	c.JSON(http.StatusOK, results)
+}
+
+type FinalizePollRequest struct {
+	OptionID uint `json:"option_id" binding:"required"`
+}
+
+// POST /api/events/:id/polls/:poll_id/finalize
+// Sets Event.Date to the chosen option and notifies everyone on the event.
+// An end time moves along; 409 when the new slot double-books the venue.
+// An RSVP deadline that would fall after the new date is moved to the date.
+func FinalizePoll(c *gin.Context) {
+	userID, ok := getUserIDFromContext(c)
+	if !ok {
+		jsonError(c, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	eventID, ok := parseEventID(c)
+	if !ok {
+		return
+	}
+	ev, ok := findMemberEvent(c, eventID, userID)
+	if !ok {
+		return
+	}
+	if ev.OrganizerID != userID {
+		jsonError(c, http.StatusForbidden, "only organizer can finalize a poll")
+		return
+	}
+	poll, ok := findPoll(c, eventID)
+	if !ok {
+		return
+	}
+
+	var body FinalizePollRequest
+	if err := c.ShouldBindJSON(&body); err != nil {
+		jsonError(c, http.StatusBadRequest, "invalid body: "+err.Error())
+		return
+	}
+	var chosen *DatePollOption
+	for i := range poll.Options {
+		if poll.Options[i].ID == body.OptionID {
+			chosen = &poll.Options[i]
+		}
+	}
+	if chosen == nil {
+		jsonError(c, http.StatusBadRequest, "option_id is not part of this poll")
+		return
+	}
+
+	err := DB.Transaction(func(tx *gorm.DB) error {
+		// Conditional update so two concurrent finalizations cannot both win
+		res := tx.Model(&DatePoll{}).Where("id = ? AND status = ?", poll.ID, "open").
+			Updates(map[string]interface{}{"status": "finalized", "final_option_id": chosen.ID})
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return errPollNotOpen
+		}
+
+		if ev.EndsAt != nil {
+			endsAt := ev.EndsAt.Add(chosen.StartsAt.Sub(ev.Date))
+			ev.EndsAt = &endsAt
+		}
+		ev.Date = chosen.StartsAt
+		if ev.RSVPDeadline != nil && ev.RSVPDeadline.After(ev.Date) {
+			deadline := ev.Date
+			ev.RSVPDeadline = &deadline
+		}
+		if err := bookVenue(tx, &ev); err != nil {
+			return err
+		}
+		if err := tx.Model(&ev).Select("date", "ends_at", "rsvp_deadline").Updates(&ev).Error; err != nil {
+			return err
+		}
+
+		members, err := eventMemberIDs(tx, ev, userID)
+		if err != nil {
+			return err
+		}
+		return notifyUsers(tx, members, "event_date_set", &ev.ID,
+			fmt.Sprintf("\"%s\" will take place on %s", ev.Title, ev.Date.UTC().Format("Mon, 02 Jan 2006 15:04 MST")))
+	})
+	if err == errPollNotOpen {
+		jsonError(c, http.StatusConflict, "poll is already finalized")
+		return
+	}
+	if venueError(c, err) {
+		return
+	}
+	if err != nil {
+		jsonError(c, http.StatusInternalServerError, "could not finalize poll: "+err.Error())
+		return
+	}
+
+	poll.Status = "finalized"
+	poll.FinalOptionID = &chosen.ID
+	c.JSON(http.StatusOK, gin.H{"event": ev, "poll": poll})
+}
@@ -0,0 +1,172 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type notificationPage struct {
+	Items  []Notification `json:"items"`
+	Unread int64          `json:"unread"`
+}
+
+// notificationTypes lists the types of userID's notifications, oldest first.
+func notificationTypes(tb testing.TB, userID uint) []string {
+	tb.Helper()
+	var list []Notification
+	if err := DB.Where("user_id = ?", userID).Order("id asc").Find(&list).Error; err != nil {
+		tb.Fatal(err)
+	}
+	types := make([]string, 0, len(list))
+	for _, n := range list {
+		types = append(types, n.Type)
+	}
+	return types
+}
+
+func TestDatePollNotifiesMembers(t *testing.T) {
+	useTestDB(t)
+	r := testRouter()
+	org := createTestUser(t, "PollOrg")
+	ann := createTestUser(t, "PollAnn")
+	ben := createTestUser(t, "PollBen")
+	outsider := createTestUser(t, "PollOutsider")
+	ev := createTestEvent(t, org.ID, "Reunion", false)
+	inviteTestUser(t, ev.ID, ann.ID, "attendee")
+	inviteTestUser(t, ev.ID, ben.ID, "attendee")
+
+	endsAt := ev.Date.Add(4 * time.Hour)
+	if err := DB.Model(&ev).Update("ends_at", endsAt).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	pollsPath := fmt.Sprintf("/api/events/%d/polls", ev.ID)
+	var options []string
+	for i := 0; i < 3; i++ {
+		options = append(options, ev.Date.Add(time.Duration(7+i)*24*time.Hour).Format(time.RFC3339))
+	}
+	body := gin.H{"options": []gin.H{{"starts_at": options[0]}, {"starts_at": options[1]}, {"starts_at": options[2]}}}
+
+	if w := apiRequest(t, r, ann.ID, http.MethodPost, pollsPath, body); w.Code != http.StatusForbidden {
+		t.Errorf("member opens poll: status %d, want 403", w.Code)
+	}
+	w := apiRequest(t, r, org.ID, http.MethodPost, pollsPath, body)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("create poll: status %d: %s", w.Code, w.Body.String())
+	}
+	var poll PollResults
+	decodeJSON(t, w, &poll)
+	if w := apiRequest(t, r, org.ID, http.MethodPost, pollsPath, body); w.Code != http.StatusConflict {
+		t.Errorf("second open poll: status %d, want 409", w.Code)
+	}
+
+	// Everyone on the event but the organizer hears about it, once
+	for _, u := range []User{ann, ben} {
+		if got := fmt.Sprint(notificationTypes(t, u.ID)); got != "[date_poll]" {
+			t.Errorf("%s: notifications %s, want [date_poll]", u.Name, got)
+		}
+	}
+	for _, u := range []User{org, outsider} {
+		if got := notificationTypes(t, u.ID); len(got) != 0 {
+			t.Errorf("%s: notifications %v, want none", u.Name, got)
+		}
+	}
+
+	// Results are ordered by options' StartsAt on creation
+	first, second, third := poll.Results[0].ID, poll.Results[1].ID, poll.Results[2].ID
+	votesPath := fmt.Sprintf("%s/%d/votes", pollsPath, poll.ID)
+	vote := func(userID uint, votes ...gin.H) int {
+		t.Helper()
+		return apiRequest(t, r, userID, http.MethodPut, votesPath, gin.H{"votes": votes}).Code
+	}
+	for name, code := range map[string]int{
+		"invalid choice": vote(ann.ID, gin.H{"option_id": first, "choice": "perhaps"}),
+		"foreign option": vote(ann.ID, gin.H{"option_id": third + 100, "choice": "yes"}),
+	} {
+		if code != http.StatusBadRequest {
+			t.Errorf("%s: status %d, want 400", name, code)
+		}
+	}
+	if code := vote(outsider.ID, gin.H{"option_id": first, "choice": "yes"}); code != http.StatusNotFound {
+		t.Errorf("outsider votes: status %d, want 404", code)
+	}
+	for _, v := range []struct {
+		user  uint
+		votes []gin.H
+	}{
+		{ann.ID, []gin.H{{"option_id": first, "choice": "yes"}, {"option_id": second, "choice": "Yes"}}},
+		{ben.ID, []gin.H{{"option_id": second, "choice": "maybe"}, {"option_id": third, "choice": "no"}}},
+		{ann.ID, []gin.H{{"option_id": first, "choice": "no"}}}, // a change of mind
+	} {
+		if code := vote(v.user, v.votes...); code != http.StatusOK {
+			t.Fatalf("vote %v: status %d", v.votes, code)
+		}
+	}
+
+	w = apiRequest(t, r, ann.ID, http.MethodGet, fmt.Sprintf("%s/%d", pollsPath, poll.ID), nil)
+	decodeJSON(t, w, &poll)
+	if poll.BestOptionID == nil || *poll.BestOptionID != second || poll.Members != 3 || poll.Respondents != 2 {
+		t.Errorf("best %v, members %d, respondents %d", poll.BestOptionID, poll.Members, poll.Respondents)
+	}
+	if best := poll.Results[0]; best.Yes != 1 || best.Maybe != 1 || best.PossibleAttendance != 2 || best.MyChoice != "yes" {
+		t.Errorf("best option = %+v", best)
+	}
+
+	finalizePath := fmt.Sprintf("%s/%d/finalize", pollsPath, poll.ID)
+	if w := apiRequest(t, r, ann.ID, http.MethodPost, finalizePath, gin.H{"option_id": second}); w.Code != http.StatusForbidden {
+		t.Errorf("member finalizes: status %d, want 403", w.Code)
+	}
+	w = apiRequest(t, r, org.ID, http.MethodPost, finalizePath, gin.H{"option_id": second})
+	if w.Code != http.StatusOK {
+		t.Fatalf("finalize: status %d: %s", w.Code, w.Body.String())
+	}
+	var final struct {
+		Event Event    `json:"event"`
+		Poll  DatePoll `json:"poll"`
+	}
+	decodeJSON(t, w, &final)
+	date, _ := time.Parse(time.RFC3339, options[1])
+	if !final.Event.Date.Equal(date) || final.Event.EndsAt == nil || !final.Event.EndsAt.Equal(date.Add(4*time.Hour)) {
+		t.Errorf("finalized event date %v, ends %v", final.Event.Date, final.Event.EndsAt)
+	}
+	if final.Poll.Status != "finalized" || final.Poll.FinalOptionID == nil || *final.Poll.FinalOptionID != second {
+		t.Errorf("finalized poll = %+v", final.Poll)
+	}
+
+	for _, u := range []User{ann, ben} {
+		if got := fmt.Sprint(notificationTypes(t, u.ID)); got != "[date_poll event_date_set]" {
+			t.Errorf("%s: notifications %s", u.Name, got)
+		}
+	}
+	if got := notificationTypes(t, org.ID); len(got) != 0 {
+		t.Errorf("organizer: notifications %v, want none", got)
+	}
+
+	if w := apiRequest(t, r, org.ID, http.MethodPost, finalizePath, gin.H{"option_id": third}); w.Code != http.StatusConflict {
+		t.Errorf("finalize twice: status %d, want 409", w.Code)
+	}
+	if code := vote(ben.ID, gin.H{"option_id": second, "choice": "yes"}); code != http.StatusConflict {
+		t.Errorf("vote after finalizing: status %d, want 409", code)
+	}
+
+	// The inbox counts them until read
+	var inbox notificationPage
+	decodeJSON(t, apiRequest(t, r, ann.ID, http.MethodGet, "/api/notifications?unread=true", nil), &inbox)
+	if len(inbox.Items) != 2 || inbox.Unread != 2 || inbox.Items[0].Type != "event_date_set" {
+		t.Fatalf("inbox = %+v", inbox)
+	}
+	if w := apiRequest(t, r, ann.ID, http.MethodPost, fmt.Sprintf("/api/notifications/%d/read", inbox.Items[0].ID), nil); w.Code != http.StatusOK {
+		t.Fatalf("mark read: status %d: %s", w.Code, w.Body.String())
+	}
+	if w := apiRequest(t, r, ben.ID, http.MethodPost, fmt.Sprintf("/api/notifications/%d/read", inbox.Items[1].ID), nil); w.Code != http.StatusNotFound {
+		t.Errorf("read someone else's notification: status %d, want 404", w.Code)
+	}
+	decodeJSON(t, apiRequest(t, r, ann.ID, http.MethodGet, "/api/notifications", nil), &inbox)
+	if len(inbox.Items) != 2 || inbox.Unread != 1 {
+		t.Errorf("after reading one: %d items, %d unread", len(inbox.Items), inbox.Unread)
+	}
+}
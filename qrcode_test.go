@@ -0,0 +1,231 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+	"testing"
+)
+
+// Golden values below come from ISO/IEC 18004 (format and version tables,
+// the "HELLO WORLD" 1-M example) and were cross-checked against ZXing's encoder.
+
+// readFormat returns both copies of the 15-bit format information.
+func readFormat(modules [][]bool) (first, second int) {
+	size := len(modules)
+	bit := func(x, y int) int {
+		if modules[y][x] {
+			return 1
+		}
+		return 0
+	}
+	for i := 0; i <= 5; i++ {
+		first |= bit(8, i) << uint(i)
+	}
+	first |= bit(8, 7) << 6
+	first |= bit(8, 8) << 7
+	first |= bit(7, 8) << 8
+	for i := 9; i < 15; i++ {
+		first |= bit(14-i, 8) << uint(i)
+	}
+	for i := 0; i < 8; i++ {
+		second |= bit(size-1-i, 8) << uint(i)
+	}
+	for i := 8; i < 15; i++ {
+		second |= bit(8, size-15+i) << uint(i)
+	}
+	return first, second
+}
+
+var qrFormatM = []int{0x5412, 0x5125, 0x5E7C, 0x5B4B, 0x45F9, 0x40CE, 0x4F97, 0x4AA0}
+
+func TestQRFormatBits(t *testing.T) {
+	for mask, want := range qrFormatM {
+		q := newQRMatrix(1)
+		q.drawFormat(mask)
+		first, second := readFormat(q.modules)
+		if first != want || second != want {
+			t.Errorf("mask %d: format bits = %#x/%#x, want %#x", mask, first, second, want)
+		}
+		if !q.modules[q.size-8][8] {
+			t.Errorf("mask %d: dark module is not set", mask)
+		}
+	}
+}
+
+func TestQRVersionBits(t *testing.T) {
+	golden := map[int]int{7: 0x07C94, 8: 0x085BC, 9: 0x09A99, 10: 0x0A4D3}
+	for version, want := range golden {
+		q := newQRMatrix(version)
+		q.drawFunctionPatterns(qrVersionsM[version])
+		lowerLeft, upperRight := 0, 0
+		for i := 0; i < 18; i++ {
+			a, b := q.size-11+i%3, i/3
+			if q.modules[b][a] {
+				upperRight |= 1 << uint(i)
+			}
+			if q.modules[a][b] {
+				lowerLeft |= 1 << uint(i)
+			}
+		}
+		if upperRight != want || lowerLeft != want {
+			t.Errorf("version %d: version bits = %#x/%#x, want %#x", version, upperRight, lowerLeft, want)
+		}
+	}
+}
+
+func TestQRErrorCorrectionCodewords(t *testing.T) {
+	cases := []struct {
+		data, ec []byte
+	}{
+		// "HELLO WORLD" at 1-M
+		{
+			data: []byte{32, 91, 11, 120, 209, 114, 220, 77, 67, 64, 236, 17, 236, 17, 236, 17},
+			ec:   []byte{196, 35, 39, 119, 235, 215, 231, 226, 93, 23},
+		},
+		// First block of the 5-Q example: 18 EC codewords
+		{
+			data: []byte{67, 85, 70, 134, 87, 38, 85, 194, 119, 50, 6, 18, 6, 103, 38},
+			ec:   []byte{213, 199, 11, 45, 115, 247, 241, 223, 229, 248, 154, 117, 154, 111, 86, 161, 111, 39},
+		},
+	}
+	for _, tc := range cases {
+		if got := rsRemainder(tc.data, rsDivisor(len(tc.ec))); !bytes.Equal(got, tc.ec) {
+			t.Errorf("rsRemainder(%v) = %v, want %v", tc.data, got, tc.ec)
+		}
+	}
+}
+
+func TestQRDataCodewords(t *testing.T) {
+	want := []byte{
+		64, 198, 134, 86, 198, 198, 242, 194, 7, 118, 247, 38, 198, 64, 236, 17,
+		13, 207, 159, 24, 55, 33, 46, 162, 149, 247,
+	}
+	got := qrInterleave(qrVersionsM[1], qrDataCodewords([]byte("hello, world"), 1, qrVersionsM[1].dataCodewords()))
+	if !bytes.Equal(got, want) {
+		t.Fatalf("codewords = %v, want %v", got, want)
+	}
+}
+
+// long9 is a ticket-like payload that needs a version 9 symbol.
+func long9() string { return "1.1767225600." + strings.Repeat("ticket-", 20) }
+
+func TestEncodeQRGoldenSymbol(t *testing.T) {
+	want := []string{
+		"#######..#.##.#######",
+		"#.....#.##..#.#.....#",
+		"#.###.#..#..#.#.###.#",
+		"#.###.#...##..#.###.#",
+		"#.###.#.#..##.#.###.#",
+		"#.....#....#..#.....#",
+		"#######.#.#.#.#######",
+		"..........#..........",
+		"#.#.#.#..#..#...#..#.",
+		"#.##...###.#....#..##",
+		".#..####.###.#.######",
+		"####.#.######..#...#.",
+		".######.#.##....#....",
+		"........##.#..###.###",
+		"#######..#..##..#.###",
+		"#.....#....#...#...#.",
+		"#.###.#.##.###.#...#.",
+		"#.###.#..#.###.##.##.",
+		"#.###.#.#..##...#.#.#",
+		"#.....#..#.#....#..#.",
+		"#######.####...#...##",
+	}
+	qr, err := EncodeQR([]byte("hello, world"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if qr.Size != len(want) {
+		t.Fatalf("size = %d, want %d", qr.Size, len(want))
+	}
+	for y, row := range qr.Modules {
+		var sb strings.Builder
+		for _, dark := range row {
+			if dark {
+				sb.WriteByte('#')
+			} else {
+				sb.WriteByte('.')
+			}
+		}
+		if sb.String() != want[y] {
+			t.Errorf("row %2d = %s\n          want %s", y, sb.String(), want[y])
+		}
+	}
+
+	// A version 9 symbol: two block groups, six alignment patterns and version bits
+	qr, err = EncodeQR([]byte(long9()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if qr.Size != 9*4+17 {
+		t.Fatalf("size = %d, want version 9", qr.Size)
+	}
+	h := sha256.New()
+	for _, row := range qr.Modules {
+		for _, dark := range row {
+			if dark {
+				h.Write([]byte{1})
+			} else {
+				h.Write([]byte{0})
+			}
+		}
+	}
+	if got := hex.EncodeToString(h.Sum(nil)); got != "de7ea8259318f64cde99cb459ad714a1c9832a7015697fa7b7400d556c43ce2d" {
+		t.Errorf("version 9 symbol digest = %s", got)
+	}
+}
+
+func TestEncodeQRMaskSelection(t *testing.T) {
+	golden := map[string]int{"hello, world": 0, "abc": 2, "ticket": 6, "event planner": 6, long9(): 1}
+	for data, want := range golden {
+		qr, err := EncodeQR([]byte(data))
+		if err != nil {
+			t.Fatal(err)
+		}
+		first, second := readFormat(qr.Modules)
+		if first != second {
+			t.Fatalf("%q: format copies differ: %#x/%#x", data, first, second)
+		}
+		mask := -1
+		for m, bits := range qrFormatM {
+			if bits == first {
+				mask = m
+			}
+		}
+		if mask != want {
+			t.Errorf("%q: mask = %d, want %d", data, mask, want)
+		}
+
+		// The chosen mask must have the lowest penalty
+		version := (qr.Size - 17) / 4
+		info := qrVersionsM[version]
+		q := newQRMatrix(version)
+		q.drawFunctionPatterns(info)
+		q.placeData(qrInterleave(info, qrDataCodewords([]byte(data), version, info.dataCodewords())))
+		penalties := make([]int, 8)
+		for m := range penalties {
+			q.applyMask(m)
+			q.drawFormat(m)
+			penalties[m] = q.penalty()
+			q.applyMask(m)
+		}
+		for m, p := range penalties {
+			if p < penalties[mask] {
+				t.Errorf("%q: mask %d scores %d, below the chosen mask %d (%d)", data, m, p, mask, penalties[mask])
+			}
+		}
+	}
+}
+
+func TestEncodeQRTooLong(t *testing.T) {
+	if _, err := EncodeQR(bytes.Repeat([]byte("x"), 214)); err != errQRTooLong {
+		t.Fatalf("err = %v, want errQRTooLong", err)
+	}
+	if _, err := EncodeQR(bytes.Repeat([]byte("x"), 213)); err != nil {
+		t.Fatalf("213 bytes: %v", err)
+	}
+}
@@ -0,0 +1,587 @@
+package main
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// -----------------------------
+// RSVP questionnaires
+// -----------------------------
+//
+// An organizer attaches a list of questions to an event. Attendees answer them
+// when they respond through SetAttendance; answers are stored per EventAttendee.
+
+var questionTypes = map[string]bool{
+	"text":          true,
+	"single_choice": true,
+	"multi_choice":  true,
+	"number":        true,
+}
+
+// errForeignQuestion rejects question IDs from another event's questionnaire.
+var errForeignQuestion = errors.New("question does not belong to this event")
+
+type QuestionInput struct {
+	ID       uint     `json:"id"` // set to update an existing question, omit to add one
+	Label    string   `json:"label" binding:"required"`
+	Type     string   `json:"type" binding:"required"`
+	Options  []string `json:"options"`
+	Required bool     `json:"required"`
+}
+
+type QuestionnaireRequest struct {
+	Questions []QuestionInput `json:"questions"`
+}
+
+type AnswerInput struct {
+	QuestionID uint        `json:"question_id" binding:"required"`
+	Value      interface{} `json:"value"` // string, number or array of strings depending on question type
+}
+
+// loadQuestionnaire returns the questions of an event in display order.
+func loadQuestionnaire(eventID uint) ([]QuestionnaireQuestion, error) {
+	var questions []QuestionnaireQuestion
+	err := DB.Where("event_id = ?", eventID).Order("position asc, id asc").Find(&questions).Error
+	return questions, err
+}
+
+// validateAnswers checks submitted answers against the questionnaire and converts
+// them to their stored form, keyed by question ID.
+func validateAnswers(questions []QuestionnaireQuestion, answers []AnswerInput) (map[uint][]string, error) {
+	byID := make(map[uint]QuestionnaireQuestion, len(questions))
+	for _, q := range questions {
+		byID[q.ID] = q
+	}
+
+	out := make(map[uint][]string, len(answers))
+	for _, a := range answers {
+		q, ok := byID[a.QuestionID]
+		if !ok {
+			return nil, fmt.Errorf("question %d does not belong to this event", a.QuestionID)
+		}
+		if _, dup := out[q.ID]; dup {
+			return nil, fmt.Errorf("question %d answered more than once", q.ID)
+		}
+
+		values, err := normalizeAnswer(q, a.Value)
+		if err != nil {
+			return nil, fmt.Errorf("%q: %v", q.Label, err)
+		}
+		out[q.ID] = values
+	}
+	return out, nil
+}
+
+func normalizeAnswer(q QuestionnaireQuestion, value interface{}) ([]string, error) {
+	if value == nil {
+		return nil, nil
+	}
+
+	switch q.Type {
+	case "text":
+		s, ok := value.(string)
+		if !ok {
+			return nil, fmt.Errorf("answer must be a string")
+		}
+		s = strings.TrimSpace(s)
+		if s == "" {
+			return nil, nil
+		}
+		return []string{s}, nil
+
+	case "number":
+		var n float64
+		switch v := value.(type) {
+		case float64:
+			n = v
+		case string:
+			parsed, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+			if err != nil {
+				return nil, fmt.Errorf("answer must be a number")
+			}
+			n = parsed
+		default:
+			return nil, fmt.Errorf("answer must be a number")
+		}
+		return []string{strconv.FormatFloat(n, 'f', -1, 64)}, nil
+
+	case "single_choice":
+		s, ok := value.(string)
+		if !ok {
+			return nil, fmt.Errorf("answer must be one of the options")
+		}
+		if s == "" {
+			return nil, nil
+		}
+		if !containsString(q.Options, s) {
+			return nil, fmt.Errorf("%q is not a valid option", s)
+		}
+		return []string{s}, nil
+
+	case "multi_choice":
+		list, ok := value.([]interface{})
+		if !ok {
+			return nil, fmt.Errorf("answer must be a list of options")
+		}
+		values := make([]string, 0, len(list))
+		for _, item := range list {
+			s, ok := item.(string)
+			if !ok || !containsString(q.Options, s) {
+				return nil, fmt.Errorf("%v is not a valid option", item)
+			}
+			if !containsString(values, s) {
+				values = append(values, s)
+			}
+		}
+		if len(values) == 0 {
+			return nil, nil
+		}
+		return values, nil
+	}
+
+	return nil, fmt.Errorf("unsupported question type %q", q.Type)
+}
+
+// saveAnswers upserts the answers of one attendee. An empty answer clears
+// any previously stored value for that question.
+func saveAnswers(tx *gorm.DB, attendeeID uint, answers map[uint][]string) error {
+	for questionID, values := range answers {
+		if len(values) == 0 {
+			if err := tx.Where("event_attendee_id = ? AND question_id = ?", attendeeID, questionID).Delete(&AttendeeAnswer{}).Error; err != nil {
+				return err
+			}
+			continue
+		}
+
+		ans := AttendeeAnswer{
+			EventAttendeeID: attendeeID,
+			QuestionID:      questionID,
+			Values:          values,
+		}
+		if err := tx.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "event_attendee_id"}, {Name: "question_id"}},
+			DoUpdates: clause.AssignmentColumns([]string{"answer_values", "updated_at"}),
+		}).Create(&ans).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// missingRequired returns the labels of required questions that have no answer.
+func missingRequired(questions []QuestionnaireQuestion, answers map[uint][]string) []string {
+	missing := make([]string, 0)
+	for _, q := range questions {
+		if q.Required && len(answers[q.ID]) == 0 {
+			missing = append(missing, q.Label)
+		}
+	}
+	return missing
+}
+
+// pruneAnswers drops stored answers that no longer fit a question after an
+// edit: all of them when the type changed, otherwise the values that are not
+// among the new options.
+func pruneAnswers(tx *gorm.DB, before, after QuestionnaireQuestion) error {
+	if before.Type != after.Type {
+		return tx.Where("question_id = ?", after.ID).Delete(&AttendeeAnswer{}).Error
+	}
+	if after.Type != "single_choice" && after.Type != "multi_choice" {
+		return nil
+	}
+
+	var answers []AttendeeAnswer
+	if err := tx.Where("question_id = ?", after.ID).Find(&answers).Error; err != nil {
+		return err
+	}
+	for _, ans := range answers {
+		values := make([]string, 0, len(ans.Values))
+		for _, v := range ans.Values {
+			if containsString(after.Options, v) {
+				values = append(values, v)
+			}
+		}
+		if len(values) == len(ans.Values) {
+			continue
+		}
+		if err := saveAnswers(tx, ans.EventAttendeeID, map[uint][]string{after.ID: values}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// PUT /api/events/:id/questionnaire
+// Replaces the questionnaire. Questions sent with an ID are updated in place so
+// existing answers are kept, minus those the new type or options invalidate;
+// questions left out are deleted with their answers.
+func SetQuestionnaire(c *gin.Context) {
+	userID, ok := getUserIDFromContext(c)
+	if !ok {
+		jsonError(c, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	eventID, ok := parseEventID(c)
+	if !ok {
+		return
+	}
+	ev, ok := findMemberEvent(c, eventID, userID)
+	if !ok {
+		return
+	}
+	if ev.OrganizerID != userID {
+		jsonError(c, http.StatusForbidden, "only organizer can edit the questionnaire")
+		return
+	}
+
+	var body QuestionnaireRequest
+	if err := c.ShouldBindJSON(&body); err != nil {
+		jsonError(c, http.StatusBadRequest, "invalid body: "+err.Error())
+		return
+	}
+
+	for i, q := range body.Questions {
+		q.Label = strings.TrimSpace(q.Label)
+		q.Type = strings.ToLower(strings.TrimSpace(q.Type))
+		if q.Label == "" {
+			jsonError(c, http.StatusBadRequest, fmt.Sprintf("question %d: label is required", i+1))
+			return
+		}
+		if !questionTypes[q.Type] {
+			jsonError(c, http.StatusBadRequest, fmt.Sprintf("question %d: type must be one of: text, single_choice, multi_choice, number", i+1))
+			return
+		}
+		if q.Type == "single_choice" || q.Type == "multi_choice" {
+			if len(q.Options) == 0 {
+				jsonError(c, http.StatusBadRequest, fmt.Sprintf("question %d: choice questions need options", i+1))
+				return
+			}
+		} else {
+			q.Options = nil
+		}
+		body.Questions[i] = q
+	}
+
+	existing, err := loadQuestionnaire(eventID)
+	if err != nil {
+		jsonError(c, http.StatusInternalServerError, "db error: "+err.Error())
+		return
+	}
+	existingByID := make(map[uint]QuestionnaireQuestion, len(existing))
+	for _, q := range existing {
+		existingByID[q.ID] = q
+	}
+
+	if err := DB.Transaction(func(tx *gorm.DB) error {
+		kept := make(map[uint]bool)
+		for i, in := range body.Questions {
+			q := QuestionnaireQuestion{
+				ID:       in.ID,
+				EventID:  eventID,
+				Label:    in.Label,
+				Type:     in.Type,
+				Options:  in.Options,
+				Required: in.Required,
+				Position: i,
+			}
+			if in.ID != 0 {
+				before, ok := existingByID[in.ID]
+				if !ok {
+					return fmt.Errorf("question %d: %w", in.ID, errForeignQuestion)
+				}
+				kept[in.ID] = true
+				if err := tx.Model(&q).Select("label", "type", "options", "required", "position").Updates(&q).Error; err != nil {
+					return err
+				}
+				if err := pruneAnswers(tx, before, q); err != nil {
+					return err
+				}
+				continue
+			}
+			if err := tx.Create(&q).Error; err != nil {
+				return err
+			}
+		}
+
+		removed := make([]uint, 0)
+		for id := range existingByID {
+			if !kept[id] {
+				removed = append(removed, id)
+			}
+		}
+		if len(removed) > 0 {
+			if err := tx.Where("question_id IN ?", removed).Delete(&AttendeeAnswer{}).Error; err != nil {
+				return err
+			}
+			if err := tx.Delete(&QuestionnaireQuestion{}, removed).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	}); err != nil {
+		if errors.Is(err, errForeignQuestion) {
+			jsonError(c, http.StatusBadRequest, err.Error())
+			return
+		}
+		jsonError(c, http.StatusInternalServerError, "could not save questionnaire: "+err.Error())
+		return
+	}
+
+	questions, err := loadQuestionnaire(eventID)
+	if err != nil {
+		jsonError(c, http.StatusInternalServerError, "db error: "+err.Error())
+		return
+	}
+	c.JSON(http.StatusOK, questions)
+}
+
+var questionSorts = map[string]sortSpec{
+	"position": {column: "questionnaire_questions.position", key: "float"},
+}
+
+// pageQuestions serves one page of an event's questions in display order.
+func pageQuestions(c *gin.Context, eventID uint) ([]QuestionnaireQuestion, string, int64, bool) {
+	page, ok := parsePageRequest(c, questionSorts, "position")
+	if !ok {
+		return nil, "", 0, false
+	}
+	return pageQuery(c, page, DB.Where("event_id = ?", eventID), "questionnaire_questions.id", func(q QuestionnaireQuestion) (interface{}, uint) {
+		return float64(q.Position), q.ID
+	})
+}
+
+// GET /api/events/:id/questionnaire?limit=&cursor=
+// Readable by the event's members, and by anyone when the event is public
+// (they may RSVP to it). Returns a page envelope (see pagination.go).
+func GetQuestionnaire(c *gin.Context) {
+	userID, ok := getUserIDFromContext(c)
+	if !ok {
+		jsonError(c, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	eventID, ok := parseEventID(c)
+	if !ok {
+		return
+	}
+	if _, ok := findVisibleEvent(c, eventID, userID); !ok {
+		return
+	}
+
+	questions, next, total, ok := pageQuestions(c, eventID)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, pageEnvelope(questions, next, total))
+}
+
+type QuestionSummary struct {
+	Question  QuestionnaireQuestion `json:"question"`
+	Responses int                   `json:"responses"`
+	Counts    map[string]int        `json:"counts,omitempty"` // choice questions
+	Min       *float64              `json:"min,omitempty"`    // number questions
+	Max       *float64              `json:"max,omitempty"`
+	Average   *float64              `json:"average,omitempty"`
+	Sum       *float64              `json:"sum,omitempty"`
+	Values    []string              `json:"values,omitempty"` // text questions
+}
+
+// GET /api/events/:id/questionnaire/summary?limit=&cursor=
+// One summary per question, in display order, as a page envelope (see
+// pagination.go).
+func GetQuestionnaireSummary(c *gin.Context) {
+	userID, ok := getUserIDFromContext(c)
+	if !ok {
+		jsonError(c, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	eventID, ok := parseEventID(c)
+	if !ok {
+		return
+	}
+	ev, ok := findMemberEvent(c, eventID, userID)
+	if !ok {
+		return
+	}
+	if ev.OrganizerID != userID {
+		jsonError(c, http.StatusForbidden, "only organizer can view questionnaire responses")
+		return
+	}
+
+	questions, next, total, ok := pageQuestions(c, eventID)
+	if !ok {
+		return
+	}
+	questionIDs := make([]uint, 0, len(questions))
+	for _, q := range questions {
+		questionIDs = append(questionIDs, q.ID)
+	}
+	var answers []AttendeeAnswer
+	if len(questionIDs) > 0 {
+		if err := DB.Where("question_id IN ?", questionIDs).Find(&answers).Error; err != nil {
+			jsonError(c, http.StatusInternalServerError, "db error: "+err.Error())
+			return
+		}
+	}
+
+	byQuestion := make(map[uint][]AttendeeAnswer)
+	for _, a := range answers {
+		byQuestion[a.QuestionID] = append(byQuestion[a.QuestionID], a)
+	}
+
+	summaries := make([]QuestionSummary, 0, len(questions))
+	for _, q := range questions {
+		s := QuestionSummary{Question: q}
+		for _, a := range byQuestion[q.ID] {
+			if len(a.Values) == 0 {
+				continue
+			}
+			s.Responses++
+
+			switch q.Type {
+			case "single_choice", "multi_choice":
+				if s.Counts == nil {
+					s.Counts = make(map[string]int, len(q.Options))
+					for _, opt := range q.Options {
+						s.Counts[opt] = 0
+					}
+				}
+				for _, v := range a.Values {
+					s.Counts[v]++
+				}
+			case "number":
+				n, err := strconv.ParseFloat(a.Values[0], 64)
+				if err != nil {
+					continue
+				}
+				if s.Sum == nil {
+					s.Min, s.Max, s.Sum = new(float64), new(float64), new(float64)
+					*s.Min, *s.Max = n, n
+				}
+				if n < *s.Min {
+					*s.Min = n
+				}
+				if n > *s.Max {
+					*s.Max = n
+				}
+				*s.Sum += n
+			case "text":
+				s.Values = append(s.Values, a.Values[0])
+			}
+		}
+		if s.Sum != nil && s.Responses > 0 {
+			avg := *s.Sum / float64(s.Responses)
+			s.Average = &avg
+		}
+		summaries = append(summaries, s)
+	}
+
+	c.JSON(http.StatusOK, pageEnvelope(summaries, next, total))
+}
+
+// csvCell neutralizes text a spreadsheet would run as a formula by prefixing
+// a quote. Plain numbers such as "-5" are left alone.
+func csvCell(s string) string {
+	if s == "" || !strings.ContainsRune("=+-@\t\r", rune(s[0])) {
+		return s
+	}
+	if _, err := strconv.ParseFloat(s, 64); err == nil {
+		return s
+	}
+	return "'" + s
+}
+
+// csvSafeRow applies csvCell to every cell of a row.
+func csvSafeRow(row []string) []string {
+	out := make([]string, len(row))
+	for i, v := range row {
+		out[i] = csvCell(v)
+	}
+	return out
+}
+
+// GET /api/events/:id/questionnaire/export
+// Streams one CSV row per attendee with their answers. Cells that would start
+// a formula are escaped (see csvCell).
+func ExportQuestionnaireResponses(c *gin.Context) {
+	userID, ok := getUserIDFromContext(c)
+	if !ok {
+		jsonError(c, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	eventID, ok := parseEventID(c)
+	if !ok {
+		return
+	}
+	ev, ok := findMemberEvent(c, eventID, userID)
+	if !ok {
+		return
+	}
+	if ev.OrganizerID != userID {
+		jsonError(c, http.StatusForbidden, "only organizer can export questionnaire responses")
+		return
+	}
+
+	questions, err := loadQuestionnaire(eventID)
+	if err != nil {
+		jsonError(c, http.StatusInternalServerError, "db error: "+err.Error())
+		return
+	}
+
+	var attendees []EventAttendee
+	if err := DB.Preload("Answers").Where("event_id = ? AND role = ?", eventID, "attendee").Order("id asc").Find(&attendees).Error; err != nil {
+		jsonError(c, http.StatusInternalServerError, "db error: "+err.Error())
+		return
+	}
+
+	users, err := attendeeUsers(attendees)
+	if err != nil {
+		jsonError(c, http.StatusInternalServerError, "db error: "+err.Error())
+		return
+	}
+
+	c.Header("Content-Type", "text/csv; charset=utf-8")
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=\"event-%d-responses.csv\"", eventID))
+	c.Status(http.StatusOK)
+
+	w := csv.NewWriter(c.Writer)
+	header := []string{"user_id", "email", "status"}
+	for _, q := range questions {
+		header = append(header, q.Label)
+	}
+	_ = w.Write(csvSafeRow(header))
+
+	for _, a := range attendees {
+		answers := make(map[uint][]string, len(a.Answers))
+		for _, ans := range a.Answers {
+			answers[ans.QuestionID] = ans.Values
+		}
+		row := []string{strconv.FormatUint(uint64(a.UserID), 10), users[a.UserID].Email, a.Status}
+		for _, q := range questions {
+			row = append(row, strings.Join(answers[q.ID], "; "))
+		}
+		_ = w.Write(csvSafeRow(row))
+	}
+	w.Flush()
+}
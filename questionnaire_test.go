@@ -0,0 +1,138 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestGetQuestionnaireVisibility(t *testing.T) {
+	f := newAuthFixture(t)
+	r := testRouter()
+	public := createTestEvent(t, f.Organizer.ID, "Street fair", true)
+
+	cases := []struct {
+		name    string
+		userID  uint
+		eventID uint
+		want    int
+	}{
+		{"member of private event", f.Member.ID, f.Event.ID, http.StatusOK},
+		{"outsider on private event", f.Outsider.ID, f.Event.ID, http.StatusNotFound},
+		{"outsider on public event", f.Outsider.ID, public.ID, http.StatusOK},
+	}
+	for _, tc := range cases {
+		w := apiRequest(t, r, tc.userID, http.MethodGet, fmt.Sprintf("/api/events/%d/questionnaire", tc.eventID), nil)
+		if w.Code != tc.want {
+			t.Errorf("%s: got %d %s, want %d", tc.name, w.Code, w.Body, tc.want)
+		}
+	}
+}
+
+func TestCSVCellEscapesFormulas(t *testing.T) {
+	cases := map[string]string{
+		"=HYPERLINK(\"http://x\")": "'=HYPERLINK(\"http://x\")",
+		"+1+2":                     "'+1+2",
+		"-2+3":                     "'-2+3",
+		"@SUM(A1)":                 "'@SUM(A1)",
+		"\t=1":                     "'\t=1",
+		"-5":                       "-5",
+		"+1.5":                     "+1.5",
+		"Veggie":                   "Veggie",
+		"":                         "",
+	}
+	for in, want := range cases {
+		if got := csvCell(in); got != want {
+			t.Errorf("csvCell(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+// Editing a question's type or options must not leave answers behind that
+// the question no longer accepts.
+func TestSetQuestionnairePrunesAnswers(t *testing.T) {
+	f := newAuthFixture(t)
+	r := testRouter()
+	path := fmt.Sprintf("/api/events/%d/questionnaire", f.Event.ID)
+
+	var questions []QuestionnaireQuestion
+	w := apiRequest(t, r, f.Organizer.ID, http.MethodPut, path, gin.H{"questions": []gin.H{
+		{"label": "Meal", "type": "single_choice", "options": []string{"Veg", "Meat", "Fish"}},
+		{"label": "Drinks", "type": "multi_choice", "options": []string{"Beer", "Wine", "Water"}},
+		{"label": "Notes", "type": "text"},
+		{"label": "Age", "type": "text"},
+	}})
+	if w.Code != http.StatusOK {
+		t.Fatalf("set questionnaire: status %d: %s", w.Code, w.Body.String())
+	}
+	decodeJSON(t, w, &questions)
+	meal, drinks, notes, age := questions[0].ID, questions[1].ID, questions[2].ID, questions[3].ID
+
+	w = apiRequest(t, r, f.Member.ID, http.MethodPost, fmt.Sprintf("/api/events/%d/respond", f.Event.ID), gin.H{
+		"status": "Going",
+		"answers": []gin.H{
+			{"question_id": meal, "value": "Fish"},
+			{"question_id": drinks, "value": []string{"Beer", "Water"}},
+			{"question_id": notes, "value": "=HYPERLINK(\"http://evil\")"},
+			{"question_id": age, "value": "thirty"},
+		},
+	})
+	if w.Code != http.StatusOK {
+		t.Fatalf("respond: status %d: %s", w.Code, w.Body.String())
+	}
+
+	// The export must not hand the formula to a spreadsheet
+	w = apiRequest(t, r, f.Organizer.ID, http.MethodGet, path+"/export", nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("export: status %d: %s", w.Code, w.Body.String())
+	}
+	records, err := csv.NewReader(w.Body).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := records[1][5]; got != "'=HYPERLINK(\"http://evil\")" {
+		t.Errorf("exported notes = %q", got)
+	}
+
+	// Unknown question IDs are the caller's fault
+	w = apiRequest(t, r, f.Organizer.ID, http.MethodPut, path, gin.H{"questions": []gin.H{
+		{"id": notes + 1000, "label": "Stolen", "type": "text"},
+	}})
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("foreign question: status %d, want 400", w.Code)
+	}
+
+	w = apiRequest(t, r, f.Organizer.ID, http.MethodPut, path, gin.H{"questions": []gin.H{
+		{"id": meal, "label": "Meal", "type": "single_choice", "options": []string{"Veg", "Meat"}},
+		{"id": drinks, "label": "Drinks", "type": "multi_choice", "options": []string{"Beer", "Wine"}},
+		{"id": notes, "label": "Notes", "type": "text"},
+		{"id": age, "label": "Age", "type": "number"},
+	}})
+	if w.Code != http.StatusOK {
+		t.Fatalf("edit questionnaire: status %d: %s", w.Code, w.Body.String())
+	}
+
+	var answers []AttendeeAnswer
+	if err := DB.Where("question_id IN ?", []uint{meal, drinks, notes, age}).Find(&answers).Error; err != nil {
+		t.Fatal(err)
+	}
+	got := make(map[uint][]string)
+	for _, a := range answers {
+		got[a.QuestionID] = a.Values
+	}
+	if _, ok := got[meal]; ok {
+		t.Errorf("answer with a removed option kept: %v", got[meal])
+	}
+	if v := got[drinks]; len(v) != 1 || v[0] != "Beer" {
+		t.Errorf("drinks = %v, want [Beer]", v)
+	}
+	if _, ok := got[notes]; !ok {
+		t.Error("unchanged question lost its answer")
+	}
+	if _, ok := got[age]; ok {
+		t.Errorf("answer kept across a type change: %v", got[age])
+	}
+}
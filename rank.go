@@ -0,0 +1,73 @@
+package main
+
+// -----------------------------
+// Fractional ranks
+// -----------------------------
+//
+// Board positions are strings that sort lexicographically. A new position can
+// always be generated between two existing ones, so moving a card only
+// rewrites the moved row. Keys use base-36 digits and never end in '0', which
+// guarantees there is room between any two distinct keys.
+//
+// Queries must compare positions with COLLATE "C" so the database orders them
+// byte-wise rather than by locale rules.
+
+const rankDigits = "0123456789abcdefghijklmnopqrstuvwxyz"
+
+func rankDigit(b byte) int {
+	switch {
+	case b >= '0' && b <= '9':
+		return int(b - '0')
+	case b >= 'a' && b <= 'z':
+		return int(b-'a') + 10
+	}
+	return 0
+}
+
+// rankBetween returns a key strictly between a and b. An empty a means "before
+// everything", an empty b means "after everything". Requires a < b when both are set.
+func rankBetween(a, b string) string {
+	if b != "" {
+		// Skip the common prefix (a is treated as padded with '0')
+		n := 0
+		for n < len(b) {
+			ca := byte(rankDigits[0])
+			if n < len(a) {
+				ca = a[n]
+			}
+			if ca != b[n] {
+				break
+			}
+			n++
+		}
+		if n > 0 {
+			rest := ""
+			if n < len(a) {
+				rest = a[n:]
+			}
+			return b[:n] + rankBetween(rest, b[n:])
+		}
+	}
+
+	lo := 0
+	if a != "" {
+		lo = rankDigit(a[0])
+	}
+	hi := len(rankDigits)
+	if b != "" {
+		hi = rankDigit(b[0])
+	}
+
+	if hi-lo > 1 {
+		return string(rankDigits[(lo+hi)/2])
+	}
+	// Adjacent digits: keep a's first digit and look further right
+	if b != "" && len(b) > 1 {
+		return b[:1]
+	}
+	rest := ""
+	if len(a) > 1 {
+		rest = a[1:]
+	}
+	return string(rankDigits[lo]) + rankBetween(rest, "")
+}
@@ -7,6 +7,7 @@ func SetupRoutes(r *gin.Engine) {
     // Public Routes
     r.POST("/signup", Signup)
     r.POST("/login", Login)
+    r.GET("/files/:attachment_id", DownloadSignedAttachment) // signed, time-limited links
 
     // Protected Routes
     authorized := r.Group("/api")
@@ -17,6 +18,19 @@ func SetupRoutes(r *gin.Engine) {
         authorized.GET("/events/organized", GetOrganizedEvents)
         authorized.GET("/events/invited", GetInvitedEvents)
         authorized.DELETE("/events/:id", DeleteEvent)
+        authorized.PUT("/events/:id/rsvp-deadline", SetRSVPDeadline)
+        authorized.PUT("/events/:id/visibility", SetEventVisibility)
+        authorized.PUT("/events/:id/category", SetEventCategory)
+        authorized.PUT("/events/:id/tags", SetEventTags)
+        authorized.PUT("/events/:id/venue", SetEventVenue)
+        authorized.GET("/events/nearby", GetNearbyEvents)
+        authorized.POST("/events/:id/clone", CloneEvent)
+
+        // TEMPLATES
+        authorized.POST("/templates", CreateTemplate)
+        authorized.GET("/templates", GetTemplates)
+        authorized.GET("/templates/:template_id", GetTemplate)
+        authorized.DELETE("/templates/:template_id", DeleteTemplate)
 
         // INVITATIONS
         authorized.POST("/events/:id/invite", InviteUser)
@@ -24,12 +38,109 @@ func SetupRoutes(r *gin.Engine) {
         // ATTENDANCE
         authorized.POST("/events/:id/respond", SetAttendance)
         authorized.GET("/events/:id/attendees", GetEventAttendees)
+        authorized.GET("/events/:id/attendees/export", ExportAttendees)
+        authorized.GET("/events/:id/rsvp-history", GetRSVPHistory)
+        authorized.GET("/events/:id/analytics", GetRSVPAnalytics)
+
+        // CHECK-IN
+        authorized.GET("/events/:id/checkin/qr", GetCheckinQR)
+        authorized.POST("/events/:id/checkin", CheckIn)
+        authorized.GET("/events/:id/checkin/stats", GetCheckinStats)
+        authorized.PUT("/events/:id/helpers", SetCheckinHelper)
+
+        // QUESTIONNAIRES
+        authorized.GET("/events/:id/questionnaire", GetQuestionnaire)
+        authorized.PUT("/events/:id/questionnaire", SetQuestionnaire)
+        authorized.GET("/events/:id/questionnaire/summary", GetQuestionnaireSummary)
+        authorized.GET("/events/:id/questionnaire/export", ExportQuestionnaireResponses)
 
         // TASKS
         authorized.POST("/events/:id/tasks", CreateTask)
         authorized.GET("/events/:id/tasks", GetTasksByEvent)
+        authorized.PATCH("/events/:id/tasks/:task_id", UpdateTask)
+        authorized.DELETE("/events/:id/tasks/:task_id", DeleteTask)
+        authorized.PUT("/events/:id/tasks/:task_id/assignees", SetTaskAssignees)
+        authorized.GET("/events/:id/tasks/graph", GetTaskGraph)
+        authorized.POST("/events/:id/tasks/:task_id/dependencies", AddTaskDependency)
+        authorized.DELETE("/events/:id/tasks/:task_id/dependencies/:depends_on_id", RemoveTaskDependency)
+        authorized.POST("/events/:id/tasks/:task_id/checklist", AddChecklistItem)
+        authorized.PATCH("/events/:id/tasks/:task_id/checklist/:item_id", UpdateChecklistItem)
+        authorized.DELETE("/events/:id/tasks/:task_id/checklist/:item_id", DeleteChecklistItem)
+        authorized.POST("/events/:id/tasks/:task_id/move", MoveTask)
+        authorized.GET("/events/:id/board", GetBoard)
+        authorized.POST("/events/:id/board/columns", CreateBoardColumn)
+        authorized.PATCH("/events/:id/board/columns/:column_id", UpdateBoardColumn)
+        authorized.DELETE("/events/:id/board/columns/:column_id", DeleteBoardColumn)
+        authorized.GET("/tasks/mine", GetMyTasks)
+
+        // COMMENTS
+        authorized.GET("/events/:id/comments", GetComments)
+        authorized.POST("/events/:id/comments", CreateComment)
+        authorized.PATCH("/events/:id/comments/:comment_id", UpdateComment)
+        authorized.DELETE("/events/:id/comments/:comment_id", DeleteComment)
+
+        // ATTACHMENTS
+        authorized.POST("/events/:id/attachments", UploadAttachment)
+        authorized.GET("/events/:id/attachments", GetAttachments)
+        authorized.GET("/events/:id/attachments/:attachment_id", DownloadAttachment)
+        authorized.DELETE("/events/:id/attachments/:attachment_id", DeleteAttachment)
+
+        // DATE POLLS
+        authorized.POST("/events/:id/polls", CreatePoll)
+        authorized.GET("/events/:id/polls", GetPolls)
+        authorized.GET("/events/:id/polls/:poll_id", GetPoll)
+        authorized.PUT("/events/:id/polls/:poll_id/votes", VotePoll)
+        authorized.POST("/events/:id/polls/:poll_id/finalize", FinalizePoll)
+
+        // BUDGET
+        authorized.GET("/events/:id/budget/rates", GetBudgetRates)
+        authorized.PUT("/events/:id/budget/rates", SetBudgetRates)
+        authorized.POST("/events/:id/budget/items", CreateBudgetItem)
+        authorized.GET("/events/:id/budget/items", GetBudgetItems)
+        authorized.PATCH("/events/:id/budget/items/:item_id", UpdateBudgetItem)
+        authorized.DELETE("/events/:id/budget/items/:item_id", DeleteBudgetItem)
+        authorized.GET("/events/:id/budget/report", GetBudgetReport)
+        authorized.GET("/events/:id/budget/split", GetBudgetSplit)
+        authorized.POST("/events/:id/expenses", CreateExpense)
+        authorized.GET("/events/:id/expenses", GetExpenses)
+        authorized.PATCH("/events/:id/expenses/:expense_id", UpdateExpense)
+        authorized.DELETE("/events/:id/expenses/:expense_id", DeleteExpense)
+
+        // NOTIFICATIONS
+        authorized.GET("/notifications", GetNotifications)
+        authorized.POST("/notifications/read-all", MarkAllNotificationsRead)
+        authorized.POST("/notifications/:notification_id/read", MarkNotificationRead)
 
         // SEARCH
         authorized.GET("/events/search", SearchHandler)  // FIXED NAME
+        authorized.GET("/events/autocomplete", Autocomplete)
+
+        // TAGS & CATEGORIES
+        authorized.GET("/categories", GetCategories)
+        authorized.GET("/tags", GetTags)
+        authorized.GET("/tags/stats", GetTagStats)
+        authorized.POST("/tags", CreateTag)
+        authorized.PUT("/tags/:tag_id", UpdateTag)
+        authorized.DELETE("/tags/:tag_id", DeleteTag)
+
+        // VENUES
+        authorized.POST("/venues", CreateVenue)
+        authorized.GET("/venues", GetVenues)
+        authorized.GET("/venues/:venue_id", GetVenue)
+        authorized.PUT("/venues/:venue_id", UpdateVenue)
+        authorized.DELETE("/venues/:venue_id", DeleteVenue)
+        authorized.GET("/venues/:venue_id/conflicts", GetVenueConflicts)
+
+        // FREE/BUSY
+        authorized.GET("/freebusy", GetFreeBusy)
+        authorized.GET("/events/:id/availability", GetEventAvailability)
+
+        // SAVED SEARCHES
+        authorized.POST("/saved-searches", CreateSavedSearch)
+        authorized.GET("/saved-searches", GetSavedSearches)
+        authorized.GET("/saved-searches/:search_id", GetSavedSearch)
+        authorized.PUT("/saved-searches/:search_id", UpdateSavedSearch)
+        authorized.DELETE("/saved-searches/:search_id", DeleteSavedSearch)
+        authorized.GET("/saved-searches/:search_id/hits", GetSavedSearchHits)
     }
 }
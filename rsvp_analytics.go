@@ -0,0 +1,199 @@
+package main
+
+import (
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// -----------------------------
+// RSVP history & analytics
+// -----------------------------
+
+var rsvpHistorySorts = map[string]sortSpec{
+	"created_at": {column: "rsvp_changes.created_at", key: "time"},
+}
+
+// GET /api/events/:id/rsvp-history?user_id=&limit=&cursor=
+// Returns the status change log of an event, oldest first, as a page
+// envelope (see pagination.go).
+func GetRSVPHistory(c *gin.Context) {
+	userID, ok := getUserIDFromContext(c)
+	if !ok {
+		jsonError(c, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	eventID, ok := parseEventID(c)
+	if !ok {
+		return
+	}
+	ev, ok := findMemberEvent(c, eventID, userID)
+	if !ok {
+		return
+	}
+	if ev.OrganizerID != userID {
+		jsonError(c, http.StatusForbidden, "only organizer can view RSVP history")
+		return
+	}
+
+	page, ok := parsePageRequest(c, rsvpHistorySorts, "created_at")
+	if !ok {
+		return
+	}
+	query := DB.Where("event_id = ?", eventID)
+	if uid := c.Query("user_id"); uid != "" {
+		filterID, err := strconv.ParseUint(uid, 10, 64)
+		if err != nil {
+			jsonError(c, http.StatusBadRequest, "invalid user_id")
+			return
+		}
+		query = query.Where("user_id = ?", filterID)
+	}
+
+	changes, next, total, ok := pageQuery(c, page, query, "rsvp_changes.id", func(ch RSVPChange) (interface{}, uint) {
+		return ch.CreatedAt, ch.ID
+	})
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, pageEnvelope(changes, next, total))
+}
+
+type RSVPTimelineDay struct {
+	Date      string         `json:"date"` // YYYY-MM-DD (UTC)
+	Responses map[string]int `json:"responses"`
+}
+
+type RSVPAnalytics struct {
+	EventID      uint           `json:"event_id"`
+	Invited      int            `json:"invited"`
+	Responded    int            `json:"responded"`
+	ResponseRate float64        `json:"response_rate"` // responded / invited, 0..1
+	StatusCounts map[string]int `json:"status_counts"`
+
+	// Hours between the invitation (attendee row creation) and the first response
+	AvgHoursToRespond    *float64 `json:"avg_hours_to_respond"`
+	MedianHoursToRespond *float64 `json:"median_hours_to_respond"`
+
+	// Churn counts status changes after the first response, keyed "From -> To"
+	Churn         map[string]int    `json:"churn"`
+	ChangedMind   int               `json:"changed_mind"` // attendees with at least one change after their first response
+	LateResponses int               `json:"late_responses"`
+	DailyTimeline []RSVPTimelineDay `json:"daily_timeline"`
+}
+
+// GET /api/events/:id/analytics
+func GetRSVPAnalytics(c *gin.Context) {
+	userID, ok := getUserIDFromContext(c)
+	if !ok {
+		jsonError(c, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	eventID, ok := parseEventID(c)
+	if !ok {
+		return
+	}
+	ev, ok := findMemberEvent(c, eventID, userID)
+	if !ok {
+		return
+	}
+	if ev.OrganizerID != userID {
+		jsonError(c, http.StatusForbidden, "only organizer can view analytics")
+		return
+	}
+
+	var attendees []EventAttendee
+	if err := DB.Where("event_id = ? AND role = ?", eventID, "attendee").Find(&attendees).Error; err != nil {
+		jsonError(c, http.StatusInternalServerError, "db error: "+err.Error())
+		return
+	}
+
+	var changes []RSVPChange
+	if err := DB.Where("event_id = ?", eventID).Order("created_at asc, id asc").Find(&changes).Error; err != nil {
+		jsonError(c, http.StatusInternalServerError, "db error: "+err.Error())
+		return
+	}
+
+	report := RSVPAnalytics{
+		EventID:      eventID,
+		Invited:      len(attendees),
+		StatusCounts: map[string]int{"Going": 0, "Maybe": 0, "Not Going": 0},
+		Churn:        make(map[string]int),
+	}
+
+	invitedAt := make(map[uint]time.Time, len(attendees))
+	for _, a := range attendees {
+		invitedAt[a.ID] = a.CreatedAt
+		if a.Status != "" {
+			report.Responded++
+			report.StatusCounts[a.Status]++
+		}
+		if a.LateResponse {
+			report.LateResponses++
+		}
+	}
+	if report.Invited > 0 {
+		report.ResponseRate = float64(report.Responded) / float64(report.Invited)
+	}
+
+	firstResponse := make(map[uint]bool)
+	changedMind := make(map[uint]bool)
+	hours := make([]float64, 0)
+	days := make(map[string]map[string]int)
+
+	for _, ch := range changes {
+		invited, isAttendee := invitedAt[ch.EventAttendeeID]
+		if !isAttendee {
+			continue
+		}
+
+		if !firstResponse[ch.EventAttendeeID] {
+			firstResponse[ch.EventAttendeeID] = true
+			d := ch.CreatedAt.Sub(invited).Hours()
+			if d < 0 {
+				d = 0
+			}
+			hours = append(hours, d)
+		} else if ch.FromStatus != "" {
+			report.Churn[ch.FromStatus+" -> "+ch.ToStatus]++
+			changedMind[ch.EventAttendeeID] = true
+		}
+
+		day := ch.CreatedAt.UTC().Format("2006-01-02")
+		if days[day] == nil {
+			days[day] = make(map[string]int)
+		}
+		days[day][ch.ToStatus]++
+	}
+	report.ChangedMind = len(changedMind)
+
+	if len(hours) > 0 {
+		sort.Float64s(hours)
+		sum := 0.0
+		for _, h := range hours {
+			sum += h
+		}
+		avg := sum / float64(len(hours))
+		median := hours[len(hours)/2]
+		if len(hours)%2 == 0 {
+			median = (hours[len(hours)/2-1] + hours[len(hours)/2]) / 2
+		}
+		report.AvgHoursToRespond = &avg
+		report.MedianHoursToRespond = &median
+	}
+
+	report.DailyTimeline = make([]RSVPTimelineDay, 0, len(days))
+	for day, counts := range days {
+		report.DailyTimeline = append(report.DailyTimeline, RSVPTimelineDay{Date: day, Responses: counts})
+	}
+	sort.Slice(report.DailyTimeline, func(i, j int) bool {
+		return report.DailyTimeline[i].Date < report.DailyTimeline[j].Date
+	})
+
+	c.JSON(http.StatusOK, report)
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type rsvpHistoryPage struct {
+	Items      []RSVPChange `json:"items"`
+	NextCursor *string      `json:"next_cursor"`
+}
+
+func TestRSVPHistoryAndAnalytics(t *testing.T) {
+	useTestDB(t)
+	r := testRouter()
+	org := createTestUser(t, "AnalyticsOrg")
+	ann := createTestUser(t, "AnalyticsAnn")
+	ben := createTestUser(t, "AnalyticsBen")
+	cat := createTestUser(t, "AnalyticsCat")
+	dan := createTestUser(t, "AnalyticsDan")
+	ev := createTestEvent(t, org.ID, "Analytics dinner", false)
+	for _, u := range []User{ann, ben, cat, dan} {
+		inviteTestUser(t, ev.ID, u.ID, "attendee")
+	}
+
+	respond := func(actor uint, body gin.H) {
+		t.Helper()
+		w := apiRequest(t, r, actor, http.MethodPost, fmt.Sprintf("/api/events/%d/respond", ev.ID), body)
+		if w.Code != http.StatusOK {
+			t.Fatalf("respond %v: status %d: %s", body, w.Code, w.Body.String())
+		}
+	}
+	respond(ann.ID, gin.H{"status": "Going"})
+	respond(ann.ID, gin.H{"status": "going"}) // same status: no history row
+	respond(ben.ID, gin.H{"status": "Maybe"})
+	respond(ben.ID, gin.H{"status": "Not Going"})
+	respond(ben.ID, gin.H{"status": "Going"})
+	respond(org.ID, gin.H{"status": "Maybe", "user_id": cat.ID}) // organizer override
+
+	// History is append-only, oldest first, and records who made the change
+	var page rsvpHistoryPage
+	w := apiRequest(t, r, org.ID, http.MethodGet, fmt.Sprintf("/api/events/%d/rsvp-history", ev.ID), nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("history: status %d: %s", w.Code, w.Body.String())
+	}
+	decodeJSON(t, w, &page)
+	if len(page.Items) != 5 {
+		t.Fatalf("history has %d changes, want 5", len(page.Items))
+	}
+	last := page.Items[4]
+	if last.UserID != cat.ID || last.ChangedByID != org.ID || last.FromStatus != "" || last.ToStatus != "Maybe" {
+		t.Errorf("override change = %+v", last)
+	}
+
+	w = apiRequest(t, r, org.ID, http.MethodGet, fmt.Sprintf("/api/events/%d/rsvp-history?user_id=%d", ev.ID, ben.ID), nil)
+	decodeJSON(t, w, &page)
+	var chain []string
+	for _, ch := range page.Items {
+		chain = append(chain, ch.FromStatus+">"+ch.ToStatus)
+	}
+	if got := fmt.Sprint(chain); got != "[>Maybe Maybe>Not Going Not Going>Going]" {
+		t.Errorf("ben's history = %s", got)
+	}
+
+	if w := apiRequest(t, r, ann.ID, http.MethodGet, fmt.Sprintf("/api/events/%d/rsvp-history", ev.ID), nil); w.Code != http.StatusForbidden {
+		t.Errorf("member history: status %d, want 403", w.Code)
+	}
+
+	var report RSVPAnalytics
+	w = apiRequest(t, r, org.ID, http.MethodGet, fmt.Sprintf("/api/events/%d/analytics", ev.ID), nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("analytics: status %d: %s", w.Code, w.Body.String())
+	}
+	decodeJSON(t, w, &report)
+
+	if report.Invited != 4 || report.Responded != 3 || math.Abs(report.ResponseRate-0.75) > 1e-9 {
+		t.Errorf("invited/responded/rate = %d/%d/%v, want 4/3/0.75", report.Invited, report.Responded, report.ResponseRate)
+	}
+	if report.StatusCounts["Going"] != 2 || report.StatusCounts["Maybe"] != 1 || report.StatusCounts["Not Going"] != 0 {
+		t.Errorf("status counts = %v", report.StatusCounts)
+	}
+	if report.Churn["Maybe -> Not Going"] != 1 || report.Churn["Not Going -> Going"] != 1 || len(report.Churn) != 2 {
+		t.Errorf("churn = %v", report.Churn)
+	}
+	if report.ChangedMind != 1 {
+		t.Errorf("changed_mind = %d, want 1", report.ChangedMind)
+	}
+	if report.AvgHoursToRespond == nil || report.MedianHoursToRespond == nil || *report.AvgHoursToRespond > 1 {
+		t.Errorf("hours to respond = %v / %v", report.AvgHoursToRespond, report.MedianHoursToRespond)
+	}
+
+	today := time.Now().UTC().Format("2006-01-02")
+	total := 0
+	for _, day := range report.DailyTimeline {
+		for _, n := range day.Responses {
+			total += n
+		}
+	}
+	if len(report.DailyTimeline) == 0 || report.DailyTimeline[len(report.DailyTimeline)-1].Date != today || total != 5 {
+		t.Errorf("timeline = %+v, want 5 responses ending %s", report.DailyTimeline, today)
+	}
+}
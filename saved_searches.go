@@ -0,0 +1,432 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// -----------------------------
+// Saved searches
+// -----------------------------
+//
+// A saved search stores the keyword, date range, role, type and tags of a search.
+// The scheduler re-runs every saved search on an interval
+// (SAVED_SEARCH_INTERVAL, default 5m), looking only at events that changed,
+// whose tasks changed, or that the owner joined, since the previous run. New matches are
+// recorded as SavedSearchHit rows and sent as in-app notifications.
+//
+// Timestamps are taken when a row is written, not when it commits, so each run
+// looks back savedSearchOverlap before the previous one; hits already recorded
+// are skipped, so the overlap never notifies twice.
+
+const savedSearchOverlap = 2 * time.Minute
+
+type SavedSearchRequest struct {
+	Name      string `json:"name" binding:"required"`
+	Keyword   string `json:"keyword"`
+	StartDate string `json:"start_date"`
+	EndDate   string `json:"end_date"`
+	Role      string `json:"role"`
+	Type      string `json:"type"` // event / task / both (default)
+	Tag       string `json:"tag"`  // comma separated; matches events with any of these tags
+}
+
+// toSavedSearch validates the request the way SearchHandler would.
+func (body SavedSearchRequest) toSavedSearch() (SavedSearch, error) {
+	s := SavedSearch{
+		Name:      strings.TrimSpace(body.Name),
+		Keyword:   strings.TrimSpace(body.Keyword),
+		StartDate: strings.TrimSpace(body.StartDate),
+		EndDate:   strings.TrimSpace(body.EndDate),
+		Role:      strings.ToLower(strings.Join(splitList(body.Role), ",")),
+		Type:      strings.ToLower(strings.TrimSpace(body.Type)),
+	}
+	tags := make([]string, 0)
+	for _, name := range splitList(body.Tag) {
+		tag := normalizeTagName(name)
+		if tag == "" {
+			return s, fmt.Errorf("invalid tag: %s", name)
+		}
+		tags = append(tags, tag)
+	}
+	s.Tag = strings.Join(tags, ",")
+	if s.Name == "" {
+		return s, errors.New("name is required")
+	}
+	if s.Type == "" {
+		s.Type = "both"
+	}
+	if s.Type != "event" && s.Type != "task" && s.Type != "both" {
+		return s, errors.New("type must be 'event', 'task' or 'both'")
+	}
+	if s.StartDate != "" {
+		if _, err := parseDateInput(s.StartDate); err != nil {
+			return s, errors.New("invalid start_date format")
+		}
+	}
+	if s.EndDate != "" {
+		if _, err := parseDateInput(s.EndDate); err != nil {
+			return s, errors.New("invalid end_date format")
+		}
+	}
+	if _, err := (SearchRequest{Role: s.Role}).facetFilters(); err != nil {
+		return s, err
+	}
+	return s, nil
+}
+
+// savedSearchMatches returns the events matching s that are not yet hits
+// and that changed, had a task added or changed, or that the owner joined,
+// after since. It runs in tx, whose word similarity threshold must be set.
+func savedSearchMatches(tx *gorm.DB, s SavedSearch, since time.Time) ([]Event, error) {
+	q := tx.Model(&Event{}).
+		Scopes(visibleEvents(s.UserID)).
+		Where("(events.updated_at > ? "+
+			"OR EXISTS (SELECT 1 FROM tasks ct WHERE ct.event_id = events.id AND ct.updated_at > ?) "+
+			"OR EXISTS (SELECT 1 FROM event_attendees na "+
+			"WHERE na.event_id = events.id AND na.user_id = ? AND na.created_at > ?))", since, since, s.UserID, since).
+		Where("NOT EXISTS (SELECT 1 FROM saved_search_hits h WHERE h.saved_search_id = ? AND h.event_id = events.id)", s.ID)
+
+	if s.StartDate != "" {
+		start, _ := parseDateInput(s.StartDate)
+		q = q.Where("events.date >= ?", start)
+	}
+	if s.EndDate != "" {
+		end, _ := parseDateInput(s.EndDate)
+		q = q.Where("events.date <= ?", end.Add(23*time.Hour+59*time.Minute+59*time.Second))
+	}
+
+	attendee := "EXISTS (SELECT 1 FROM event_attendees ra WHERE ra.event_id = events.id AND ra.user_id = ? AND ra.role = 'attendee')"
+	roles := make([]string, 0, 3)
+	roleArgs := make([]interface{}, 0, 3)
+	for _, role := range splitList(s.Role) {
+		switch role {
+		case "organizer":
+			roles = append(roles, "events.organizer_id = ?")
+			roleArgs = append(roleArgs, s.UserID)
+		case "attendee":
+			roles = append(roles, attendee)
+			roleArgs = append(roleArgs, s.UserID)
+		case "public":
+			roles = append(roles, "(events.organizer_id <> ? AND NOT "+attendee+")")
+			roleArgs = append(roleArgs, s.UserID, s.UserID)
+		}
+	}
+	if len(roles) > 0 {
+		q = q.Where("("+strings.Join(roles, " OR ")+")", roleArgs...)
+	}
+	if tags := splitList(s.Tag); len(tags) > 0 {
+		q = q.Where("EXISTS (SELECT 1 FROM event_tags st JOIN tags sg ON sg.id = st.tag_id "+
+			"WHERE st.event_id = events.id AND sg.name IN ?)", tags)
+	}
+
+	// same matching rules as SearchHandler; a task match reports its event
+	if s.Keyword != "" {
+		tsq, tsqArgs := buildTSQuery(s.Keyword)
+		tsqArgs = append(tsqArgs, fuzzyText(s.Keyword))
+		q = q.Joins("CROSS JOIN (SELECT "+tsq+" AS q, ?::text AS fz) AS sq", tsqArgs...)
+
+		eventMatch := "events.search_vector @@ sq.q OR sq.fz <% events.title OR sq.fz <% events.location"
+		taskMatch := "EXISTS (SELECT 1 FROM tasks t WHERE t.event_id = events.id AND " +
+			"(t.search_vector @@ sq.q OR sq.fz <% t.title))"
+		switch s.Type {
+		case "event":
+			q = q.Where("(" + eventMatch + ")")
+		case "task":
+			q = q.Where(taskMatch)
+		default:
+			q = q.Where("(" + eventMatch + " OR " + taskMatch + ")")
+		}
+	} else if s.Type == "task" {
+		q = q.Where("EXISTS (SELECT 1 FROM tasks t WHERE t.event_id = events.id)")
+	}
+
+	var events []Event
+	err := q.Order("events.id asc").Find(&events).Error
+	return events, err
+}
+
+// checkSavedSearch records and notifies the new matches of s.
+func checkSavedSearch(s SavedSearch) (int, error) {
+	now := time.Now()
+	notified := 0
+	err := DB.Transaction(func(tx *gorm.DB) error {
+		if err := setWordSimilarity(tx, defaultSimilarity); err != nil {
+			return err
+		}
+		// Look back past the previous run for rows that committed late, but
+		// never before the criteria were last saved
+		since := s.LastCheckedAt.Add(-savedSearchOverlap)
+		if since.Before(s.UpdatedAt) {
+			since = s.UpdatedAt
+		}
+		events, err := savedSearchMatches(tx, s, since)
+		if err != nil {
+			return err
+		}
+		for _, ev := range events {
+			hit := SavedSearchHit{SavedSearchID: s.ID, EventID: ev.ID}
+			res := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&hit)
+			if res.Error != nil {
+				return res.Error
+			}
+			if res.RowsAffected == 0 {
+				continue // reported by an overlapping run
+			}
+			msg := fmt.Sprintf("New match for your saved search \"%s\": %s", s.Name, ev.Title)
+			if err := notifyUsers(tx, []uint{s.UserID}, "saved_search", &ev.ID, msg); err != nil {
+				return err
+			}
+			notified++
+		}
+		return tx.Model(&SavedSearch{}).Where("id = ?", s.ID).UpdateColumn("last_checked_at", now).Error
+	})
+	return notified, err
+}
+
+func runSavedSearches() {
+	var searches []SavedSearch
+	if err := DB.Find(&searches).Error; err != nil {
+		log.Printf("⚠️ Saved searches: %v", err)
+		return
+	}
+	for _, s := range searches {
+		if _, err := checkSavedSearch(s); err != nil {
+			log.Printf("⚠️ Saved search %d: %v", s.ID, err)
+		}
+	}
+}
+
+// StartSavedSearchScheduler runs the saved searches in the background.
+func StartSavedSearchScheduler() {
+	interval := 5 * time.Minute
+	if v := strings.TrimSpace(os.Getenv("SAVED_SEARCH_INTERVAL")); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d <= 0 {
+			log.Fatalf("❌ Invalid SAVED_SEARCH_INTERVAL %q", v)
+		}
+		interval = d
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			runSavedSearches()
+		}
+	}()
+	log.Printf("🔔 Checking saved searches every %s", interval)
+}
+
+func findSavedSearch(c *gin.Context, userID uint) (SavedSearch, bool) {
+	var s SavedSearch
+	id, err := strconv.ParseUint(c.Param("search_id"), 10, 64)
+	if err != nil {
+		jsonError(c, http.StatusBadRequest, "invalid saved search id")
+		return s, false
+	}
+	if err := DB.Where("id = ? AND user_id = ?", id, userID).First(&s).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			jsonError(c, http.StatusNotFound, "saved search not found")
+			return s, false
+		}
+		jsonError(c, http.StatusInternalServerError, "db error: "+err.Error())
+		return s, false
+	}
+	return s, true
+}
+
+// POST /api/saved-searches
+// Only events that change after saving are reported.
+func CreateSavedSearch(c *gin.Context) {
+	userID, ok := getUserIDFromContext(c)
+	if !ok {
+		jsonError(c, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	var body SavedSearchRequest
+	if err := c.ShouldBindJSON(&body); err != nil {
+		jsonError(c, http.StatusBadRequest, "invalid body: "+err.Error())
+		return
+	}
+	s, err := body.toSavedSearch()
+	if err != nil {
+		jsonError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	s.UserID = userID
+	s.LastCheckedAt = time.Now()
+
+	if err := DB.Create(&s).Error; err != nil {
+		jsonError(c, http.StatusInternalServerError, "could not save search: "+err.Error())
+		return
+	}
+	c.JSON(http.StatusCreated, s)
+}
+
+var savedSearchSorts = map[string]sortSpec{
+	"name": {column: "saved_searches.name", key: "text"},
+}
+
+// GET /api/saved-searches?limit=&cursor=
+// The caller's saved searches by name, as a page envelope (see pagination.go).
+func GetSavedSearches(c *gin.Context) {
+	userID, ok := getUserIDFromContext(c)
+	if !ok {
+		jsonError(c, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	page, ok := parsePageRequest(c, savedSearchSorts, "name")
+	if !ok {
+		return
+	}
+	list, next, total, ok := pageQuery(c, page, DB.Where("user_id = ?", userID), "saved_searches.id", func(s SavedSearch) (interface{}, uint) {
+		return s.Name, s.ID
+	})
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, pageEnvelope(list, next, total))
+}
+
+// GET /api/saved-searches/:search_id
+func GetSavedSearch(c *gin.Context) {
+	userID, ok := getUserIDFromContext(c)
+	if !ok {
+		jsonError(c, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	s, ok := findSavedSearch(c, userID)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, s)
+}
+
+// PUT /api/saved-searches/:search_id
+// Replaces the criteria; events changed before the update are not reported.
+func UpdateSavedSearch(c *gin.Context) {
+	userID, ok := getUserIDFromContext(c)
+	if !ok {
+		jsonError(c, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	s, ok := findSavedSearch(c, userID)
+	if !ok {
+		return
+	}
+	var body SavedSearchRequest
+	if err := c.ShouldBindJSON(&body); err != nil {
+		jsonError(c, http.StatusBadRequest, "invalid body: "+err.Error())
+		return
+	}
+	updated, err := body.toSavedSearch()
+	if err != nil {
+		jsonError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	updated.ID = s.ID
+	updated.UserID = s.UserID
+	updated.CreatedAt = s.CreatedAt
+	updated.LastCheckedAt = time.Now()
+
+	if err := DB.Save(&updated).Error; err != nil {
+		jsonError(c, http.StatusInternalServerError, "could not update saved search: "+err.Error())
+		return
+	}
+	c.JSON(http.StatusOK, updated)
+}
+
+// DELETE /api/saved-searches/:search_id
+func DeleteSavedSearch(c *gin.Context) {
+	userID, ok := getUserIDFromContext(c)
+	if !ok {
+		jsonError(c, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	s, ok := findSavedSearch(c, userID)
+	if !ok {
+		return
+	}
+	err := DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("saved_search_id = ?", s.ID).Delete(&SavedSearchHit{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&s).Error
+	})
+	if err != nil {
+		jsonError(c, http.StatusInternalServerError, "delete failed: "+err.Error())
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "saved search deleted"})
+}
+
+var savedSearchHitSorts = map[string]sortSpec{
+	"matched_at": {column: "saved_search_hits.created_at", desc: true, key: "time"},
+}
+
+// GET /api/saved-searches/:search_id/hits?limit=&cursor=
+// Events reported for the search, newest first, with the time they were
+// reported, as a page envelope (see pagination.go). Events the caller can no
+// longer see are left out, so a page may hold fewer than limit items.
+func GetSavedSearchHits(c *gin.Context) {
+	userID, ok := getUserIDFromContext(c)
+	if !ok {
+		jsonError(c, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	s, ok := findSavedSearch(c, userID)
+	if !ok {
+		return
+	}
+
+	page, ok := parsePageRequest(c, savedSearchHitSorts, "matched_at")
+	if !ok {
+		return
+	}
+	hits, next, total, ok := pageQuery(c, page, DB.Where("saved_search_id = ?", s.ID), "saved_search_hits.id", func(h SavedSearchHit) (interface{}, uint) {
+		return h.CreatedAt, h.ID
+	})
+	if !ok {
+		return
+	}
+	ids := make([]uint, 0, len(hits))
+	for _, h := range hits {
+		ids = append(ids, h.EventID)
+	}
+	var events []Event
+	if len(ids) > 0 {
+		if err := DB.Model(&Event{}).Scopes(visibleEvents(userID)).Where("events.id IN ?", ids).Find(&events).Error; err != nil {
+			jsonError(c, http.StatusInternalServerError, "db error: "+err.Error())
+			return
+		}
+	}
+	byID := make(map[uint]Event, len(events))
+	for _, e := range events {
+		byID[e.ID] = e
+	}
+
+	out := make([]gin.H, 0, len(hits))
+	for _, h := range hits {
+		if ev, ok := byID[h.EventID]; ok {
+			out = append(out, gin.H{"event": ev, "matched_at": h.CreatedAt})
+		}
+	}
+	c.JSON(http.StatusOK, pageEnvelope(out, next, total))
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func createTestSavedSearch(tb testing.TB, r http.Handler, userID uint, body gin.H) SavedSearch {
+	tb.Helper()
+	var s SavedSearch
+	w := apiRequest(tb, r, userID, http.MethodPost, "/api/saved-searches", body)
+	if w.Code != http.StatusCreated {
+		tb.Fatalf("create saved search: status %d: %s", w.Code, w.Body.String())
+	}
+	decodeJSON(tb, w, &s)
+	return s
+}
+
+// runTestSavedSearch reloads s and checks it like the scheduler does.
+func runTestSavedSearch(tb testing.TB, s SavedSearch) int {
+	tb.Helper()
+	if err := DB.First(&s, s.ID).Error; err != nil {
+		tb.Fatal(err)
+	}
+	n, err := checkSavedSearch(s)
+	if err != nil {
+		tb.Fatalf("check saved search: %v", err)
+	}
+	return n
+}
+
+// A row written before a run but committed after it carries a timestamp
+// older than the run; the next run must still see it.
+func TestSavedSearchSeesLateCommits(t *testing.T) {
+	useTestDB(t)
+	r := testRouter()
+	owner := createTestUser(t, "LateOwner")
+	s := createTestSavedSearch(t, r, owner.ID, gin.H{"name": "Everything"})
+
+	// Saved long ago, last checked a moment ago
+	checked := time.Now()
+	if err := DB.Model(&SavedSearch{}).Where("id = ?", s.ID).
+		UpdateColumns(map[string]interface{}{"updated_at": checked.Add(-time.Hour), "last_checked_at": checked}).Error; err != nil {
+		t.Fatal(err)
+	}
+	ev := createTestEvent(t, owner.ID, "Late commit", false)
+	if err := DB.Model(&Event{}).Where("id = ?", ev.ID).UpdateColumn("updated_at", checked.Add(-time.Minute)).Error; err != nil {
+		t.Fatal(err)
+	}
+	if n := runTestSavedSearch(t, s); n != 1 {
+		t.Fatalf("late commit: %d new hits, want 1", n)
+	}
+	// The overlap must not report it twice
+	if n := runTestSavedSearch(t, s); n != 0 {
+		t.Fatalf("second run: %d new hits, want 0", n)
+	}
+
+	// Changes from before the search was saved stay unreported
+	old := createTestEvent(t, owner.ID, "Before saving", false)
+	if err := DB.Model(&Event{}).Where("id = ?", old.ID).UpdateColumn("updated_at", checked.Add(-2*time.Hour)).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := DB.Model(&EventAttendee{}).Where("event_id = ?", old.ID).UpdateColumn("created_at", checked.Add(-2*time.Hour)).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := DB.Model(&SavedSearch{}).Where("id = ?", s.ID).UpdateColumn("last_checked_at", checked.Add(-time.Hour)).Error; err != nil {
+		t.Fatal(err)
+	}
+	if n := runTestSavedSearch(t, s); n != 0 {
+		t.Fatalf("change before saving: %d new hits, want 0", n)
+	}
+}
+
+func TestSavedSearchMatchesTagEdits(t *testing.T) {
+	useTestDB(t)
+	r := testRouter()
+	owner := createTestUser(t, "TagOwner")
+	ev := createTestEvent(t, owner.ID, "Members night", false)
+	vip := createTestSavedSearch(t, r, owner.ID, gin.H{"name": "VIP", "tag": "vip"})
+	gala := createTestSavedSearch(t, r, owner.ID, gin.H{"name": "Gala", "tag": "gala"})
+
+	if w := apiRequest(t, r, owner.ID, http.MethodPost, "/api/saved-searches", gin.H{"name": "Bad", "tag": "vip," + strings.Repeat("x", 40)}); w.Code != http.StatusBadRequest {
+		t.Errorf("invalid tag: status %d, want 400", w.Code)
+	}
+
+	// Tagging the event makes it match
+	if w := apiRequest(t, r, owner.ID, http.MethodPut, fmt.Sprintf("/api/events/%d/tags", ev.ID), gin.H{"tags": []string{"vip"}}); w.Code != http.StatusOK {
+		t.Fatalf("set tags: status %d: %s", w.Code, w.Body.String())
+	}
+	if n := runTestSavedSearch(t, vip); n != 1 {
+		t.Errorf("after tagging: %d new hits for vip, want 1", n)
+	}
+	if n := runTestSavedSearch(t, gala); n != 0 {
+		t.Errorf("after tagging: %d new hits for gala, want 0", n)
+	}
+
+	// Renaming the tag moves the event to the other search
+	var tags []Tag
+	if err := DB.Where("user_id = ? AND name = ?", owner.ID, "vip").Find(&tags).Error; err != nil || len(tags) != 1 {
+		t.Fatalf("load tag: %v (%d found)", err, len(tags))
+	}
+	if w := apiRequest(t, r, owner.ID, http.MethodPut, fmt.Sprintf("/api/tags/%d", tags[0].ID), gin.H{"name": "gala"}); w.Code != http.StatusOK {
+		t.Fatalf("rename tag: status %d: %s", w.Code, w.Body.String())
+	}
+	if n := runTestSavedSearch(t, gala); n != 1 {
+		t.Errorf("after rename: %d new hits for gala, want 1", n)
+	}
+}
@@ -0,0 +1,653 @@
+package main
+
+import (
+	"fmt"
+	"html"
+	"log"
+	"net/http"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// -----------------------------
+// Full-text search setup
+// -----------------------------
+//
+// events and tasks carry a generated, GIN-indexed "search_vector" column.
+// The text search configuration (stemming language) comes from
+// SEARCH_LANGUAGE, default "english". The configuration a column was built
+// with is stored in its comment, so changing SEARCH_LANGUAGE rebuilds the
+// column on the next start.
+
+var searchLanguage = "english"
+
+var searchLanguagePattern = regexp.MustCompile(`^[a-z_]+$`)
+
+type searchColumnSpec struct {
+	table string
+	expr  string // tsvector expression, %[1]s is the quoted configuration
+}
+
+var searchColumns = []searchColumnSpec{
+	{"events", `setweight(to_tsvector(%[1]s, coalesce(title, '')), 'A') ||
+		setweight(to_tsvector(%[1]s, coalesce(description, '')), 'B') ||
+		setweight(to_tsvector(%[1]s, coalesce(location, '')), 'C')`},
+	{"tasks", `setweight(to_tsvector(%[1]s, coalesce(title, '')), 'A') ||
+		setweight(to_tsvector(%[1]s, coalesce(description, '')), 'B')`},
+}
+
+func setupFullTextSearch(db *gorm.DB) error {
+	if v := strings.ToLower(strings.TrimSpace(os.Getenv("SEARCH_LANGUAGE"))); v != "" {
+		searchLanguage = v
+	}
+	if !searchLanguagePattern.MatchString(searchLanguage) {
+		return fmt.Errorf("invalid SEARCH_LANGUAGE %q", searchLanguage)
+	}
+	var count int64
+	if err := db.Raw("SELECT COUNT(*) FROM pg_ts_config WHERE cfgname = ?", searchLanguage).Scan(&count).Error; err != nil {
+		return err
+	}
+	if count == 0 {
+		return fmt.Errorf("SEARCH_LANGUAGE %q is not a text search configuration of this database", searchLanguage)
+	}
+
+	config := "'" + searchLanguage + "'::regconfig"
+	marker := "search_language=" + searchLanguage
+	for _, spec := range searchColumns {
+		var current []struct{ Comment *string }
+		if err := db.Raw(`SELECT col_description(a.attrelid, a.attnum) AS comment
+			FROM pg_attribute a
+			WHERE a.attrelid = ?::regclass AND a.attname = 'search_vector' AND NOT a.attisdropped`, spec.table).
+			Scan(&current).Error; err != nil {
+			return err
+		}
+		upToDate := len(current) == 1 && current[0].Comment != nil && *current[0].Comment == marker
+
+		err := db.Transaction(func(tx *gorm.DB) error {
+			if !upToDate {
+				if len(current) > 0 {
+					log.Printf("🔎 Rebuilding %s.search_vector for %s", spec.table, searchLanguage)
+					if err := tx.Exec("ALTER TABLE " + spec.table + " DROP COLUMN search_vector").Error; err != nil {
+						return err
+					}
+				}
+				if err := tx.Exec("ALTER TABLE " + spec.table + " ADD COLUMN search_vector tsvector GENERATED ALWAYS AS (" +
+					fmt.Sprintf(spec.expr, config) + ") STORED").Error; err != nil {
+					return err
+				}
+				if err := tx.Exec("COMMENT ON COLUMN " + spec.table + ".search_vector IS '" + marker + "'").Error; err != nil {
+					return err
+				}
+			}
+			return tx.Exec("CREATE INDEX IF NOT EXISTS idx_" + spec.table + "_search_vector ON " + spec.table + " USING GIN (search_vector)").Error
+		})
+		if err != nil {
+			return fmt.Errorf("%s.search_vector: %w", spec.table, err)
+		}
+	}
+	return nil
+}
+
+// buildTSQuery turns user input into a tsquery SQL expression and its args.
+//
+// Web-search syntax is supported: "quoted phrases", OR, and -excluded words.
+// Words ending in * are prefix matches (conf* finds conference).
+func buildTSQuery(input string) (string, []interface{}) {
+	config := "'" + searchLanguage + "'::regconfig"
+
+	var rest strings.Builder
+	prefixes := make([]string, 0)
+	inQuote := false
+	for _, field := range strings.Fields(input) {
+		quotes := strings.Count(field, `"`)
+		if !inQuote && quotes == 0 && strings.HasSuffix(field, "*") && !strings.HasPrefix(field, "-") {
+			term := strings.Map(func(r rune) rune {
+				if unicode.IsLetter(r) || unicode.IsDigit(r) {
+					return unicode.ToLower(r)
+				}
+				return -1
+			}, field)
+			if term != "" {
+				prefixes = append(prefixes, term+":*")
+			}
+			continue
+		}
+		if quotes%2 == 1 {
+			inQuote = !inQuote
+		}
+		rest.WriteString(strings.TrimRight(field, "*") + " ")
+	}
+
+	web := strings.TrimSpace(rest.String())
+	switch {
+	case len(prefixes) == 0:
+		return "websearch_to_tsquery(" + config + ", ?)", []interface{}{web}
+	case web == "":
+		return "to_tsquery(" + config + ", ?)", []interface{}{strings.Join(prefixes, " & ")}
+	default:
+		return "(websearch_to_tsquery(" + config + ", ?) && to_tsquery(" + config + ", ?))",
+			[]interface{}{web, strings.Join(prefixes, " & ")}
+	}
+}
+
+// ts_headline marks matches with STX/ETX control characters. They are
+// stripped from the text before highlighting, so they can't come from user
+// input; highlightHTML escapes everything else and turns them into <mark> tags.
+const (
+	highlightStart  = "\x02"
+	highlightStop   = "\x03"
+	highlightMarks  = `'StartSel="' || chr(2) || '", StopSel="' || chr(3) || '", '`
+	headlineOptions = "MaxWords=25, MinWords=8, MaxFragments=2, FragmentDelimiter=\" … \""
+)
+
+var highlightTags = strings.NewReplacer(highlightStart, "<mark>", highlightStop, "</mark>")
+
+// highlightHTML turns ts_headline output into safe HTML.
+func highlightHTML(s string) string {
+	return highlightTags.Replace(html.EscapeString(s))
+}
+
+// searchHit is one row of the merged event/task result list.
+type searchHit struct {
+	Kind          int // 0 = event, 1 = task
+	ID            uint
+	EventID       uint
+	SortDate      time.Time
+	SortCreated   time.Time
+	SortTitle     string
+	Rank          float64
+	HLTitle       string `gorm:"column:hl_title"`
+	HLDescription string `gorm:"column:hl_description"`
+}
+
+func (h searchHit) sortKey(sort string) interface{} {
+	switch sort {
+	case "created_at":
+		return h.SortCreated
+	case "title":
+		return h.SortTitle
+	case "relevance":
+		return h.Rank
+	default:
+		return h.SortDate
+	}
+}
+
+// searchSorts orders merged results; events sort before tasks on equal keys.
+var searchSorts = map[string]sortSpec{
+	"date":       {column: "sort_date", key: "time"},
+	"relevance":  {column: "rank", desc: true, key: "float"},
+	"created_at": {column: "sort_created", desc: true, key: "time"},
+	"title":      {column: "sort_title", key: "text"},
+}
+
+// visibleEvents limits a query joined on "events" to events userID may see:
+// organized by them, with an EventAttendee row for them, or marked public.
+func visibleEvents(userID uint) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Where("(events.organizer_id = ? OR events.is_public OR EXISTS ("+
+			"SELECT 1 FROM event_attendees va WHERE va.event_id = events.id AND va.user_id = ?))", userID, userID)
+	}
+}
+
+// -----------------------------
+// Search (events and tasks)
+// -----------------------------
+//
+// GET /api/events/search?keyword=&start_date=&end_date=&type=event|task|both
+// &role=&status=&month=&location=&category=&tag=&limit=&sort=relevance|date|created_at|title&cursor=
+//
+//   - keyword is a full-text query over event title/description/location and task
+//     title/description (depending on type); see buildTSQuery for the syntax.
+//     Titles and locations also match by trigram similarity of at least
+//     ?similarity= (default 0.5), and a search without hits suggests "did_you_mean"
+//   - date filters event.date
+//   - results only come from events the caller can see: ones they organize, ones they
+//     are a member of, and public ones (see visibleEvents)
+//   - role, status, month, location, category and tag narrow that set; each takes
+//     several values (repeated, or comma separated except location) and the response carries
+//     facet counts for them (see searchFacetCounts)
+//   - sort defaults to relevance with a keyword and to date without; with a keyword
+//     each item carries its rank and a highlight of the matches (HTML-escaped text
+//     with <mark> around matches)
+//   - returns a page envelope (see pagination.go) plus "facets"; items are
+//     { type: "event", event: {...} } or { type: "task", task: {...}, event: {...} }
+type SearchRequest struct {
+	Keyword   string `form:"keyword" json:"keyword"`
+	StartDate string `form:"start_date" json:"start_date"`
+	EndDate   string `form:"end_date" json:"end_date"`
+	Role      string `form:"role" json:"role"`
+	Type      string `form:"type" json:"type"`
+
+	// Similarity is the trigram word similarity (0-1) at which a misspelled
+	// keyword still matches a title or location; see fuzzy.go
+	Similarity string `form:"similarity" json:"similarity"`
+
+	Status   []string `form:"status" json:"status"`
+	Month    []string `form:"month" json:"month"`
+	Location []string `form:"location" json:"location"`
+	Category []string `form:"category" json:"category"`
+	Tag      []string `form:"tag" json:"tag"`
+}
+
+func SearchHandler(c *gin.Context) {
+	userID, ok := getUserIDFromContext(c)
+	if !ok {
+		jsonError(c, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	var req SearchRequest
+	// support query params (GET) and JSON (POST)
+	if c.Request.Method == http.MethodGet {
+		if err := c.ShouldBindQuery(&req); err != nil {
+			// ignore, we'll validate later
+			_ = err
+		}
+	} else {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			_ = err
+			_ = c.ShouldBindQuery(&req)
+		}
+	}
+
+	// default type
+	if req.Type == "" {
+		req.Type = "both"
+	}
+
+	// parse dates (accept RFC3339 or YYYY-MM-DD)
+	var start, end time.Time
+	var err error
+	if req.StartDate != "" {
+		start, err = parseDateInput(req.StartDate)
+		if err != nil {
+			jsonError(c, http.StatusBadRequest, "invalid start_date format")
+			return
+		}
+	}
+	if req.EndDate != "" {
+		end, err = parseDateInput(req.EndDate)
+		if err != nil {
+			jsonError(c, http.StatusBadRequest, "invalid end_date format")
+			return
+		}
+		// include whole day
+		end = end.Add(23*time.Hour + 59*time.Minute + 59*time.Second)
+	}
+	similarity, ok := parseSimilarity(req.Similarity)
+	if !ok {
+		jsonError(c, http.StatusBadRequest, "similarity must be a number between 0 and 1")
+		return
+	}
+	filters, err := req.facetFilters()
+	if err != nil {
+		jsonError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	keyword := strings.TrimSpace(req.Keyword)
+	defaultSort := "date"
+	if keyword != "" {
+		defaultSort = "relevance"
+	}
+	page, ok := parsePageRequest(c, searchSorts, defaultSort)
+	if !ok {
+		return
+	}
+	if page.Sort == "relevance" && keyword == "" {
+		jsonError(c, http.StatusBadRequest, "sort=relevance requires a keyword")
+		return
+	}
+
+	tsq, tsqArgs := buildTSQuery(keyword)
+	tsqArgs = append(tsqArgs, fuzzyText(keyword))
+	headline := func(col string, options string) string {
+		return "ts_headline('" + searchLanguage + "', translate(coalesce(" + col + ", ''), chr(2) || chr(3), ''), sq.q, " +
+			highlightMarks + " || '" + options + "')"
+	}
+
+	// filters shared by both branches; both join "events"
+	filter := func(q *gorm.DB) *gorm.DB {
+		if keyword != "" {
+			q = q.Joins("CROSS JOIN (SELECT "+tsq+" AS q, ?::text AS fz) AS sq", tsqArgs...)
+		}
+		if !start.IsZero() {
+			q = q.Where("events.date >= ?", start)
+		}
+		if !end.IsZero() {
+			q = q.Where("events.date <= ?", end)
+		}
+		// the caller's own attendee row feeds the role and status facets
+		q = q.Joins("LEFT JOIN event_attendees me ON me.event_id = events.id AND me.user_id = ?", userID)
+		return q.Scopes(visibleEvents(userID))
+	}
+
+	branches := make([]interface{}, 0, 2)
+
+	// Search events
+	if req.Type == "both" || req.Type == "event" {
+		cols := "0 AS kind, events.id, events.id AS event_id, events.date AS sort_date, " +
+			"events.created_at AS sort_created, events.title AS sort_title"
+		if keyword != "" {
+			cols += ", (ts_rank_cd(events.search_vector, sq.q, 32) + " +
+				"greatest(word_similarity(sq.fz, events.title), word_similarity(sq.fz, events.location)))::float8 AS rank, " +
+				headline("events.title", "HighlightAll=true") + " AS hl_title, " +
+				headline("events.description", headlineOptions) + " AS hl_description"
+		} else {
+			cols += ", 0::float8 AS rank, ''::text AS hl_title, ''::text AS hl_description"
+		}
+		query := filter(DB.Table("events").Select(cols+", "+facetColumns, userID))
+		if keyword != "" {
+			query = query.Where("events.search_vector @@ sq.q OR sq.fz <% events.title OR sq.fz <% events.location")
+		}
+		branches = append(branches, query)
+	}
+
+	// Search tasks (and attach event info)
+	if req.Type == "both" || req.Type == "task" {
+		cols := "1 AS kind, tasks.id, tasks.event_id, events.date AS sort_date, " +
+			"tasks.created_at AS sort_created, tasks.title AS sort_title"
+		if keyword != "" {
+			// A match in the task itself counts double compared to a match in its event
+			cols += ", (2 * (ts_rank_cd(tasks.search_vector, sq.q, 32) + word_similarity(sq.fz, tasks.title)) + " +
+				"ts_rank_cd(events.search_vector, sq.q, 32))::float8 AS rank, " +
+				headline("tasks.title", "HighlightAll=true") + " AS hl_title, " +
+				headline("tasks.description", headlineOptions) + " AS hl_description"
+		} else {
+			cols += ", 0::float8 AS rank, ''::text AS hl_title, ''::text AS hl_description"
+		}
+		taskQuery := filter(DB.Table("tasks").Joins("JOIN events ON events.id = tasks.event_id").Select(cols+", "+facetColumns, userID))
+		if keyword != "" {
+			taskQuery = taskQuery.Where("tasks.search_vector @@ sq.q OR events.search_vector @@ sq.q OR sq.fz <% tasks.title")
+		}
+		branches = append(branches, taskQuery)
+	}
+
+	if len(branches) == 0 {
+		jsonError(c, http.StatusBadRequest, "type must be 'event', 'task' or 'both'")
+		return
+	}
+	union := "?"
+	if len(branches) == 2 {
+		union = "? UNION ALL ?"
+	}
+
+	var kind int
+	var afterID uint
+	if page.After != nil {
+		kind, afterID = page.After.Kind, page.After.ID
+	}
+	var (
+		total  int64
+		hits   []searchHit
+		facets map[string][]facetCount
+	)
+	// the rows, total and facets share the transaction's similarity threshold
+	err = DB.Transaction(func(tx *gorm.DB) error {
+		if err := setWordSimilarity(tx, similarity); err != nil {
+			return err
+		}
+		base := tx.Table("("+union+") AS r", branches...)
+		if where, args := facetWhere(filters, ""); where != "" {
+			base = base.Where(where, args...)
+		}
+		base = base.Session(&gorm.Session{})
+
+		var err error
+		if total, err = estimateTotal(base); err != nil {
+			return err
+		}
+		if err := page.seek(base.Select("r.*"), "r."+page.spec.column, "r.kind, r.id", kind, afterID).Scan(&hits).Error; err != nil {
+			return err
+		}
+		facets, err = searchFacetCounts(tx, union, branches, filters)
+		return err
+	})
+	if err != nil {
+		jsonError(c, http.StatusInternalServerError, "db error: "+err.Error())
+		return
+	}
+
+	next := ""
+	if len(hits) > page.Limit {
+		hits = hits[:page.Limit]
+		last := hits[len(hits)-1]
+		next = page.cursorAfter(last.sortKey(page.Sort), last.Kind, last.ID)
+	}
+
+	// Load the page's events and tasks with one query each. Every hit carries
+	// its event id, so task results need no per-row lookup of their parent;
+	// events are returned without their task lists.
+	eventIDs := make([]uint, 0, len(hits))
+	taskIDs := make([]uint, 0, len(hits))
+	for _, h := range hits {
+		eventIDs = append(eventIDs, h.EventID)
+		if h.Kind == 1 {
+			taskIDs = append(taskIDs, h.ID)
+		}
+	}
+	var events []Event
+	if len(eventIDs) > 0 {
+		if err := DB.Preload("Tags").Where("id IN ?", eventIDs).Find(&events).Error; err != nil {
+			jsonError(c, http.StatusInternalServerError, "db error: "+err.Error())
+			return
+		}
+	}
+	eventsByID := make(map[uint]Event, len(events))
+	for _, e := range events {
+		eventsByID[e.ID] = e
+	}
+	var tasks []Task
+	if len(taskIDs) > 0 {
+		if err := DB.Where("id IN ?", taskIDs).Find(&tasks).Error; err != nil {
+			jsonError(c, http.StatusInternalServerError, "db error: "+err.Error())
+			return
+		}
+	}
+	tasksByID := make(map[uint]Task, len(tasks))
+	for _, t := range tasks {
+		tasksByID[t.ID] = t
+	}
+
+	results := make([]interface{}, 0, len(hits))
+	for _, h := range hits {
+		ev, ok := eventsByID[h.EventID]
+		if !ok {
+			continue
+		}
+		var item gin.H
+		if h.Kind == 0 {
+			item = gin.H{"type": "event", "event": ev}
+		} else {
+			t, ok := tasksByID[h.ID]
+			if !ok {
+				continue
+			}
+			item = gin.H{"type": "task", "task": t, "event": ev}
+		}
+		if keyword != "" {
+			item["rank"] = h.Rank
+			item["highlight"] = gin.H{"title": highlightHTML(h.HLTitle), "description": highlightHTML(h.HLDescription)}
+		}
+		results = append(results, item)
+	}
+
+	resp := pageEnvelope(results, next, total)
+	resp["facets"] = facets
+	if keyword != "" {
+		// offer alternatives when a fresh search finds nothing
+		suggestions := []string{}
+		if page.After == nil && len(results) == 0 {
+			if suggestions, err = didYouMean(userID, fuzzyText(keyword)); err != nil {
+				jsonError(c, http.StatusInternalServerError, "db error: "+err.Error())
+				return
+			}
+		}
+		resp["did_you_mean"] = suggestions
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// -----------------------------
+// Search facets
+// -----------------------------
+//
+// Every search row carries the facet values of its event, seen from the
+// caller:
+//
+//   - role: organizer, attendee, or public (visible only because it is public)
+//   - status: the caller's RSVP (Going / Maybe / Not Going), Pending when
+//     invited without an answer, None otherwise
+//   - month: YYYY-MM of the event date (UTC)
+//   - location: the event location as entered
+//   - category: the event category
+//   - tag: the names of the event's tags (an event counts once per tag)
+
+var searchFacets = []string{"role", "status", "month", "location", "category", "tag"}
+
+// facetColumns needs the caller's id as its only argument and the caller's
+// attendee row joined as "me".
+const facetColumns = `CASE WHEN events.organizer_id = ? THEN 'organizer'
+		WHEN me.role = 'attendee' THEN 'attendee' ELSE 'public' END AS facet_role,
+	CASE WHEN me.status IN ('Going', 'Maybe', 'Not Going') THEN me.status
+		WHEN me.role = 'attendee' THEN 'Pending' ELSE 'None' END AS facet_status,
+	to_char(events.date AT TIME ZONE 'UTC', 'YYYY-MM') AS facet_month,
+	trim(coalesce(events.location, '')) AS facet_location,
+	events.category AS facet_category`
+
+var facetStatuses = map[string]string{
+	"going": "Going", "maybe": "Maybe", "not going": "Not Going", "pending": "Pending", "none": "None",
+}
+
+var facetMonthPattern = regexp.MustCompile(`^\d{4}-(0[1-9]|1[0-2])$`)
+
+// facetFilters validates the facet filters of a request, keyed by facet.
+func (req SearchRequest) facetFilters() (map[string][]string, error) {
+	filters := make(map[string][]string)
+
+	for _, role := range splitList(req.Role) {
+		role = strings.ToLower(role)
+		if role != "organizer" && role != "attendee" && role != "public" {
+			return nil, fmt.Errorf("role must be 'organizer', 'attendee' or 'public'")
+		}
+		filters["role"] = append(filters["role"], role)
+	}
+	for _, value := range req.Status {
+		for _, status := range splitList(value) {
+			canonical, ok := facetStatuses[strings.ToLower(status)]
+			if !ok {
+				return nil, fmt.Errorf("status must be one of: Going, Maybe, Not Going, Pending, None")
+			}
+			filters["status"] = append(filters["status"], canonical)
+		}
+	}
+	for _, value := range req.Month {
+		for _, month := range splitList(value) {
+			if !facetMonthPattern.MatchString(month) {
+				return nil, fmt.Errorf("month must be in YYYY-MM format")
+			}
+			filters["month"] = append(filters["month"], month)
+		}
+	}
+	for _, location := range req.Location {
+		if location = strings.TrimSpace(location); location != "" {
+			filters["location"] = append(filters["location"], location)
+		}
+	}
+	for _, value := range req.Category {
+		for _, name := range splitList(value) {
+			category, valid := normalizeCategory(name)
+			if !valid {
+				return nil, fmt.Errorf("category must be one of: %s", strings.Join(eventCategories, ", "))
+			}
+			filters["category"] = append(filters["category"], category)
+		}
+	}
+	for _, value := range req.Tag {
+		for _, name := range splitList(value) {
+			tag := normalizeTagName(name)
+			if tag == "" {
+				return nil, fmt.Errorf("invalid tag: %s", name)
+			}
+			filters["tag"] = append(filters["tag"], tag)
+		}
+	}
+	return filters, nil
+}
+
+// facetWhere combines the filters on all facets but except into a condition on "r".
+func facetWhere(filters map[string][]string, except string) (string, []interface{}) {
+	conds := make([]string, 0, len(filters))
+	args := make([]interface{}, 0, len(filters))
+	for _, facet := range searchFacets {
+		values := filters[facet]
+		if facet == except || len(values) == 0 {
+			continue
+		}
+		if facet == "tag" {
+			conds = append(conds, "EXISTS (SELECT 1 FROM event_tags et JOIN tags tg ON tg.id = et.tag_id "+
+				"WHERE et.event_id = r.event_id AND tg.name IN ?)")
+		} else {
+			conds = append(conds, "r.facet_"+facet+" IN ?")
+		}
+		args = append(args, values)
+	}
+	return strings.Join(conds, " AND "), args
+}
+
+type facetCount struct {
+	Value string `json:"value"`
+	Count int64  `json:"count"`
+}
+
+// searchFacetCounts counts the search rows per facet value in one query.
+// Counts are disjunctive: a facet's own filter is ignored when counting it,
+// so every value shows how many results selecting it (too) would give.
+func searchFacetCounts(tx *gorm.DB, union string, branches []interface{}, filters map[string][]string) (map[string][]facetCount, error) {
+	args := append([]interface{}{}, branches...)
+	parts := make([]string, 0, len(searchFacets))
+	for _, facet := range searchFacets {
+		col, from := "r.facet_"+facet, "r"
+		if facet == "tag" {
+			col, from = "tg.name", "r JOIN event_tags et ON et.event_id = r.event_id JOIN tags tg ON tg.id = et.tag_id"
+		}
+		where, whereArgs := facetWhere(filters, facet)
+		if facet == "location" || facet == "category" {
+			where = strings.TrimPrefix(where+" AND "+col+" <> ''", " AND ")
+		}
+		if where != "" {
+			where = " WHERE " + where
+		}
+		order := "count(*) DESC, " + col
+		if facet == "month" {
+			order = col
+		}
+		parts = append(parts, "(SELECT '"+facet+"' AS facet, "+col+" AS value, count(*) AS count FROM "+from+where+
+			" GROUP BY "+col+" ORDER BY "+order+" LIMIT 20)")
+		args = append(args, whereArgs...)
+	}
+	var rows []struct {
+		Facet string
+		Value string
+		Count int64
+	}
+	if err := tx.Raw("WITH r AS ("+union+") "+strings.Join(parts, " UNION ALL "), args...).Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	facets := make(map[string][]facetCount, len(searchFacets))
+	for _, facet := range searchFacets {
+		facets[facet] = make([]facetCount, 0)
+	}
+	for _, row := range rows {
+		facets[row.Facet] = append(facets[row.Facet], facetCount{Value: row.Value, Count: row.Count})
+	}
+	return facets, nil
+}
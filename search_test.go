@@ -0,0 +1,314 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+func TestHighlightHTMLEscapesUserText(t *testing.T) {
+	cases := map[string]string{
+		"Summer \x02party\x03":                     "Summer <mark>party</mark>",
+		"<img src=x onerror=alert(1)> \x02bbq\x03": "&lt;img src=x onerror=alert(1)&gt; <mark>bbq</mark>",
+		"Tom & Jerry's <mark>\x02fest\x03</mark>":  "Tom &amp; Jerry&#39;s &lt;mark&gt;<mark>fest</mark>&lt;/mark&gt;",
+	}
+	for in, want := range cases {
+		if got := highlightHTML(in); got != want {
+			t.Errorf("highlightHTML(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+type searchPage struct {
+	Items []struct {
+		Type  string `json:"type"`
+		Event Event  `json:"event"`
+		Task  *Task  `json:"task"`
+	} `json:"items"`
+	NextCursor string   `json:"next_cursor"`
+	DidYouMean []string `json:"did_you_mean"`
+}
+
+// User B must never see user A's private events or their tasks, whatever
+// the filters, even after trying to RSVP to A's event.
+func TestSearchDoesNotLeakPrivateEvents(t *testing.T) {
+	useTestDB(t)
+	r := testRouter()
+	alice := createTestUser(t, "Alice")
+	bob := createTestUser(t, "Bob")
+
+	secret := createTestEvent(t, alice.ID, "Secret gala dinner", false)
+	secretTask := createTestTask(t, secret.ID, "Order gala champagne")
+	public := createTestEvent(t, alice.ID, "Open gala concert", true)
+	own := createTestEvent(t, bob.ID, "Bob gala picnic", false)
+	createTestTask(t, own.ID, "Bring gala champagne")
+
+	if w := apiRequest(t, r, bob.ID, http.MethodPost, fmt.Sprintf("/api/events/%d/respond", secret.ID),
+		gin.H{"status": "Not Going"}); w.Code != http.StatusNotFound {
+		t.Fatalf("respond to private event: got %d %s, want 404", w.Code, w.Body)
+	}
+
+	for _, keyword := range []string{"", "gala", "champagne", "secret", "secrt gala"} {
+		for _, role := range []string{"", "organizer", "attendee", "public", "organizer,attendee,public"} {
+			q := url.Values{"type": {"both"}, "limit": {"100"}}
+			if keyword != "" {
+				q.Set("keyword", keyword)
+			}
+			if role != "" {
+				q.Set("role", role)
+			}
+			w := apiRequest(t, r, bob.ID, http.MethodGet, "/api/events/search?"+q.Encode(), nil)
+			if w.Code != http.StatusOK {
+				t.Fatalf("%s: got %d %s", q.Encode(), w.Code, w.Body)
+			}
+			var page searchPage
+			decodeJSON(t, w, &page)
+			for _, item := range page.Items {
+				if item.Event.ID == secret.ID || (item.Task != nil && item.Task.ID == secretTask.ID) {
+					t.Fatalf("%s: bob sees alice's private %s: %s", q.Encode(), item.Type, w.Body)
+				}
+			}
+			for _, s := range page.DidYouMean {
+				if s == secret.Title || s == secretTask.Title {
+					t.Fatalf("%s: suggestion leaks %q", q.Encode(), s)
+				}
+			}
+		}
+	}
+
+	// sanity check: bob does find his own and the public event
+	w := apiRequest(t, r, bob.ID, http.MethodGet, "/api/events/search?type=event&keyword=gala", nil)
+	var page searchPage
+	decodeJSON(t, w, &page)
+	found := make([]uint, 0)
+	for _, item := range page.Items {
+		found = append(found, item.Event.ID)
+	}
+	if !containsID(found, public.ID) || !containsID(found, own.ID) {
+		t.Fatalf("bob's search found events %v, want %d and %d", found, public.ID, own.ID)
+	}
+
+	w = apiRequest(t, r, bob.ID, http.MethodGet, "/api/events/autocomplete?prefix=Secret", nil)
+	var completions []completion
+	decodeJSON(t, w, &completions)
+	if len(completions) != 0 {
+		t.Fatalf("autocomplete leaks %v", completions)
+	}
+}
+
+// seedSearchBenchmark creates events events of organizerID with tasksPerEvent
+// tasks each, all matching the keyword "planning".
+func seedSearchBenchmark(tb testing.TB, organizerID uint, events, tasksPerEvent int) {
+	tb.Helper()
+	date := time.Now().UTC().Add(30 * 24 * time.Hour).Truncate(time.Hour)
+	list := make([]Event, 0, events)
+	for i := 0; i < events; i++ {
+		list = append(list, Event{
+			Title:          fmt.Sprintf("Planning meeting %d", i),
+			Description:    "Quarterly planning with the whole team",
+			Location:       "Berlin",
+			Date:           date.Add(time.Duration(i) * time.Hour),
+			OrganizerID:    organizerID,
+			LateRSVPPolicy: "reject",
+		})
+	}
+	if err := DB.CreateInBatches(&list, 500).Error; err != nil {
+		tb.Fatalf("seed events: %v", err)
+	}
+	tasks := make([]Task, 0, events*tasksPerEvent)
+	for _, ev := range list {
+		for j := 0; j < tasksPerEvent; j++ {
+			tasks = append(tasks, Task{
+				EventID:     ev.ID,
+				Title:       fmt.Sprintf("Planning task %d", j),
+				Description: "Prepare the planning documents",
+				Status:      "todo",
+				Priority:    "medium",
+			})
+		}
+	}
+	if err := DB.CreateInBatches(&tasks, 1000).Error; err != nil {
+		tb.Fatalf("seed tasks: %v", err)
+	}
+}
+
+// BenchmarkSearchHandler searches 2000 events with 10000 tasks and reports
+// the queries per request. "per-row" adds what the handler used to do for
+// every task hit, loading its event with all of the event's tasks, to show
+// the difference:
+//
+//	TEST_DATABASE_DSN=... go test -run '^$' -bench SearchHandler
+func BenchmarkSearchHandler(b *testing.B) {
+	useTestDB(b)
+	organizer := createTestUser(b, "Organizer")
+	seedSearchBenchmark(b, organizer.ID, 2000, 5)
+	r := testRouter()
+	path := "/api/events/search?keyword=planning&limit=100"
+
+	var queries int64
+	count := func(*gorm.DB) { atomic.AddInt64(&queries, 1) }
+	cb := DB.Callback()
+	if err := cb.Query().Before("gorm:query").Register("bench:count", count); err != nil {
+		b.Fatal(err)
+	}
+	if err := cb.Row().Before("gorm:row").Register("bench:count", count); err != nil {
+		b.Fatal(err)
+	}
+	if err := cb.Raw().Before("gorm:raw").Register("bench:count", count); err != nil {
+		b.Fatal(err)
+	}
+	b.Cleanup(func() {
+		cb.Query().Remove("bench:count")
+		cb.Row().Remove("bench:count")
+		cb.Raw().Remove("bench:count")
+	})
+
+	for _, mode := range []string{"batched", "per-row"} {
+		b.Run(mode, func(b *testing.B) {
+			atomic.StoreInt64(&queries, 0)
+			for i := 0; i < b.N; i++ {
+				w := apiRequest(b, r, organizer.ID, http.MethodGet, path, nil)
+				if w.Code != http.StatusOK {
+					b.Fatalf("search: got %d %s", w.Code, w.Body)
+				}
+				if mode != "per-row" {
+					continue
+				}
+				var page searchPage
+				decodeJSON(b, w, &page)
+				for _, item := range page.Items {
+					if item.Task == nil {
+						continue
+					}
+					var ev Event
+					if err := DB.Preload("Tasks").First(&ev, item.Task.EventID).Error; err != nil {
+						b.Fatalf("load event: %v", err)
+					}
+				}
+			}
+			b.ReportMetric(float64(atomic.LoadInt64(&queries))/float64(b.N), "queries/op")
+		})
+	}
+}
+
+// Facet counts are disjunctive: filtering on a facet narrows every other
+// facet's counts but not its own.
+func TestSearchFacetCounts(t *testing.T) {
+	useTestDB(t)
+	r := testRouter()
+	faye := createTestUser(t, "Faye")
+	other := createTestUser(t, "Gus")
+
+	type setup struct {
+		organizer uint
+		date      string
+		location  string
+		category  string
+		public    bool
+		tags      []string
+	}
+	events := make(map[string]Event)
+	for title, s := range map[string]setup{
+		"Own":     {faye.ID, "2030-05-10", "Berlin", "party", false, []string{"vip", "summer"}},
+		"Going":   {other.ID, "2030-05-20", "Paris", "party", false, []string{"vip"}},
+		"Pending": {other.ID, "2030-06-01", " Berlin ", "concert", false, nil},
+		"Public":  {other.ID, "2030-07-01", "Rome", "", true, nil},
+	} {
+		ev := createTestEvent(t, s.organizer, title, s.public)
+		date, _ := time.Parse("2006-01-02", s.date)
+		if err := DB.Model(&ev).Updates(map[string]interface{}{"date": date.Add(18 * time.Hour), "location": s.location, "category": s.category}).Error; err != nil {
+			t.Fatal(err)
+		}
+		if len(s.tags) > 0 {
+			tags, err := ensureTags(DB, s.organizer, s.tags)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := DB.Model(&ev).Association("Tags").Replace(tags); err != nil {
+				t.Fatal(err)
+			}
+		}
+		events[title] = ev
+	}
+	inviteTestUser(t, events["Going"].ID, faye.ID, "attendee")
+	inviteTestUser(t, events["Pending"].ID, faye.ID, "attendee")
+	if err := DB.Model(&EventAttendee{}).Where("event_id = ? AND user_id = ?", events["Going"].ID, faye.ID).Update("status", "Going").Error; err != nil {
+		t.Fatal(err)
+	}
+
+	search := func(query string) (int, map[string]string) {
+		t.Helper()
+		w := apiRequest(t, r, faye.ID, http.MethodGet, "/api/events/search?type=event&"+query, nil)
+		if w.Code != http.StatusOK {
+			t.Fatalf("%s: status %d: %s", query, w.Code, w.Body.String())
+		}
+		var page struct {
+			Items  []json.RawMessage       `json:"items"`
+			Facets map[string][]facetCount `json:"facets"`
+		}
+		decodeJSON(t, w, &page)
+		facets := make(map[string]string, len(page.Facets))
+		for facet, counts := range page.Facets {
+			parts := make([]string, 0, len(counts))
+			for _, fc := range counts {
+				parts = append(parts, fmt.Sprintf("%s:%d", fc.Value, fc.Count))
+			}
+			facets[facet] = strings.Join(parts, " ")
+		}
+		return len(page.Items), facets
+	}
+	check := func(query string, items int, want map[string]string) {
+		t.Helper()
+		n, got := search(query)
+		if n != items {
+			t.Errorf("%s: %d items, want %d", query, n, items)
+		}
+		for facet, counts := range want {
+			if got[facet] != counts {
+				t.Errorf("%s: %s facet = %q, want %q", query, facet, got[facet], counts)
+			}
+		}
+	}
+
+	// Ties are broken by value; months are listed in order and empty
+	// locations and categories are left out
+	check("", 4, map[string]string{
+		"role":     "attendee:2 organizer:1 public:1",
+		"status":   "None:2 Going:1 Pending:1",
+		"month":    "2030-05:2 2030-06:1 2030-07:1",
+		"location": "Berlin:2 Paris:1 Rome:1",
+		"category": "party:2 concert:1",
+		"tag":      "vip:2 summer:1",
+	})
+	check("role=attendee", 2, map[string]string{
+		"role":     "attendee:2 organizer:1 public:1",
+		"status":   "Going:1 Pending:1",
+		"location": "Berlin:1 Paris:1",
+		"tag":      "vip:1",
+	})
+	check("role=attendee,organizer&tag=vip", 2, map[string]string{
+		"role":   "attendee:1 organizer:1",
+		"tag":    "vip:2 summer:1",
+		"month":  "2030-05:2",
+		"status": "Going:1 None:1",
+	})
+	check("status=pending&status=none&month=2030-06,2030-07", 2, map[string]string{
+		"status":   "None:1 Pending:1",
+		"month":    "2030-05:1 2030-06:1 2030-07:1",
+		"category": "concert:1",
+	})
+
+	for _, query := range []string{"month=2030-13", "status=declined", "role=guest", "category=rave"} {
+		if w := apiRequest(t, r, faye.ID, http.MethodGet, "/api/events/search?"+query, nil); w.Code != http.StatusBadRequest {
+			t.Errorf("%s: status %d, want 400", query, w.Code)
+		}
+	}
+}
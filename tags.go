@@ -0,0 +1,483 @@
+package main
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// -----------------------------
+// Categories and tags
+// -----------------------------
+//
+// Every event can have one category from a fixed list and any number of
+// tags. Tags belong to the user who created them; organizers tag their own
+// events, and everyone filters by tag name.
+
+var eventCategories = []string{
+	"conference", "meetup", "workshop", "party", "wedding",
+	"sports", "concert", "fundraiser", "travel", "other",
+}
+
+func validCategory(category string) bool {
+	for _, c := range eventCategories {
+		if c == category {
+			return true
+		}
+	}
+	return false
+}
+
+// normalizeCategory lowercases category and checks it against the list;
+// empty stays empty (no category).
+func normalizeCategory(category string) (string, bool) {
+	category = strings.ToLower(strings.TrimSpace(category))
+	return category, category == "" || validCategory(category)
+}
+
+// normalizeTagName lowercases a tag, collapses whitespace and drops a
+// leading '#'. It returns "" for names that are empty or too long.
+func normalizeTagName(name string) string {
+	name = strings.ToLower(strings.Join(strings.Fields(strings.TrimPrefix(strings.TrimSpace(name), "#")), " "))
+	if name == "" || len(name) > 32 || strings.Contains(name, ",") {
+		return ""
+	}
+	return name
+}
+
+// tagFilter reads ?tag= (repeated or comma separated) and ?category= from
+// the query string, writing a 400 and returning false when they are invalid.
+func tagFilter(c *gin.Context) (tags []string, categories []string, ok bool) {
+	tags = make([]string, 0)
+	for _, value := range c.QueryArray("tag") {
+		for _, name := range splitList(value) {
+			tag := normalizeTagName(name)
+			if tag == "" {
+				jsonError(c, http.StatusBadRequest, "invalid tag: "+name)
+				return nil, nil, false
+			}
+			tags = append(tags, tag)
+		}
+	}
+	categories = make([]string, 0)
+	for _, value := range c.QueryArray("category") {
+		for _, name := range splitList(value) {
+			category, valid := normalizeCategory(name)
+			if !valid {
+				jsonError(c, http.StatusBadRequest, "category must be one of: "+strings.Join(eventCategories, ", "))
+				return nil, nil, false
+			}
+			categories = append(categories, category)
+		}
+	}
+	return tags, categories, true
+}
+
+// eventHasTag is a condition on "events" matching events with any of the
+// tag names given as its argument.
+const eventHasTag = "EXISTS (SELECT 1 FROM event_tags et JOIN tags tg ON tg.id = et.tag_id " +
+	"WHERE et.event_id = events.id AND tg.name IN ?)"
+
+// filterByTags narrows an event query to the ?tag= and ?category= filters.
+// Several values of one filter match events with any of them.
+func filterByTags(c *gin.Context, q *gorm.DB) (*gorm.DB, bool) {
+	tags, categories, ok := tagFilter(c)
+	if !ok {
+		return q, false
+	}
+	if len(tags) > 0 {
+		q = q.Where(eventHasTag, tags)
+	}
+	if len(categories) > 0 {
+		q = q.Where("events.category IN ?", categories)
+	}
+	return q, true
+}
+
+// ensureTags returns the tags of userID with the given names, creating
+// the missing ones.
+func ensureTags(tx *gorm.DB, userID uint, names []string) ([]Tag, error) {
+	tags := make([]Tag, 0, len(names))
+	seen := make(map[string]bool, len(names))
+	for _, name := range names {
+		if seen[name] {
+			continue
+		}
+		seen[name] = true
+		tag := Tag{UserID: userID, Name: name}
+		if err := tx.Where("user_id = ? AND name = ?", userID, name).FirstOrCreate(&tag).Error; err != nil {
+			return nil, err
+		}
+		tags = append(tags, tag)
+	}
+	return tags, nil
+}
+
+// GET /api/categories
+func GetCategories(c *gin.Context) {
+	c.JSON(http.StatusOK, eventCategories)
+}
+
+type TagWithUsage struct {
+	Tag
+	EventCount int64 `json:"event_count"`
+}
+
+var tagSorts = map[string]sortSpec{
+	"name": {column: "tags.name", key: "text"},
+}
+
+// GET /api/tags?limit=&cursor=
+// The caller's tags by name with the number of events using each, as a page
+// envelope (see pagination.go).
+func GetTags(c *gin.Context) {
+	userID, ok := getUserIDFromContext(c)
+	if !ok {
+		jsonError(c, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	page, ok := parsePageRequest(c, tagSorts, "name")
+	if !ok {
+		return
+	}
+	base := DB.Table("tags").
+		Select("tags.*, count(et.event_id) AS event_count").
+		Joins("LEFT JOIN event_tags et ON et.tag_id = tags.id").
+		Where("tags.user_id = ?", userID).
+		Group("tags.id")
+	list, next, total, ok := pageQuery(c, page, base, "tags.id", func(tag TagWithUsage) (interface{}, uint) {
+		return tag.Name, tag.ID
+	})
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, pageEnvelope(list, next, total))
+}
+
+type TagRequest struct {
+	Name string `json:"name" binding:"required"`
+}
+
+func findTag(c *gin.Context, userID uint) (Tag, bool) {
+	var tag Tag
+	id, err := strconv.ParseUint(c.Param("tag_id"), 10, 64)
+	if err != nil {
+		jsonError(c, http.StatusBadRequest, "invalid tag id")
+		return tag, false
+	}
+	if err := DB.Where("id = ? AND user_id = ?", id, userID).First(&tag).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			jsonError(c, http.StatusNotFound, "tag not found")
+			return tag, false
+		}
+		jsonError(c, http.StatusInternalServerError, "db error: "+err.Error())
+		return tag, false
+	}
+	return tag, true
+}
+
+// tagNameTaken reports whether userID has another tag called name.
+func tagNameTaken(userID uint, name string, exceptID uint) (bool, error) {
+	var count int64
+	err := DB.Model(&Tag{}).Where("user_id = ? AND name = ? AND id <> ?", userID, name, exceptID).Count(&count).Error
+	return count > 0, err
+}
+
+// POST /api/tags
+func CreateTag(c *gin.Context) {
+	userID, ok := getUserIDFromContext(c)
+	if !ok {
+		jsonError(c, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	var body TagRequest
+	if err := c.ShouldBindJSON(&body); err != nil {
+		jsonError(c, http.StatusBadRequest, "invalid body: "+err.Error())
+		return
+	}
+	name := normalizeTagName(body.Name)
+	if name == "" {
+		jsonError(c, http.StatusBadRequest, "tag name must be 1-32 characters without commas")
+		return
+	}
+	taken, err := tagNameTaken(userID, name, 0)
+	if err != nil {
+		jsonError(c, http.StatusInternalServerError, "db error: "+err.Error())
+		return
+	}
+	if taken {
+		jsonError(c, http.StatusConflict, "tag already exists")
+		return
+	}
+
+	tag := Tag{UserID: userID, Name: name}
+	if err := DB.Create(&tag).Error; err != nil {
+		jsonError(c, http.StatusInternalServerError, "could not create tag: "+err.Error())
+		return
+	}
+	c.JSON(http.StatusCreated, tag)
+}
+
+// touchTaggedEvents bumps updated_at on the events carrying a tag, so saved
+// searches re-check them after the tag changes.
+func touchTaggedEvents(tx *gorm.DB, tagID uint) error {
+	return tx.Model(&Event{}).
+		Where("id IN (SELECT event_id FROM event_tags WHERE tag_id = ?)", tagID).
+		UpdateColumn("updated_at", time.Now()).Error
+}
+
+// PUT /api/tags/:tag_id
+// Renaming a tag renames it on every event using it.
+func UpdateTag(c *gin.Context) {
+	userID, ok := getUserIDFromContext(c)
+	if !ok {
+		jsonError(c, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	tag, ok := findTag(c, userID)
+	if !ok {
+		return
+	}
+	var body TagRequest
+	if err := c.ShouldBindJSON(&body); err != nil {
+		jsonError(c, http.StatusBadRequest, "invalid body: "+err.Error())
+		return
+	}
+	name := normalizeTagName(body.Name)
+	if name == "" {
+		jsonError(c, http.StatusBadRequest, "tag name must be 1-32 characters without commas")
+		return
+	}
+	taken, err := tagNameTaken(userID, name, tag.ID)
+	if err != nil {
+		jsonError(c, http.StatusInternalServerError, "db error: "+err.Error())
+		return
+	}
+	if taken {
+		jsonError(c, http.StatusConflict, "tag already exists")
+		return
+	}
+
+	tag.Name = name
+	err = DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&tag).Update("name", name).Error; err != nil {
+			return err
+		}
+		return touchTaggedEvents(tx, tag.ID)
+	})
+	if err != nil {
+		jsonError(c, http.StatusInternalServerError, "could not update tag: "+err.Error())
+		return
+	}
+	c.JSON(http.StatusOK, tag)
+}
+
+// DELETE /api/tags/:tag_id
+// Removes the tag from all events.
+func DeleteTag(c *gin.Context) {
+	userID, ok := getUserIDFromContext(c)
+	if !ok {
+		jsonError(c, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	tag, ok := findTag(c, userID)
+	if !ok {
+		return
+	}
+	err := DB.Transaction(func(tx *gorm.DB) error {
+		if err := touchTaggedEvents(tx, tag.ID); err != nil {
+			return err
+		}
+		if err := tx.Exec("DELETE FROM event_tags WHERE tag_id = ?", tag.ID).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&tag).Error
+	})
+	if err != nil {
+		jsonError(c, http.StatusInternalServerError, "delete failed: "+err.Error())
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "tag deleted"})
+}
+
+type EventTagsRequest struct {
+	Tags []string `json:"tags"`
+}
+
+// PUT /api/events/:id/tags
+// Replaces the event's tags; names the organizer has no tag for yet are created.
+func SetEventTags(c *gin.Context) {
+	userID, ok := getUserIDFromContext(c)
+	if !ok {
+		jsonError(c, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	eventID, ok := parseEventID(c)
+	if !ok {
+		return
+	}
+	ev, ok := findMemberEvent(c, eventID, userID)
+	if !ok {
+		return
+	}
+	if ev.OrganizerID != userID {
+		jsonError(c, http.StatusForbidden, "only organizer can tag the event")
+		return
+	}
+
+	var body EventTagsRequest
+	if err := c.ShouldBindJSON(&body); err != nil {
+		jsonError(c, http.StatusBadRequest, "invalid body: "+err.Error())
+		return
+	}
+	if len(body.Tags) > 20 {
+		jsonError(c, http.StatusBadRequest, "an event can have at most 20 tags")
+		return
+	}
+	names := make([]string, 0, len(body.Tags))
+	for _, raw := range body.Tags {
+		name := normalizeTagName(raw)
+		if name == "" {
+			jsonError(c, http.StatusBadRequest, "invalid tag: "+raw)
+			return
+		}
+		names = append(names, name)
+	}
+
+	err := DB.Transaction(func(tx *gorm.DB) error {
+		tags, err := ensureTags(tx, userID, names)
+		if err != nil {
+			return err
+		}
+		if err := tx.Model(&ev).Association("Tags").Replace(tags); err != nil {
+			return err
+		}
+		ev.Tags = tags
+		// bump updated_at so saved searches see the change
+		return tx.Model(&ev).Update("updated_at", time.Now()).Error
+	})
+	if err != nil {
+		jsonError(c, http.StatusInternalServerError, "could not update tags: "+err.Error())
+		return
+	}
+	c.JSON(http.StatusOK, ev)
+}
+
+type CategoryRequest struct {
+	Category string `json:"category"` // empty clears the category
+}
+
+// PUT /api/events/:id/category
+func SetEventCategory(c *gin.Context) {
+	userID, ok := getUserIDFromContext(c)
+	if !ok {
+		jsonError(c, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	eventID, ok := parseEventID(c)
+	if !ok {
+		return
+	}
+	ev, ok := findMemberEvent(c, eventID, userID)
+	if !ok {
+		return
+	}
+	if ev.OrganizerID != userID {
+		jsonError(c, http.StatusForbidden, "only organizer can change the category")
+		return
+	}
+
+	var body CategoryRequest
+	if err := c.ShouldBindJSON(&body); err != nil {
+		jsonError(c, http.StatusBadRequest, "invalid body: "+err.Error())
+		return
+	}
+	category, valid := normalizeCategory(body.Category)
+	if !valid {
+		jsonError(c, http.StatusBadRequest, "category must be one of: "+strings.Join(eventCategories, ", "))
+		return
+	}
+
+	ev.Category = category
+	if err := DB.Model(&ev).Update("category", category).Error; err != nil {
+		jsonError(c, http.StatusInternalServerError, "could not update event: "+err.Error())
+		return
+	}
+	c.JSON(http.StatusOK, ev)
+}
+
+type tagStat struct {
+	Name      string     `json:"name"`
+	Events    int64      `json:"events"`
+	Upcoming  int64      `json:"upcoming"`
+	NextEvent *time.Time `json:"next_event"`
+	LastEvent *time.Time `json:"last_event"`
+}
+
+type categoryStat struct {
+	Category string `json:"category"`
+	Events   int64  `json:"events"`
+}
+
+// GET /api/tags/stats
+// How the caller's tags and categories are used across the events they
+// organize: per tag the number of events, upcoming events and the nearest
+// upcoming / latest past event date; per category the number of events.
+func GetTagStats(c *gin.Context) {
+	userID, ok := getUserIDFromContext(c)
+	if !ok {
+		jsonError(c, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	now := time.Now()
+	tags := make([]tagStat, 0)
+	err := DB.Table("tags").
+		Select("tags.name, count(e.id) AS events, "+
+			"count(e.id) FILTER (WHERE e.date >= ?) AS upcoming, "+
+			"min(e.date) FILTER (WHERE e.date >= ?) AS next_event, "+
+			"max(e.date) FILTER (WHERE e.date < ?) AS last_event", now, now, now).
+		Joins("LEFT JOIN event_tags et ON et.tag_id = tags.id").
+		Joins("LEFT JOIN events e ON e.id = et.event_id").
+		Where("tags.user_id = ?", userID).
+		Group("tags.id, tags.name").
+		Order("events DESC, tags.name ASC").
+		Scan(&tags).Error
+	if err != nil {
+		jsonError(c, http.StatusInternalServerError, "db error: "+err.Error())
+		return
+	}
+
+	categories := make([]categoryStat, 0)
+	err = DB.Model(&Event{}).
+		Select("category, count(*) AS events").
+		Where("organizer_id = ?", userID).
+		Group("category").
+		Order("events DESC, category ASC").
+		Scan(&categories).Error
+	if err != nil {
+		jsonError(c, http.StatusInternalServerError, "db error: "+err.Error())
+		return
+	}
+
+	var untagged int64
+	err = DB.Model(&Event{}).
+		Where("organizer_id = ? AND NOT EXISTS (SELECT 1 FROM event_tags et WHERE et.event_id = events.id)", userID).
+		Count(&untagged).Error
+	if err != nil {
+		jsonError(c, http.StatusInternalServerError, "db error: "+err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"tags": tags, "categories": categories, "untagged_events": untagged})
+}
@@ -0,0 +1,175 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestNormalizeTagName(t *testing.T) {
+	cases := map[string]string{
+		"  #Summer   Party ":    "summer party",
+		"VIP":                   "vip",
+		"#":                     "",
+		"a,b":                   "",
+		"##double":              "#double",
+		strings.Repeat("x", 32): strings.Repeat("x", 32),
+		strings.Repeat("x", 33): "",
+	}
+	for in, want := range cases {
+		if got := normalizeTagName(in); got != want {
+			t.Errorf("normalizeTagName(%q) = %q, want %q", in, got, want)
+		}
+	}
+
+	for in, want := range map[string]string{" Party ": "party", "": "", "OTHER": "other"} {
+		if got, ok := normalizeCategory(in); !ok || got != want {
+			t.Errorf("normalizeCategory(%q) = %q, %v; want %q", in, got, ok, want)
+		}
+	}
+	if _, ok := normalizeCategory("rave"); ok {
+		t.Error("normalizeCategory accepted an unknown category")
+	}
+}
+
+func TestEventTagsAndCategories(t *testing.T) {
+	f := newAuthFixture(t)
+	r := testRouter()
+	org := f.Organizer.ID
+
+	date := time.Now().UTC().Add(10 * 24 * time.Hour).Truncate(time.Hour).Format(time.RFC3339)
+	for name, body := range map[string]gin.H{
+		"unknown category": {"title": "Rave", "date": date, "category": "rave"},
+		"tag with comma":   {"title": "Rave", "date": date, "tags": []string{"a,b"}},
+	} {
+		if w := apiRequest(t, r, org, http.MethodPost, "/api/events", body); w.Code != http.StatusBadRequest {
+			t.Errorf("%s: status %d, want 400", name, w.Code)
+		}
+	}
+	w := apiRequest(t, r, org, http.MethodPost, "/api/events",
+		gin.H{"title": "Garden party", "date": date, "category": "Party", "tags": []string{"#VIP", "Summer  Party", "vip"}})
+	if w.Code != http.StatusCreated {
+		t.Fatalf("create event: status %d: %s", w.Code, w.Body.String())
+	}
+	var party Event
+	decodeJSON(t, w, &party)
+	if party.Category != "party" || len(party.Tags) != 2 {
+		t.Fatalf("created event has category %q and %d tags", party.Category, len(party.Tags))
+	}
+
+	concert := f.Event
+	eventPath := fmt.Sprintf("/api/events/%d", concert.ID)
+	if w := apiRequest(t, r, f.Member.ID, http.MethodPut, eventPath+"/tags", gin.H{"tags": []string{"vip"}}); w.Code != http.StatusForbidden {
+		t.Errorf("member tags: status %d, want 403", w.Code)
+	}
+	if w := apiRequest(t, r, org, http.MethodPut, eventPath+"/tags", gin.H{"tags": []string{"vip", "outdoor"}}); w.Code != http.StatusOK {
+		t.Fatalf("set tags: status %d: %s", w.Code, w.Body.String())
+	}
+	if w := apiRequest(t, r, org, http.MethodPut, eventPath+"/category", gin.H{"category": "Concert"}); w.Code != http.StatusOK {
+		t.Fatalf("set category: status %d: %s", w.Code, w.Body.String())
+	}
+
+	organized := func(query string) []uint {
+		t.Helper()
+		w := apiRequest(t, r, org, http.MethodGet, "/api/events/organized?"+query, nil)
+		if w.Code != http.StatusOK {
+			t.Fatalf("%s: status %d: %s", query, w.Code, w.Body.String())
+		}
+		var page struct {
+			Items []Event `json:"items"`
+		}
+		decodeJSON(t, w, &page)
+		ids := make([]uint, 0, len(page.Items))
+		for _, ev := range page.Items {
+			ids = append(ids, ev.ID)
+		}
+		sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+		return ids
+	}
+	for query, want := range map[string][]uint{
+		"tag=VIP":                            {concert.ID, party.ID},
+		"tag=outdoor,summer%20party":         {concert.ID, party.ID},
+		"tag=outdoor&category=party":         {},
+		"category=concert&category=wedding":  {concert.ID},
+		"tag=outdoor&tag=nothing&sort=title": {concert.ID},
+	} {
+		if got := organized(query); !sameIDs(got, want) {
+			t.Errorf("%s: events %v, want %v", query, got, want)
+		}
+	}
+
+	// Tag names are unique per user; renames follow through to the events
+	var outdoor, vip Tag
+	DB.Where("user_id = ? AND name = ?", org, "outdoor").First(&outdoor)
+	DB.Where("user_id = ? AND name = ?", org, "vip").First(&vip)
+	if w := apiRequest(t, r, org, http.MethodPost, "/api/tags", gin.H{"name": "#Vip"}); w.Code != http.StatusConflict {
+		t.Errorf("duplicate tag: status %d, want 409", w.Code)
+	}
+	if w := apiRequest(t, r, f.Member.ID, http.MethodPost, "/api/tags", gin.H{"name": "vip"}); w.Code != http.StatusCreated {
+		t.Errorf("same name, other user: status %d: %s", w.Code, w.Body.String())
+	}
+	if w := apiRequest(t, r, org, http.MethodPost, "/api/tags", gin.H{"name": "Indoor"}); w.Code != http.StatusCreated {
+		t.Errorf("create tag: status %d: %s", w.Code, w.Body.String())
+	}
+	tagPath := fmt.Sprintf("/api/tags/%d", outdoor.ID)
+	if w := apiRequest(t, r, org, http.MethodPut, tagPath, gin.H{"name": "VIP"}); w.Code != http.StatusConflict {
+		t.Errorf("rename onto another tag: status %d, want 409", w.Code)
+	}
+	if w := apiRequest(t, r, f.Member.ID, http.MethodPut, tagPath, gin.H{"name": "mine"}); w.Code != http.StatusNotFound {
+		t.Errorf("rename someone else's tag: status %d, want 404", w.Code)
+	}
+	if w := apiRequest(t, r, org, http.MethodPut, tagPath, gin.H{"name": "Garden"}); w.Code != http.StatusOK {
+		t.Fatalf("rename: status %d: %s", w.Code, w.Body.String())
+	}
+	if got := organized("tag=garden"); !sameIDs(got, []uint{concert.ID}) {
+		t.Errorf("after rename: events %v", got)
+	}
+
+	// A past event with the tag and an untagged one feed the stats
+	past := createTestEvent(t, org, "Last year", false)
+	lastDate := time.Now().UTC().Add(-365 * 24 * time.Hour).Truncate(time.Hour)
+	if err := DB.Model(&past).Update("date", lastDate).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := DB.Model(&past).Association("Tags").Append(&vip); err != nil {
+		t.Fatal(err)
+	}
+	createTestEvent(t, org, "Untagged", false)
+
+	var stats struct {
+		Tags           []tagStat      `json:"tags"`
+		Categories     []categoryStat `json:"categories"`
+		UntaggedEvents int64          `json:"untagged_events"`
+	}
+	w = apiRequest(t, r, org, http.MethodGet, "/api/tags/stats", nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("stats: status %d: %s", w.Code, w.Body.String())
+	}
+	decodeJSON(t, w, &stats)
+	var got []string
+	for _, s := range stats.Tags {
+		got = append(got, fmt.Sprintf("%s:%d/%d", s.Name, s.Events, s.Upcoming))
+	}
+	if want := "[vip:3/2 garden:1/1 summer party:1/1 indoor:0/0]"; fmt.Sprint(got) != want {
+		t.Fatalf("tag stats = %v, want %s", got, want)
+	}
+	if v := stats.Tags[0]; v.LastEvent == nil || !v.LastEvent.Equal(lastDate) || v.NextEvent == nil || !v.NextEvent.Equal(concert.Date) {
+		t.Errorf("vip dates: next %v, last %v", v.NextEvent, v.LastEvent)
+	}
+	if fmt.Sprint(stats.Categories) != "[{ 2} {concert 1} {party 1}]" || stats.UntaggedEvents != 1 {
+		t.Errorf("categories %v, untagged %d", stats.Categories, stats.UntaggedEvents)
+	}
+
+	// Deleting a tag takes it off every event
+	if w := apiRequest(t, r, org, http.MethodDelete, fmt.Sprintf("/api/tags/%d", vip.ID), nil); w.Code != http.StatusOK {
+		t.Fatalf("delete tag: status %d: %s", w.Code, w.Body.String())
+	}
+	if got := organized("tag=vip"); len(got) != 0 {
+		t.Errorf("after delete: events %v", got)
+	}
+}
@@ -0,0 +1,188 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// -----------------------------
+// Task assignees
+// -----------------------------
+
+// checkEventMembers returns an error naming the first user that has no
+// EventAttendee row on the event. Only members can be assigned to tasks.
+func checkEventMembers(eventID uint, userIDs []uint) error {
+	if len(userIDs) == 0 {
+		return nil
+	}
+
+	var members []uint
+	if err := DB.Model(&EventAttendee{}).
+		Where("event_id = ? AND user_id IN ?", eventID, userIDs).
+		Pluck("user_id", &members).Error; err != nil {
+		return err
+	}
+	found := make(map[uint]bool, len(members))
+	for _, id := range members {
+		found[id] = true
+	}
+	for _, id := range userIDs {
+		if !found[id] {
+			return fmt.Errorf("user %d is not a member of this event", id)
+		}
+	}
+	return nil
+}
+
+// replaceAssignees sets the exact assignee list of a task.
+func replaceAssignees(tx *gorm.DB, taskID uint, userIDs []uint) error {
+	if err := tx.Where("task_id = ?", taskID).Delete(&TaskAssignee{}).Error; err != nil {
+		return err
+	}
+	seen := make(map[uint]bool, len(userIDs))
+	for _, uid := range userIDs {
+		if seen[uid] {
+			continue
+		}
+		seen[uid] = true
+		if err := tx.Create(&TaskAssignee{TaskID: taskID, UserID: uid}).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// isTaskAssignee reports whether userID is assigned to the task.
+func isTaskAssignee(taskID, userID uint) (bool, error) {
+	var count int64
+	err := DB.Model(&TaskAssignee{}).Where("task_id = ? AND user_id = ?", taskID, userID).Count(&count).Error
+	return count > 0, err
+}
+
+type AssigneesRequest struct {
+	UserIDs []uint `json:"user_ids"` // full list; empty unassigns everyone
+}
+
+// PUT /api/events/:id/tasks/:task_id/assignees
+func SetTaskAssignees(c *gin.Context) {
+	userID, ok := getUserIDFromContext(c)
+	if !ok {
+		jsonError(c, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	eventID, ok := parseEventID(c)
+	if !ok {
+		return
+	}
+	ev, ok := findMemberEvent(c, eventID, userID)
+	if !ok {
+		return
+	}
+	if ev.OrganizerID != userID {
+		jsonError(c, http.StatusForbidden, "only organizer can assign tasks")
+		return
+	}
+	task, ok := findTask(c, eventID)
+	if !ok {
+		return
+	}
+
+	var body AssigneesRequest
+	if err := c.ShouldBindJSON(&body); err != nil {
+		jsonError(c, http.StatusBadRequest, "invalid body: "+err.Error())
+		return
+	}
+	if err := checkEventMembers(eventID, body.UserIDs); err != nil {
+		jsonError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if err := DB.Transaction(func(tx *gorm.DB) error {
+		return replaceAssignees(tx, task.ID, body.UserIDs)
+	}); err != nil {
+		jsonError(c, http.StatusInternalServerError, "could not assign task: "+err.Error())
+		return
+	}
+
+	if err := DB.Preload("Assignees").First(&task, task.ID).Error; err != nil {
+		jsonError(c, http.StatusInternalServerError, "db error: "+err.Error())
+		return
+	}
+	c.JSON(http.StatusOK, task)
+}
+
+// GET /api/tasks/mine?status=&sort=&order=&limit=&cursor=
+// Tasks assigned to the authenticated user across all events, soonest due
+// first by default. sort and order work as for GET /api/events/:id/tasks,
+// except position. Returns a page envelope (see pagination.go) of
+// { task: {...}, event: {...} } items.
+func GetMyTasks(c *gin.Context) {
+	userID, ok := getUserIDFromContext(c)
+	if !ok {
+		jsonError(c, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	query := DB.Preload("Assignees").
+		Select("tasks.*").
+		Joins("JOIN task_assignees ta ON ta.task_id = tasks.id").
+		Where("ta.user_id = ?", userID)
+
+	if v := c.Query("status"); v != "" {
+		statuses := splitList(strings.ToLower(v))
+		for _, s := range statuses {
+			if !taskStatuses[s] {
+				jsonError(c, http.StatusBadRequest, "status must be one of: todo, in-progress, done, blocked")
+				return
+			}
+		}
+		query = query.Where("tasks.status IN ?", statuses)
+	}
+
+	order := strings.ToLower(c.DefaultQuery("order", "asc"))
+	if order != "asc" && order != "desc" {
+		jsonError(c, http.StatusBadRequest, "order must be 'asc' or 'desc'")
+		return
+	}
+	desc := order == "desc"
+	sorts := taskListSorts(desc)
+	delete(sorts, "position")
+	page, ok := parsePageRequest(c, sorts, "due_at")
+	if !ok {
+		return
+	}
+
+	tasks, next, total, ok := pageQuery(c, page, query, "tasks.id", func(t Task) (interface{}, uint) {
+		return taskSortKey(page.Sort, desc, t, nil), t.ID
+	})
+	if !ok {
+		return
+	}
+
+	eventIDs := make([]uint, 0, len(tasks))
+	for _, t := range tasks {
+		eventIDs = append(eventIDs, t.EventID)
+	}
+	events := make(map[uint]Event)
+	if len(eventIDs) > 0 {
+		var list []Event
+		if err := DB.Where("id IN ?", eventIDs).Find(&list).Error; err != nil {
+			jsonError(c, http.StatusInternalServerError, "db error: "+err.Error())
+			return
+		}
+		for _, e := range list {
+			events[e.ID] = e
+		}
+	}
+
+	results := make([]gin.H, 0, len(tasks))
+	for _, t := range tasks {
+		results = append(results, gin.H{"task": t, "event": events[t.EventID]})
+	}
+	c.JSON(http.StatusOK, pageEnvelope(results, next, total))
+}
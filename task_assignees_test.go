@@ -0,0 +1,128 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type myTasksPage struct {
+	Items []struct {
+		Task  Task  `json:"task"`
+		Event Event `json:"event"`
+	} `json:"items"`
+	NextCursor *string `json:"next_cursor"`
+}
+
+func TestTaskAssigneesAreMembers(t *testing.T) {
+	f := newAuthFixture(t)
+	r := testRouter()
+	assignees := fmt.Sprintf("/api/events/%d/tasks/%d/assignees", f.Event.ID, f.Task.ID)
+	taskPath := fmt.Sprintf("/api/events/%d/tasks/%d", f.Event.ID, f.Task.ID)
+
+	if w := apiRequest(t, r, f.Organizer.ID, http.MethodPut, assignees, gin.H{"user_ids": []uint{f.Member.ID, f.Outsider.ID}}); w.Code != http.StatusBadRequest {
+		t.Errorf("assign outsider: status %d, want 400", w.Code)
+	}
+	if w := apiRequest(t, r, f.Organizer.ID, http.MethodPost, fmt.Sprintf("/api/events/%d/tasks", f.Event.ID),
+		gin.H{"title": "Outsourced", "assignee_ids": []uint{f.Outsider.ID}}); w.Code != http.StatusBadRequest {
+		t.Errorf("create with outsider: status %d, want 400", w.Code)
+	}
+	if w := apiRequest(t, r, f.Member.ID, http.MethodPut, assignees, gin.H{"user_ids": []uint{f.Member.ID}}); w.Code != http.StatusForbidden {
+		t.Errorf("member assigns: status %d, want 403", w.Code)
+	}
+
+	// Duplicates collapse into one assignment
+	w := apiRequest(t, r, f.Organizer.ID, http.MethodPut, assignees, gin.H{"user_ids": []uint{f.Member.ID, f.Organizer.ID, f.Member.ID}})
+	if w.Code != http.StatusOK {
+		t.Fatalf("assign: status %d: %s", w.Code, w.Body.String())
+	}
+	var task Task
+	decodeJSON(t, w, &task)
+	if len(task.Assignees) != 2 {
+		t.Fatalf("task has %d assignees, want 2", len(task.Assignees))
+	}
+
+	// An assignee may move the task along but not edit it
+	if w := apiRequest(t, r, f.Member.ID, http.MethodPatch, taskPath, gin.H{"status": "in-progress"}); w.Code != http.StatusOK {
+		t.Errorf("assignee changes status: status %d: %s", w.Code, w.Body.String())
+	}
+	if w := apiRequest(t, r, f.Member.ID, http.MethodPatch, taskPath, gin.H{"status": "done", "title": "Renamed"}); w.Code != http.StatusForbidden {
+		t.Errorf("assignee renames: status %d, want 403", w.Code)
+	}
+
+	// Unassigning takes that right away again
+	if w := apiRequest(t, r, f.Organizer.ID, http.MethodPut, assignees, gin.H{"user_ids": []uint{}}); w.Code != http.StatusOK {
+		t.Fatalf("unassign: status %d: %s", w.Code, w.Body.String())
+	}
+	if w := apiRequest(t, r, f.Member.ID, http.MethodPatch, taskPath, gin.H{"status": "done"}); w.Code != http.StatusForbidden {
+		t.Errorf("former assignee changes status: status %d, want 403", w.Code)
+	}
+}
+
+func TestGetMyTasks(t *testing.T) {
+	f := newAuthFixture(t)
+	r := testRouter()
+	other := createTestEvent(t, f.Organizer.ID, "Other party", true)
+	inviteTestUser(t, other.ID, f.Member.ID, "attendee")
+
+	soon := time.Now().Add(24 * time.Hour).UTC().Truncate(time.Second)
+	later := soon.Add(48 * time.Hour)
+	tasks := []Task{
+		{EventID: f.Event.ID, Title: "Later", Status: "todo", Priority: "medium", DueAt: &later},
+		{EventID: other.ID, Title: "Soon", Status: "in-progress", Priority: "medium", DueAt: &soon},
+		{EventID: other.ID, Title: "Whenever", Status: "todo", Priority: "medium"},
+		{EventID: other.ID, Title: "Not mine", Status: "todo", Priority: "medium", DueAt: &soon},
+	}
+	for i := range tasks {
+		if err := DB.Create(&tasks[i]).Error; err != nil {
+			t.Fatal(err)
+		}
+		if tasks[i].Title == "Not mine" {
+			continue
+		}
+		if err := DB.Create(&TaskAssignee{TaskID: tasks[i].ID, UserID: f.Member.ID}).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	mine := func(userID uint, query string) myTasksPage {
+		t.Helper()
+		var page myTasksPage
+		w := apiRequest(t, r, userID, http.MethodGet, "/api/tasks/mine"+query, nil)
+		if w.Code != http.StatusOK {
+			t.Fatalf("my tasks%s: status %d: %s", query, w.Code, w.Body.String())
+		}
+		decodeJSON(t, w, &page)
+		return page
+	}
+	titles := func(page myTasksPage) string {
+		var out []string
+		for _, item := range page.Items {
+			out = append(out, item.Task.Title)
+		}
+		return fmt.Sprint(out)
+	}
+
+	// Soonest due first across events, undated last
+	page := mine(f.Member.ID, "")
+	if got := titles(page); got != "[Soon Later Whenever]" {
+		t.Errorf("my tasks = %s", got)
+	}
+	for _, item := range page.Items {
+		if item.Event.ID != item.Task.EventID || item.Event.Title == "" {
+			t.Errorf("%s: event = %+v", item.Task.Title, item.Event)
+		}
+	}
+	if got := titles(mine(f.Member.ID, "?status=todo&sort=title&order=desc")); got != "[Whenever Later]" {
+		t.Errorf("todo by title = %s", got)
+	}
+	if got := titles(mine(f.Outsider.ID, "")); got != "[]" {
+		t.Errorf("outsider's tasks = %s", got)
+	}
+	if w := apiRequest(t, r, f.Member.ID, http.MethodGet, "/api/tasks/mine?sort=position", nil); w.Code != http.StatusBadRequest {
+		t.Errorf("sort by position: status %d, want 400", w.Code)
+	}
+}
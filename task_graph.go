@@ -0,0 +1,540 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// -----------------------------
+// Subtasks, checklists & dependencies
+// -----------------------------
+
+// openPrerequisites returns the tasks taskID depends on that are not done yet.
+func openPrerequisites(taskID uint) ([]Task, error) {
+	var tasks []Task
+	err := DB.Where("id IN (?) AND status <> ?",
+		DB.Model(&TaskDependency{}).Select("depends_on_id").Where("task_id = ?", taskID), "done").
+		Find(&tasks).Error
+	return tasks, err
+}
+
+var errDependencyCycle = errors.New("dependency would create a cycle")
+
+// dependencyCreatesCycle reports whether making taskID depend on dependsOnID
+// would close a loop, i.e. dependsOnID already (transitively) depends on taskID.
+func dependencyCreatesCycle(tx *gorm.DB, eventID, taskID, dependsOnID uint) (bool, error) {
+	var deps []TaskDependency
+	if err := tx.Where("task_id IN (?)", tx.Model(&Task{}).Select("id").Where("event_id = ?", eventID)).
+		Find(&deps).Error; err != nil {
+		return false, err
+	}
+	edges := make(map[uint][]uint)
+	for _, d := range deps {
+		edges[d.TaskID] = append(edges[d.TaskID], d.DependsOnID)
+	}
+
+	visited := make(map[uint]bool)
+	stack := []uint{dependsOnID}
+	for len(stack) > 0 {
+		id := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if id == taskID {
+			return true, nil
+		}
+		if visited[id] {
+			continue
+		}
+		visited[id] = true
+		stack = append(stack, edges[id]...)
+	}
+	return false, nil
+}
+
+// parentCreatesCycle reports whether making parentID the parent of taskID
+// would make the task its own ancestor.
+func parentCreatesCycle(taskID, parentID uint) (bool, error) {
+	for id, hops := parentID, 0; id != 0; hops++ {
+		if id == taskID {
+			return true, nil
+		}
+		var parent Task
+		if err := DB.Select("id", "parent_id").First(&parent, id).Error; err != nil {
+			return false, err
+		}
+		if parent.ParentID == nil || hops > 1000 {
+			break
+		}
+		id = *parent.ParentID
+	}
+	return false, nil
+}
+
+// deleteTaskLinks removes rows that hang off the given tasks. Subtasks of a
+// deleted task are kept and moved up to the deleted task's parent.
+func deleteTaskLinks(tx *gorm.DB, tasks []Task) error {
+	ids := make([]uint, 0, len(tasks))
+	for _, t := range tasks {
+		ids = append(ids, t.ID)
+		if err := tx.Model(&Task{}).Where("parent_id = ?", t.ID).Update("parent_id", t.ParentID).Error; err != nil {
+			return err
+		}
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+	if err := tx.Where("task_id IN ?", ids).Delete(&TaskAssignee{}).Error; err != nil {
+		return err
+	}
+	if err := tx.Where("task_id IN ?", ids).Delete(&ChecklistItem{}).Error; err != nil {
+		return err
+	}
+	if err := deleteComments(tx, "task_id IN ?", ids); err != nil {
+		return err
+	}
+	// Files and expenses stay with the event
+	if err := tx.Model(&Attachment{}).Where("task_id IN ?", ids).Update("task_id", nil).Error; err != nil {
+		return err
+	}
+	if err := tx.Model(&Expense{}).Where("task_id IN ?", ids).Update("task_id", nil).Error; err != nil {
+		return err
+	}
+	return tx.Where("task_id IN ? OR depends_on_id IN ?", ids, ids).Delete(&TaskDependency{}).Error
+}
+
+type DependencyRequest struct {
+	DependsOnID uint `json:"depends_on_id" binding:"required"`
+}
+
+// POST /api/events/:id/tasks/:task_id/dependencies
+func AddTaskDependency(c *gin.Context) {
+	userID, ok := getUserIDFromContext(c)
+	if !ok {
+		jsonError(c, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	eventID, ok := parseEventID(c)
+	if !ok {
+		return
+	}
+	ev, ok := findMemberEvent(c, eventID, userID)
+	if !ok {
+		return
+	}
+	if ev.OrganizerID != userID {
+		jsonError(c, http.StatusForbidden, "only organizer can change task dependencies")
+		return
+	}
+	task, ok := findTask(c, eventID)
+	if !ok {
+		return
+	}
+
+	var body DependencyRequest
+	if err := c.ShouldBindJSON(&body); err != nil {
+		jsonError(c, http.StatusBadRequest, "invalid body: "+err.Error())
+		return
+	}
+	if body.DependsOnID == task.ID {
+		jsonError(c, http.StatusBadRequest, "a task cannot depend on itself")
+		return
+	}
+
+	var prerequisite Task
+	if err := DB.Where("id = ? AND event_id = ?", body.DependsOnID, eventID).First(&prerequisite).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			jsonError(c, http.StatusBadRequest, "prerequisite must be a task of the same event")
+			return
+		}
+		jsonError(c, http.StatusInternalServerError, "db error: "+err.Error())
+		return
+	}
+
+	dep := TaskDependency{TaskID: task.ID, DependsOnID: prerequisite.ID}
+	err := DB.Transaction(func(tx *gorm.DB) error {
+		// Serialize dependency changes per event, so two requests adding A->B
+		// and B->A cannot both pass the cycle check
+		var locked Event
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id").First(&locked, eventID).Error; err != nil {
+			return err
+		}
+		cycle, err := dependencyCreatesCycle(tx, eventID, task.ID, prerequisite.ID)
+		if err != nil {
+			return err
+		}
+		if cycle {
+			return errDependencyCycle
+		}
+		return tx.Where(dep).FirstOrCreate(&dep).Error
+	})
+	if err == errDependencyCycle {
+		jsonError(c, http.StatusConflict, err.Error())
+		return
+	}
+	if err != nil {
+		jsonError(c, http.StatusInternalServerError, "could not add dependency: "+err.Error())
+		return
+	}
+	c.JSON(http.StatusCreated, dep)
+}
+
+// DELETE /api/events/:id/tasks/:task_id/dependencies/:depends_on_id
+func RemoveTaskDependency(c *gin.Context) {
+	userID, ok := getUserIDFromContext(c)
+	if !ok {
+		jsonError(c, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	eventID, ok := parseEventID(c)
+	if !ok {
+		return
+	}
+	ev, ok := findMemberEvent(c, eventID, userID)
+	if !ok {
+		return
+	}
+	if ev.OrganizerID != userID {
+		jsonError(c, http.StatusForbidden, "only organizer can change task dependencies")
+		return
+	}
+	task, ok := findTask(c, eventID)
+	if !ok {
+		return
+	}
+	dependsOnID, err := strconv.ParseUint(c.Param("depends_on_id"), 10, 64)
+	if err != nil {
+		jsonError(c, http.StatusBadRequest, "invalid dependency id")
+		return
+	}
+
+	res := DB.Where("task_id = ? AND depends_on_id = ?", task.ID, dependsOnID).Delete(&TaskDependency{})
+	if res.Error != nil {
+		jsonError(c, http.StatusInternalServerError, "delete failed: "+res.Error.Error())
+		return
+	}
+	if res.RowsAffected == 0 {
+		jsonError(c, http.StatusNotFound, "dependency not found")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "dependency removed"})
+}
+
+// -----------------------------
+// Checklist items
+// -----------------------------
+
+type ChecklistItemRequest struct {
+	Title    *string `json:"title"`
+	Done     *bool   `json:"done"`
+	Position *int    `json:"position"`
+}
+
+// findChecklistItem loads the :item_id path param item of a task.
+func findChecklistItem(c *gin.Context, taskID uint) (ChecklistItem, bool) {
+	var item ChecklistItem
+	itemID, err := strconv.ParseUint(c.Param("item_id"), 10, 64)
+	if err != nil {
+		jsonError(c, http.StatusBadRequest, "invalid checklist item id")
+		return item, false
+	}
+	if err := DB.Where("id = ? AND task_id = ?", itemID, taskID).First(&item).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			jsonError(c, http.StatusNotFound, "checklist item not found")
+			return item, false
+		}
+		jsonError(c, http.StatusInternalServerError, "db error: "+err.Error())
+		return item, false
+	}
+	return item, true
+}
+
+// POST /api/events/:id/tasks/:task_id/checklist
+func AddChecklistItem(c *gin.Context) {
+	userID, ok := getUserIDFromContext(c)
+	if !ok {
+		jsonError(c, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	eventID, ok := parseEventID(c)
+	if !ok {
+		return
+	}
+	ev, ok := findMemberEvent(c, eventID, userID)
+	if !ok {
+		return
+	}
+	if ev.OrganizerID != userID {
+		jsonError(c, http.StatusForbidden, "only organizer can edit checklists")
+		return
+	}
+	task, ok := findTask(c, eventID)
+	if !ok {
+		return
+	}
+
+	var body ChecklistItemRequest
+	if err := c.ShouldBindJSON(&body); err != nil {
+		jsonError(c, http.StatusBadRequest, "invalid body: "+err.Error())
+		return
+	}
+	if body.Title == nil || strings.TrimSpace(*body.Title) == "" {
+		jsonError(c, http.StatusBadRequest, "title is required")
+		return
+	}
+
+	item := ChecklistItem{TaskID: task.ID, Title: strings.TrimSpace(*body.Title)}
+	if body.Done != nil {
+		item.Done = *body.Done
+	}
+	if body.Position != nil {
+		item.Position = *body.Position
+	} else {
+		var maxPos *int
+		if err := DB.Model(&ChecklistItem{}).Where("task_id = ?", task.ID).Select("MAX(position)").Scan(&maxPos).Error; err != nil {
+			jsonError(c, http.StatusInternalServerError, "db error: "+err.Error())
+			return
+		}
+		if maxPos != nil {
+			item.Position = *maxPos + 1
+		}
+	}
+
+	if err := DB.Create(&item).Error; err != nil {
+		jsonError(c, http.StatusInternalServerError, "could not add checklist item: "+err.Error())
+		return
+	}
+	c.JSON(http.StatusCreated, item)
+}
+
+// PATCH /api/events/:id/tasks/:task_id/checklist/:item_id
+// Assignees may tick items off; everything else is organizer only.
+func UpdateChecklistItem(c *gin.Context) {
+	userID, ok := getUserIDFromContext(c)
+	if !ok {
+		jsonError(c, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	eventID, ok := parseEventID(c)
+	if !ok {
+		return
+	}
+	ev, ok := findMemberEvent(c, eventID, userID)
+	if !ok {
+		return
+	}
+	task, ok := findTask(c, eventID)
+	if !ok {
+		return
+	}
+	item, ok := findChecklistItem(c, task.ID)
+	if !ok {
+		return
+	}
+
+	var body ChecklistItemRequest
+	if err := c.ShouldBindJSON(&body); err != nil {
+		jsonError(c, http.StatusBadRequest, "invalid body: "+err.Error())
+		return
+	}
+
+	if ev.OrganizerID != userID {
+		assigned, err := isTaskAssignee(task.ID, userID)
+		if err != nil {
+			jsonError(c, http.StatusInternalServerError, "db error: "+err.Error())
+			return
+		}
+		if !assigned {
+			jsonError(c, http.StatusForbidden, "only organizer or assignees can update checklists")
+			return
+		}
+		if body.Title != nil || body.Position != nil {
+			jsonError(c, http.StatusForbidden, "assignees can only tick checklist items")
+			return
+		}
+	}
+
+	if body.Title != nil {
+		title := strings.TrimSpace(*body.Title)
+		if title == "" {
+			jsonError(c, http.StatusBadRequest, "title cannot be empty")
+			return
+		}
+		item.Title = title
+	}
+	if body.Done != nil {
+		item.Done = *body.Done
+	}
+	if body.Position != nil {
+		item.Position = *body.Position
+	}
+
+	if err := DB.Save(&item).Error; err != nil {
+		jsonError(c, http.StatusInternalServerError, "could not update checklist item: "+err.Error())
+		return
+	}
+	c.JSON(http.StatusOK, item)
+}
+
+// DELETE /api/events/:id/tasks/:task_id/checklist/:item_id
+func DeleteChecklistItem(c *gin.Context) {
+	userID, ok := getUserIDFromContext(c)
+	if !ok {
+		jsonError(c, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	eventID, ok := parseEventID(c)
+	if !ok {
+		return
+	}
+	ev, ok := findMemberEvent(c, eventID, userID)
+	if !ok {
+		return
+	}
+	if ev.OrganizerID != userID {
+		jsonError(c, http.StatusForbidden, "only organizer can edit checklists")
+		return
+	}
+	task, ok := findTask(c, eventID)
+	if !ok {
+		return
+	}
+	item, ok := findChecklistItem(c, task.ID)
+	if !ok {
+		return
+	}
+
+	if err := DB.Delete(&item).Error; err != nil {
+		jsonError(c, http.StatusInternalServerError, "delete failed: "+err.Error())
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "checklist item deleted"})
+}
+
+// -----------------------------
+// Dependency graph
+// -----------------------------
+
+type TaskGraphNode struct {
+	ID            uint       `json:"id"`
+	Title         string     `json:"title"`
+	Status        string     `json:"status"`
+	Priority      string     `json:"priority"`
+	ParentID      *uint      `json:"parent_id"`
+	Start         time.Time  `json:"start"` // created_at; Gantt bars run from start to due_at
+	DueAt         *time.Time `json:"due_at"`
+	CompletedAt   *time.Time `json:"completed_at"`
+	ChecklistDone int        `json:"checklist_done"`
+	ChecklistSize int        `json:"checklist_total"`
+	Blocked       bool       `json:"blocked"` // has prerequisites that are not done
+}
+
+type TaskGraphEdge struct {
+	From uint `json:"from"` // prerequisite
+	To   uint `json:"to"`   // dependent task
+}
+
+// GET /api/events/:id/tasks/graph
+// Returns all tasks of an event as nodes, dependencies as edges and a
+// topological order (prerequisites first) for laying out a Gantt chart.
+func GetTaskGraph(c *gin.Context) {
+	userID, ok := getUserIDFromContext(c)
+	if !ok {
+		jsonError(c, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	eventID, ok := parseEventID(c)
+	if !ok {
+		return
+	}
+	if _, ok := findMemberEvent(c, eventID, userID); !ok {
+		return
+	}
+
+	var tasks []Task
+	if err := DB.Preload("Checklist").Where("event_id = ?", eventID).Order("id asc").Find(&tasks).Error; err != nil {
+		jsonError(c, http.StatusInternalServerError, "db error: "+err.Error())
+		return
+	}
+	var deps []TaskDependency
+	if err := DB.Where("task_id IN (?)", DB.Model(&Task{}).Select("id").Where("event_id = ?", eventID)).
+		Order("id asc").Find(&deps).Error; err != nil {
+		jsonError(c, http.StatusInternalServerError, "db error: "+err.Error())
+		return
+	}
+
+	status := make(map[uint]string, len(tasks))
+	for _, t := range tasks {
+		status[t.ID] = t.Status
+	}
+
+	edges := make([]TaskGraphEdge, 0, len(deps))
+	blocked := make(map[uint]bool)
+	indegree := make(map[uint]int, len(tasks))
+	next := make(map[uint][]uint)
+	for _, d := range deps {
+		edges = append(edges, TaskGraphEdge{From: d.DependsOnID, To: d.TaskID})
+		if status[d.DependsOnID] != "done" {
+			blocked[d.TaskID] = true
+		}
+		indegree[d.TaskID]++
+		next[d.DependsOnID] = append(next[d.DependsOnID], d.TaskID)
+	}
+
+	nodes := make([]TaskGraphNode, 0, len(tasks))
+	for _, t := range tasks {
+		n := TaskGraphNode{
+			ID:            t.ID,
+			Title:         t.Title,
+			Status:        t.Status,
+			Priority:      t.Priority,
+			ParentID:      t.ParentID,
+			Start:         t.CreatedAt,
+			DueAt:         t.DueAt,
+			CompletedAt:   t.CompletedAt,
+			ChecklistSize: len(t.Checklist),
+			Blocked:       blocked[t.ID],
+		}
+		for _, item := range t.Checklist {
+			if item.Done {
+				n.ChecklistDone++
+			}
+		}
+		nodes = append(nodes, n)
+	}
+
+	// Kahn's algorithm; ties broken by ID so the order is stable
+	order := make([]uint, 0, len(tasks))
+	ready := make([]uint, 0)
+	for _, t := range tasks {
+		if indegree[t.ID] == 0 {
+			ready = append(ready, t.ID)
+		}
+	}
+	for len(ready) > 0 {
+		sort.Slice(ready, func(i, j int) bool { return ready[i] < ready[j] })
+		id := ready[0]
+		ready = ready[1:]
+		order = append(order, id)
+		for _, n := range next[id] {
+			indegree[n]--
+			if indegree[n] == 0 {
+				ready = append(ready, n)
+			}
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"nodes": nodes, "edges": edges, "order": order})
+}
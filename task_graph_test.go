@@ -0,0 +1,73 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestAddTaskDependencyRejectsCycles(t *testing.T) {
+	f := newAuthFixture(t)
+	r := testRouter()
+	a := f.Task
+	b := createTestTask(t, f.Event.ID, "Book venue")
+	c := createTestTask(t, f.Event.ID, "Send invitations")
+
+	depend := func(task, on Task) int {
+		t.Helper()
+		path := fmt.Sprintf("/api/events/%d/tasks/%d/dependencies", f.Event.ID, task.ID)
+		return apiRequest(t, r, f.Organizer.ID, http.MethodPost, path, gin.H{"depends_on_id": on.ID}).Code
+	}
+
+	steps := []struct {
+		name     string
+		task, on Task
+		want     int
+	}{
+		{"self", a, a, http.StatusBadRequest},
+		{"a -> b", a, b, http.StatusCreated},
+		{"direct cycle b -> a", b, a, http.StatusConflict},
+		{"b -> c", b, c, http.StatusCreated},
+		{"transitive cycle c -> a", c, a, http.StatusConflict},
+		{"a -> c again", a, c, http.StatusCreated},
+	}
+	for _, s := range steps {
+		if got := depend(s.task, s.on); got != s.want {
+			t.Fatalf("%s: got %d, want %d", s.name, got, s.want)
+		}
+	}
+}
+
+// Two requests closing a loop at the same time must not both succeed.
+func TestAddTaskDependencyConcurrentCycle(t *testing.T) {
+	f := newAuthFixture(t)
+	r := testRouter()
+	a := f.Task
+	b := createTestTask(t, f.Event.ID, "Book venue")
+
+	for round := 0; round < 10; round++ {
+		if err := DB.Where("1 = 1").Delete(&TaskDependency{}).Error; err != nil {
+			t.Fatal(err)
+		}
+		codes := make([]int, 2)
+		var wg sync.WaitGroup
+		for i, pair := range [][2]Task{{a, b}, {b, a}} {
+			wg.Add(1)
+			go func(i int, task, on Task) {
+				defer wg.Done()
+				path := fmt.Sprintf("/api/events/%d/tasks/%d/dependencies", f.Event.ID, task.ID)
+				codes[i] = apiRequest(t, r, f.Organizer.ID, http.MethodPost, path, gin.H{"depends_on_id": on.ID}).Code
+			}(i, pair[0], pair[1])
+		}
+		wg.Wait()
+
+		var count int64
+		DB.Model(&TaskDependency{}).Count(&count)
+		if count != 1 {
+			t.Fatalf("round %d: %d dependencies stored (responses %v), want 1", round, count, codes)
+		}
+	}
+}
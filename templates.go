@@ -0,0 +1,518 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5/pgconn"
+	"gorm.io/gorm"
+)
+
+// -----------------------------
+// Event templates & cloning
+// -----------------------------
+
+var errTemplateNotFound = errors.New("template not found")
+
+// maxDueOffsetMinutes keeps task due dates within ten years of the event, so
+// the offset always fits a time.Duration.
+const maxDueOffsetMinutes = 10 * 366 * 24 * 60
+
+// isUniqueViolation reports whether err is a Postgres unique constraint error.
+func isUniqueViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == "23505"
+}
+
+// validateTemplateTasks normalizes priorities and checks that Parent and
+// DependsOn indexes are in range and free of cycles.
+func validateTemplateTasks(tasks []TemplateTask) error {
+	for i := range tasks {
+		t := &tasks[i]
+		t.Title = strings.TrimSpace(t.Title)
+		if t.Title == "" {
+			return fmt.Errorf("task %d: title is required", i)
+		}
+		t.Priority = strings.ToLower(strings.TrimSpace(t.Priority))
+		if t.Priority == "" {
+			t.Priority = "medium"
+		}
+		if !taskPriorities[t.Priority] {
+			return fmt.Errorf("task %d: priority must be one of: low, medium, high, urgent", i)
+		}
+		if t.DueOffsetMinutes != nil && (*t.DueOffsetMinutes > maxDueOffsetMinutes || *t.DueOffsetMinutes < -maxDueOffsetMinutes) {
+			return fmt.Errorf("task %d: due_offset_minutes must be within ten years of the event", i)
+		}
+		if t.Parent != nil && (*t.Parent < 0 || *t.Parent >= len(tasks) || *t.Parent == i) {
+			return fmt.Errorf("task %d: parent must be the index of another task", i)
+		}
+		for _, d := range t.DependsOn {
+			if d < 0 || d >= len(tasks) || d == i {
+				return fmt.Errorf("task %d: depends_on must list indexes of other tasks", i)
+			}
+		}
+	}
+
+	// Parent chains must end at a top-level task
+	for i := range tasks {
+		seen := map[int]bool{i: true}
+		for p := tasks[i].Parent; p != nil; p = tasks[*p].Parent {
+			if seen[*p] {
+				return fmt.Errorf("task %d: parent chain contains a cycle", i)
+			}
+			seen[*p] = true
+		}
+	}
+
+	// Dependencies must form a DAG (Kahn's algorithm)
+	indegree := make([]int, len(tasks))
+	dependents := make([][]int, len(tasks))
+	for i, t := range tasks {
+		for _, d := range t.DependsOn {
+			indegree[i]++
+			dependents[d] = append(dependents[d], i)
+		}
+	}
+	queue := make([]int, 0, len(tasks))
+	for i, n := range indegree {
+		if n == 0 {
+			queue = append(queue, i)
+		}
+	}
+	visited := 0
+	for len(queue) > 0 {
+		i := queue[0]
+		queue = queue[1:]
+		visited++
+		for _, j := range dependents[i] {
+			indegree[j]--
+			if indegree[j] == 0 {
+				queue = append(queue, j)
+			}
+		}
+	}
+	if visited != len(tasks) {
+		return fmt.Errorf("task dependencies contain a cycle")
+	}
+	return nil
+}
+
+// snapshotEventTasks turns an event's tasks into template tasks, with due
+// dates relative to the event date. Status, assignees and board placement are
+// not part of a template.
+func snapshotEventTasks(tx *gorm.DB, ev Event) ([]TemplateTask, error) {
+	var tasks []Task
+	if err := tx.Preload("Checklist", func(db *gorm.DB) *gorm.DB {
+		return db.Order("position asc, id asc")
+	}).Where("event_id = ?", ev.ID).Order("id asc").Find(&tasks).Error; err != nil {
+		return nil, err
+	}
+	if len(tasks) == 0 {
+		return []TemplateTask{}, nil
+	}
+
+	index := make(map[uint]int, len(tasks))
+	ids := make([]uint, 0, len(tasks))
+	for i, t := range tasks {
+		index[t.ID] = i
+		ids = append(ids, t.ID)
+	}
+	var deps []TaskDependency
+	if err := tx.Where("task_id IN ?", ids).Order("id asc").Find(&deps).Error; err != nil {
+		return nil, err
+	}
+
+	result := make([]TemplateTask, len(tasks))
+	for i, t := range tasks {
+		tt := TemplateTask{Title: t.Title, Description: t.Description, Priority: t.Priority}
+		if t.DueAt != nil {
+			offset := int64(t.DueAt.Sub(ev.Date) / time.Minute)
+			tt.DueOffsetMinutes = &offset
+		}
+		if t.ParentID != nil {
+			if p, ok := index[*t.ParentID]; ok {
+				tt.Parent = &p
+			}
+		}
+		for _, item := range t.Checklist {
+			tt.Checklist = append(tt.Checklist, item.Title)
+		}
+		result[i] = tt
+	}
+	for _, d := range deps {
+		if j, ok := index[d.DependsOnID]; ok {
+			i := index[d.TaskID]
+			result[i].DependsOn = append(result[i].DependsOn, j)
+		}
+	}
+	return result, nil
+}
+
+// instantiateTasks creates fresh todo tasks for eventID from template tasks,
+// placing due dates relative to date. Tasks must be validated already.
+func instantiateTasks(tx *gorm.DB, eventID uint, date time.Time, blueprint []TemplateTask) ([]Task, error) {
+	tasks := make([]Task, len(blueprint))
+	for i, tt := range blueprint {
+		task := Task{
+			EventID:     eventID,
+			Title:       tt.Title,
+			Description: tt.Description,
+			Status:      "todo",
+			Priority:    tt.Priority,
+		}
+		if task.Priority == "" {
+			task.Priority = "medium"
+		}
+		if tt.DueOffsetMinutes != nil {
+			due := date.Add(time.Duration(*tt.DueOffsetMinutes) * time.Minute)
+			task.DueAt = &due
+		}
+		if err := tx.Create(&task).Error; err != nil {
+			return nil, err
+		}
+		tasks[i] = task
+	}
+
+	for i, tt := range blueprint {
+		if tt.Parent != nil {
+			parentID := tasks[*tt.Parent].ID
+			if err := tx.Model(&Task{}).Where("id = ?", tasks[i].ID).Update("parent_id", parentID).Error; err != nil {
+				return nil, err
+			}
+			tasks[i].ParentID = &parentID
+		}
+		for _, d := range tt.DependsOn {
+			dep := TaskDependency{TaskID: tasks[i].ID, DependsOnID: tasks[d].ID}
+			if err := tx.Where(dep).FirstOrCreate(&dep).Error; err != nil {
+				return nil, err
+			}
+		}
+		for pos, title := range tt.Checklist {
+			item := ChecklistItem{TaskID: tasks[i].ID, Title: title, Position: pos}
+			if err := tx.Create(&item).Error; err != nil {
+				return nil, err
+			}
+			tasks[i].Checklist = append(tasks[i].Checklist, item)
+		}
+	}
+	return tasks, nil
+}
+
+// loadOwnTemplate returns a template owned by userID.
+func loadOwnTemplate(tx *gorm.DB, templateID, userID uint) (EventTemplate, error) {
+	var tpl EventTemplate
+	err := tx.Where("id = ? AND owner_id = ?", templateID, userID).First(&tpl).Error
+	if err == gorm.ErrRecordNotFound {
+		return tpl, errTemplateNotFound
+	}
+	return tpl, err
+}
+
+type TemplateRequest struct {
+	Name        string         `json:"name" binding:"required"`
+	Description string         `json:"description"`
+	Location    string         `json:"location"`
+	Tasks       []TemplateTask `json:"tasks"`
+	// FromEventID snapshots an event you organize instead of using the fields above
+	FromEventID uint `json:"from_event_id"`
+}
+
+// POST /api/templates
+func CreateTemplate(c *gin.Context) {
+	userID, ok := getUserIDFromContext(c)
+	if !ok {
+		jsonError(c, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	var body TemplateRequest
+	if err := c.ShouldBindJSON(&body); err != nil {
+		jsonError(c, http.StatusBadRequest, "invalid body: "+err.Error())
+		return
+	}
+
+	tpl := EventTemplate{
+		OwnerID:     userID,
+		Name:        strings.TrimSpace(body.Name),
+		Description: body.Description,
+		Location:    body.Location,
+		Tasks:       body.Tasks,
+	}
+	if tpl.Name == "" {
+		jsonError(c, http.StatusBadRequest, "name is required")
+		return
+	}
+
+	if body.FromEventID != 0 {
+		ev, ok := findMemberEvent(c, body.FromEventID, userID)
+		if !ok {
+			return
+		}
+		if ev.OrganizerID != userID {
+			jsonError(c, http.StatusForbidden, "only organizer can make a template from an event")
+			return
+		}
+		tasks, err := snapshotEventTasks(DB, ev)
+		if err != nil {
+			jsonError(c, http.StatusInternalServerError, "db error: "+err.Error())
+			return
+		}
+		tpl.Tasks = tasks
+		if tpl.Description == "" {
+			tpl.Description = ev.Description
+		}
+		if tpl.Location == "" {
+			tpl.Location = ev.Location
+		}
+	}
+	if tpl.Tasks == nil {
+		tpl.Tasks = []TemplateTask{}
+	}
+	if err := validateTemplateTasks(tpl.Tasks); err != nil {
+		jsonError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	var count int64
+	if err := DB.Model(&EventTemplate{}).Where("owner_id = ? AND name = ?", userID, tpl.Name).Count(&count).Error; err != nil {
+		jsonError(c, http.StatusInternalServerError, "db error: "+err.Error())
+		return
+	}
+	if count > 0 {
+		jsonError(c, http.StatusConflict, "you already have a template with this name")
+		return
+	}
+
+	if err := DB.Create(&tpl).Error; err != nil {
+		// A concurrent request can still win the race past the count above
+		if isUniqueViolation(err) {
+			jsonError(c, http.StatusConflict, "you already have a template with this name")
+			return
+		}
+		jsonError(c, http.StatusInternalServerError, "could not create template: "+err.Error())
+		return
+	}
+	c.JSON(http.StatusCreated, tpl)
+}
+
+var templateSorts = map[string]sortSpec{
+	"name": {column: "event_templates.name", key: "text"},
+}
+
+// GET /api/templates?limit=&cursor=
+// The caller's templates by name, as a page envelope (see pagination.go).
+func GetTemplates(c *gin.Context) {
+	userID, ok := getUserIDFromContext(c)
+	if !ok {
+		jsonError(c, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	page, ok := parsePageRequest(c, templateSorts, "name")
+	if !ok {
+		return
+	}
+	templates, next, total, ok := pageQuery(c, page, DB.Where("owner_id = ?", userID), "event_templates.id", func(tpl EventTemplate) (interface{}, uint) {
+		return tpl.Name, tpl.ID
+	})
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, pageEnvelope(templates, next, total))
+}
+
+// findTemplate loads the caller's :template_id template. Writes a 400/404/500 response on failure.
+func findTemplate(c *gin.Context, userID uint) (EventTemplate, bool) {
+	templateID, err := strconv.ParseUint(c.Param("template_id"), 10, 64)
+	if err != nil {
+		jsonError(c, http.StatusBadRequest, "invalid template id")
+		return EventTemplate{}, false
+	}
+	tpl, err := loadOwnTemplate(DB, uint(templateID), userID)
+	if err == errTemplateNotFound {
+		jsonError(c, http.StatusNotFound, "template not found")
+		return tpl, false
+	}
+	if err != nil {
+		jsonError(c, http.StatusInternalServerError, "db error: "+err.Error())
+		return tpl, false
+	}
+	return tpl, true
+}
+
+// GET /api/templates/:template_id
+func GetTemplate(c *gin.Context) {
+	userID, ok := getUserIDFromContext(c)
+	if !ok {
+		jsonError(c, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	tpl, ok := findTemplate(c, userID)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, tpl)
+}
+
+// DELETE /api/templates/:template_id
+// Events created from the template are not affected.
+func DeleteTemplate(c *gin.Context) {
+	userID, ok := getUserIDFromContext(c)
+	if !ok {
+		jsonError(c, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	tpl, ok := findTemplate(c, userID)
+	if !ok {
+		return
+	}
+	if err := DB.Delete(&tpl).Error; err != nil {
+		jsonError(c, http.StatusInternalServerError, "delete failed: "+err.Error())
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "template deleted"})
+}
+
+type CloneEventRequest struct {
+	Title            string `json:"title"`                   // defaults to the source title
+	Date             string `json:"date" binding:"required"` // RFC3339 or YYYY-MM-DD
+	RSVPDeadline     string `json:"rsvp_deadline"`           // defaults to the source deadline, shifted
+	IncludeAttendees bool   `json:"include_attendees"`       // re-invite the source attendees
+}
+
+// POST /api/events/:id/clone
+// Copies the event and its tasks to a new date. Task due dates keep their
+// distance to the event date; tasks start over as todo and unassigned.
+// Attendees, when included, get fresh invitations without an RSVP.
+func CloneEvent(c *gin.Context) {
+	userID, ok := getUserIDFromContext(c)
+	if !ok {
+		jsonError(c, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	eventID, ok := parseEventID(c)
+	if !ok {
+		return
+	}
+	src, ok := findMemberEvent(c, eventID, userID)
+	if !ok {
+		return
+	}
+	if src.OrganizerID != userID {
+		jsonError(c, http.StatusForbidden, "only organizer can clone an event")
+		return
+	}
+
+	var body CloneEventRequest
+	if err := c.ShouldBindJSON(&body); err != nil {
+		jsonError(c, http.StatusBadRequest, "invalid body: "+err.Error())
+		return
+	}
+	date, err := parseDateInput(body.Date)
+	if err != nil {
+		jsonError(c, http.StatusBadRequest, "invalid date format (use RFC3339 or YYYY-MM-DD)")
+		return
+	}
+
+	deadlineInput := body.RSVPDeadline
+	if deadlineInput == "" && src.RSVPDeadline != nil {
+		deadlineInput = date.Add(src.RSVPDeadline.Sub(src.Date)).Format(time.RFC3339)
+	}
+	deadline, policy, err := parseRSVPRules(deadlineInput, src.LateRSVPPolicy, date)
+	if err != nil {
+		jsonError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	ev := Event{
+		Title:          strings.TrimSpace(body.Title),
+		Description:    src.Description,
+		Location:       src.Location,
+		Date:           date,
+		OrganizerID:    userID,
+		RSVPDeadline:   deadline,
+		LateRSVPPolicy: policy,
+		BudgetCurrency: src.BudgetCurrency,
+		IsPublic:       src.IsPublic,
+		Category:       src.Category,
+		VenueID:        src.VenueID,
+	}
+	if ev.Title == "" {
+		ev.Title = src.Title
+	}
+	if src.EndsAt != nil {
+		endsAt := src.EndsAt.Add(date.Sub(src.Date))
+		ev.EndsAt = &endsAt
+	}
+
+	// The organizer is busy for the whole event; check their other commitments
+	conflicts, err := scheduleConflicts(DB, userID, ev)
+	if err != nil {
+		jsonError(c, http.StatusInternalServerError, "db error: "+err.Error())
+		return
+	}
+	if len(conflicts) > 0 && blockConflicts() {
+		conflictError(c, conflicts)
+		return
+	}
+	ev.Conflicts = conflicts
+
+	var tasks []Task
+	invited := 0
+	err = DB.Transaction(func(tx *gorm.DB) error {
+		blueprint, err := snapshotEventTasks(tx, src)
+		if err != nil {
+			return err
+		}
+		if err := tx.Model(&src).Association("Tags").Find(&ev.Tags); err != nil {
+			return err
+		}
+		if err := bookVenue(tx, &ev); err != nil {
+			return err
+		}
+		if err := tx.Create(&ev).Error; err != nil {
+			return err
+		}
+		org := EventAttendee{EventID: ev.ID, UserID: userID, Role: "organizer"}
+		if err := tx.Create(&org).Error; err != nil {
+			return err
+		}
+		if tasks, err = instantiateTasks(tx, ev.ID, date, blueprint); err != nil {
+			return err
+		}
+
+		if !body.IncludeAttendees {
+			return nil
+		}
+		var attendees []EventAttendee
+		if err := tx.Where("event_id = ? AND user_id <> ?", src.ID, userID).Order("id asc").Find(&attendees).Error; err != nil {
+			return err
+		}
+		for _, a := range attendees {
+			att := EventAttendee{EventID: ev.ID, UserID: a.UserID, Role: "attendee", Status: ""}
+			if err := tx.Create(&att).Error; err != nil {
+				return err
+			}
+			invited++
+		}
+		return nil
+	})
+	if venueError(c, err) {
+		return
+	}
+	if err != nil {
+		jsonError(c, http.StatusInternalServerError, "could not clone event: "+err.Error())
+		return
+	}
+
+	ev.Tasks = tasks
+	c.JSON(http.StatusCreated, gin.H{"event": ev, "invited": invited})
+}
@@ -0,0 +1,217 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type cloneResult struct {
+	Event   Event `json:"event"`
+	Invited int   `json:"invited"`
+}
+
+func TestCloneEvent(t *testing.T) {
+	useTestDB(t)
+	r := testRouter()
+	org := createTestUser(t, "CloneOrg")
+	guest := createTestUser(t, "CloneGuest")
+	src := createTestEvent(t, org.ID, "Spring fair", true)
+	inviteTestUser(t, src.ID, guest.ID, "attendee")
+
+	venue := Venue{OwnerID: org.ID, Name: "Town hall"}
+	if err := DB.Create(&venue).Error; err != nil {
+		t.Fatal(err)
+	}
+	endsAt := src.Date.Add(3 * time.Hour)
+	deadline := src.Date.Add(-24 * time.Hour)
+	if err := DB.Model(&src).Updates(map[string]interface{}{
+		"venue_id": venue.ID, "ends_at": endsAt, "rsvp_deadline": deadline, "budget_currency": "EUR",
+	}).Error; err != nil {
+		t.Fatal(err)
+	}
+	due := src.Date.Add(-48 * time.Hour)
+	setup := Task{EventID: src.ID, Title: "Set up stalls", Status: "done", Priority: "high", DueAt: &due}
+	if err := DB.Create(&setup).Error; err != nil {
+		t.Fatal(err)
+	}
+	signs := Task{EventID: src.ID, Title: "Paint signs", Status: "todo", Priority: "low", ParentID: &setup.ID}
+	if err := DB.Create(&signs).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := DB.Create(&TaskDependency{TaskID: setup.ID, DependsOnID: signs.ID}).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	clonePath := fmt.Sprintf("/api/events/%d/clone", src.ID)
+	shift := 14 * 24 * time.Hour
+	date := src.Date.Add(shift)
+
+	if w := apiRequest(t, r, guest.ID, http.MethodPost, clonePath, gin.H{"date": date.Format(time.RFC3339)}); w.Code != http.StatusForbidden {
+		t.Errorf("member clone: status %d, want 403", w.Code)
+	}
+
+	w := apiRequest(t, r, org.ID, http.MethodPost, clonePath, gin.H{"date": date.Format(time.RFC3339), "include_attendees": true})
+	if w.Code != http.StatusCreated {
+		t.Fatalf("clone: status %d: %s", w.Code, w.Body.String())
+	}
+	var res cloneResult
+	decodeJSON(t, w, &res)
+	ev := res.Event
+	if ev.Title != src.Title || !ev.IsPublic || ev.BudgetCurrency != "EUR" || ev.VenueID == nil || *ev.VenueID != venue.ID {
+		t.Errorf("clone = %+v", ev)
+	}
+	if ev.EndsAt == nil || !ev.EndsAt.Equal(endsAt.Add(shift)) {
+		t.Errorf("ends_at = %v, want %v", ev.EndsAt, endsAt.Add(shift))
+	}
+	if ev.RSVPDeadline == nil || !ev.RSVPDeadline.Equal(deadline.Add(shift)) {
+		t.Errorf("rsvp_deadline = %v, want %v", ev.RSVPDeadline, deadline.Add(shift))
+	}
+	if res.Invited != 1 {
+		t.Errorf("invited %d, want 1", res.Invited)
+	}
+
+	// Tasks start over, keeping their distance to the event date
+	var tasks []Task
+	if err := DB.Where("event_id = ?", ev.ID).Order("id asc").Find(&tasks).Error; err != nil {
+		t.Fatal(err)
+	}
+	if len(tasks) != 2 {
+		t.Fatalf("clone has %d tasks, want 2", len(tasks))
+	}
+	if tasks[0].Status != "todo" || tasks[0].CompletedAt != nil || tasks[0].DueAt == nil || !tasks[0].DueAt.Equal(due.Add(shift)) {
+		t.Errorf("cloned %s = %+v", tasks[0].Title, tasks[0])
+	}
+	if tasks[1].ParentID == nil || *tasks[1].ParentID != tasks[0].ID {
+		t.Errorf("cloned %s has parent %v, want %d", tasks[1].Title, tasks[1].ParentID, tasks[0].ID)
+	}
+	var deps int64
+	DB.Model(&TaskDependency{}).Where("task_id = ? AND depends_on_id = ?", tasks[0].ID, tasks[1].ID).Count(&deps)
+	if deps != 1 {
+		t.Error("dependency not cloned")
+	}
+
+	// The venue is booked by the source at its own date
+	w = apiRequest(t, r, org.ID, http.MethodPost, clonePath, gin.H{"date": src.Date.Add(time.Hour).Format(time.RFC3339)})
+	if w.Code != http.StatusConflict {
+		t.Errorf("clone onto a booked venue: status %d: %s", w.Code, w.Body.String())
+	}
+
+	// So is the organizer, which blocks when the policy says so
+	if err := DB.Model(&src).Update("venue_id", nil).Error; err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("SCHEDULE_CONFLICT_POLICY", "block")
+	w = apiRequest(t, r, org.ID, http.MethodPost, clonePath, gin.H{"date": src.Date.Add(time.Hour).Format(time.RFC3339)})
+	var blocked struct {
+		Conflicts []busyInterval `json:"conflicts"`
+	}
+	decodeJSON(t, w, &blocked)
+	if w.Code != http.StatusConflict || len(blocked.Conflicts) == 0 || blocked.Conflicts[0].EventID != src.ID {
+		t.Errorf("clone over own event: status %d: %s", w.Code, w.Body.String())
+	}
+}
+
+func TestTemplatesInstantiate(t *testing.T) {
+	useTestDB(t)
+	r := testRouter()
+	owner := createTestUser(t, "TemplateOwner")
+	other := createTestUser(t, "TemplateOther")
+
+	offset := func(minutes int64) *int64 { return &minutes }
+	index := func(i int) *int { return &i }
+	body := gin.H{
+		"name":     "Wedding",
+		"location": "Chapel",
+		"tasks": []TemplateTask{
+			{Title: "Book caterer", Priority: "HIGH", DueOffsetMinutes: offset(-30 * 24 * 60), Checklist: []string{"Menu", "Tasting"}},
+			{Title: "Send invitations", DueOffsetMinutes: offset(-60 * 24 * 60), DependsOn: []int{0}},
+			{Title: "Print menus", Parent: index(0)},
+		},
+	}
+
+	w := apiRequest(t, r, owner.ID, http.MethodPost, "/api/templates", body)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("create template: status %d: %s", w.Code, w.Body.String())
+	}
+	var tpl EventTemplate
+	decodeJSON(t, w, &tpl)
+	if tpl.Tasks[0].Priority != "high" || tpl.Tasks[1].Priority != "medium" {
+		t.Errorf("priorities = %q, %q", tpl.Tasks[0].Priority, tpl.Tasks[1].Priority)
+	}
+
+	if w := apiRequest(t, r, owner.ID, http.MethodPost, "/api/templates", body); w.Code != http.StatusConflict {
+		t.Errorf("duplicate name: status %d, want 409", w.Code)
+	}
+	if w := apiRequest(t, r, other.ID, http.MethodPost, "/api/templates", body); w.Code != http.StatusCreated {
+		t.Errorf("same name, other owner: status %d: %s", w.Code, w.Body.String())
+	}
+	// The unique index backs the count check against concurrent requests
+	err := DB.Create(&EventTemplate{OwnerID: owner.ID, Name: "Wedding", Tasks: []TemplateTask{}}).Error
+	if !isUniqueViolation(err) {
+		t.Errorf("duplicate insert: %v, want a unique violation", err)
+	}
+
+	for name, tasks := range map[string][]TemplateTask{
+		"offset too far":   {{Title: "Far", DueOffsetMinutes: offset(maxDueOffsetMinutes + 1)}},
+		"offset too early": {{Title: "Early", DueOffsetMinutes: offset(-maxDueOffsetMinutes - 1)}},
+		"dependency cycle": {{Title: "A", DependsOn: []int{1}}, {Title: "B", DependsOn: []int{0}}},
+		"parent cycle":     {{Title: "A", Parent: index(1)}, {Title: "B", Parent: index(0)}},
+		"bad index":        {{Title: "A", DependsOn: []int{3}}},
+	} {
+		w := apiRequest(t, r, owner.ID, http.MethodPost, "/api/templates", gin.H{"name": name, "tasks": tasks})
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("%s: status %d, want 400", name, w.Code)
+		}
+	}
+
+	date := time.Now().UTC().Add(90 * 24 * time.Hour).Truncate(time.Hour)
+	if w := apiRequest(t, r, other.ID, http.MethodPost, "/api/events", gin.H{"title": "Not yours", "date": date.Format(time.RFC3339), "template_id": tpl.ID}); w.Code != http.StatusNotFound {
+		t.Errorf("someone else's template: status %d, want 404", w.Code)
+	}
+
+	w = apiRequest(t, r, owner.ID, http.MethodPost, "/api/events", gin.H{"title": "Our wedding", "date": date.Format(time.RFC3339), "template_id": tpl.ID})
+	if w.Code != http.StatusCreated {
+		t.Fatalf("create from template: status %d: %s", w.Code, w.Body.String())
+	}
+	var ev Event
+	decodeJSON(t, w, &ev)
+	if ev.Location != "Chapel" || len(ev.Tasks) != 3 {
+		t.Fatalf("event = %+v", ev)
+	}
+	caterer, invitations, menus := ev.Tasks[0], ev.Tasks[1], ev.Tasks[2]
+	if caterer.DueAt == nil || !caterer.DueAt.Equal(date.Add(-30*24*time.Hour)) || len(caterer.Checklist) != 2 {
+		t.Errorf("caterer = %+v", caterer)
+	}
+	if invitations.DueAt == nil || !invitations.DueAt.Equal(date.Add(-60*24*time.Hour)) {
+		t.Errorf("invitations due %v", invitations.DueAt)
+	}
+	if menus.ParentID == nil || *menus.ParentID != caterer.ID || menus.DueAt != nil {
+		t.Errorf("menus = %+v", menus)
+	}
+	var deps int64
+	DB.Model(&TaskDependency{}).Where("task_id = ? AND depends_on_id = ?", invitations.ID, caterer.ID).Count(&deps)
+	if deps != 1 {
+		t.Error("dependency not instantiated")
+	}
+
+	// Snapshotting the event gives back the blueprint
+	w = apiRequest(t, r, owner.ID, http.MethodPost, "/api/templates", gin.H{"name": "Wedding again", "from_event_id": ev.ID})
+	if w.Code != http.StatusCreated {
+		t.Fatalf("snapshot: status %d: %s", w.Code, w.Body.String())
+	}
+	var snap EventTemplate
+	decodeJSON(t, w, &snap)
+	got, _ := json.Marshal(snap.Tasks)
+	want, _ := json.Marshal(tpl.Tasks)
+	if string(got) != string(want) {
+		t.Errorf("snapshot tasks = %s, want %s", got, want)
+	}
+	if w := apiRequest(t, r, other.ID, http.MethodPost, "/api/templates", gin.H{"name": "Stolen", "from_event_id": ev.ID}); w.Code != http.StatusNotFound {
+		t.Errorf("snapshot of someone else's event: status %d, want 404", w.Code)
+	}
+}
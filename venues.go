@@ -0,0 +1,566 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// -----------------------------
+// Venues
+// -----------------------------
+//
+// Venues are shared: anyone can pick an existing venue for their event, only
+// the user who added a venue can edit or delete it. An event occupies its
+// venue from Date until EndsAt, or for defaultEventDuration when EndsAt is
+// not set; two events overlapping at one venue are a double booking.
+
+const defaultEventDuration = 3 * time.Hour
+
+// eventEndSQL is the end time of an event row in "events".
+const eventEndSQL = "coalesce(events.ends_at, events.date + interval '3 hours')"
+
+const earthRadiusKm = 6371.0
+
+// eventEnd is the Go counterpart of eventEndSQL.
+func eventEnd(ev Event) time.Time {
+	if ev.EndsAt != nil {
+		return *ev.EndsAt
+	}
+	return ev.Date.Add(defaultEventDuration)
+}
+
+// parseEndsAt validates an optional end time for an event starting at start.
+func parseEndsAt(value string, start time.Time) (*time.Time, error) {
+	if strings.TrimSpace(value) == "" {
+		return nil, nil
+	}
+	end, err := parseDateInput(strings.TrimSpace(value))
+	if err != nil {
+		return nil, errors.New("invalid ends_at format (use RFC3339 or YYYY-MM-DD)")
+	}
+	if !end.After(start) {
+		return nil, errors.New("ends_at must be after the event date")
+	}
+	return &end, nil
+}
+
+type venueBooking struct {
+	EventID  uint      `json:"event_id"`
+	StartsAt time.Time `json:"starts_at"`
+	EndsAt   time.Time `json:"ends_at"`
+}
+
+// venueConflicts returns the other events at venueID overlapping [start, end).
+func venueConflicts(tx *gorm.DB, venueID, exceptEventID uint, start, end time.Time) ([]venueBooking, error) {
+	conflicts := make([]venueBooking, 0)
+	err := tx.Model(&Event{}).
+		Select("events.id AS event_id, events.date AS starts_at, "+eventEndSQL+" AS ends_at").
+		Where("events.venue_id = ? AND events.id <> ?", venueID, exceptEventID).
+		Where("events.date < ? AND "+eventEndSQL+" > ?", end, start).
+		Order("events.date asc").
+		Scan(&conflicts).Error
+	return conflicts, err
+}
+
+var errVenueNotFound = errors.New("venue not found")
+
+// venueBookedError lists the bookings an event would overlap.
+type venueBookedError struct {
+	conflicts []venueBooking
+}
+
+func (e *venueBookedError) Error() string { return "venue is already booked at that time" }
+
+// lockVenue locks a venue row so concurrent bookings of it are serialized.
+func lockVenue(tx *gorm.DB, venueID uint) (Venue, error) {
+	var v Venue
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&v, venueID).Error
+	if err == gorm.ErrRecordNotFound {
+		return v, errVenueNotFound
+	}
+	return v, err
+}
+
+// bookVenue checks, with the venue locked, that ev does not overlap another
+// event at its venue. It fills in ev.Venue, and ev.Location when empty.
+func bookVenue(tx *gorm.DB, ev *Event) error {
+	if ev.VenueID == nil {
+		ev.Venue = nil
+		return nil
+	}
+	v, err := lockVenue(tx, *ev.VenueID)
+	if err != nil {
+		return err
+	}
+	conflicts, err := venueConflicts(tx, v.ID, ev.ID, ev.Date, eventEnd(*ev))
+	if err != nil {
+		return err
+	}
+	if len(conflicts) > 0 {
+		return &venueBookedError{conflicts: conflicts}
+	}
+	ev.Venue = &v
+	if strings.TrimSpace(ev.Location) == "" {
+		ev.Location = v.Name
+	}
+	return nil
+}
+
+// venueError writes the response for an error from bookVenue and reports
+// whether err was one. Only the event ids and times of other bookings are
+// revealed.
+func venueError(c *gin.Context, err error) bool {
+	var booked *venueBookedError
+	switch {
+	case err == errVenueNotFound:
+		jsonError(c, http.StatusNotFound, "venue not found")
+	case errors.As(err, &booked):
+		c.JSON(http.StatusConflict, gin.H{"error": booked.Error(), "conflicts": booked.conflicts})
+	default:
+		return false
+	}
+	return true
+}
+
+// loadVenue returns the venue with the given id, writing a 404 when missing.
+func loadVenue(c *gin.Context, venueID uint) (Venue, bool) {
+	var v Venue
+	if err := DB.First(&v, venueID).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			jsonError(c, http.StatusNotFound, "venue not found")
+			return v, false
+		}
+		jsonError(c, http.StatusInternalServerError, "db error: "+err.Error())
+		return v, false
+	}
+	return v, true
+}
+
+func findVenue(c *gin.Context) (Venue, bool) {
+	id, err := strconv.ParseUint(c.Param("venue_id"), 10, 64)
+	if err != nil {
+		jsonError(c, http.StatusBadRequest, "invalid venue id")
+		return Venue{}, false
+	}
+	return loadVenue(c, uint(id))
+}
+
+type VenueRequest struct {
+	Name               string   `json:"name" binding:"required"`
+	Address            string   `json:"address"`
+	Latitude           *float64 `json:"latitude"`
+	Longitude          *float64 `json:"longitude"`
+	Capacity           int      `json:"capacity"`
+	AccessibilityNotes string   `json:"accessibility_notes"`
+}
+
+func (body VenueRequest) apply(v *Venue) error {
+	v.Name = strings.TrimSpace(body.Name)
+	if v.Name == "" {
+		return errors.New("name is required")
+	}
+	if (body.Latitude == nil) != (body.Longitude == nil) {
+		return errors.New("latitude and longitude must be given together")
+	}
+	if body.Latitude != nil && (*body.Latitude < -90 || *body.Latitude > 90) {
+		return errors.New("latitude must be between -90 and 90")
+	}
+	if body.Longitude != nil && (*body.Longitude < -180 || *body.Longitude > 180) {
+		return errors.New("longitude must be between -180 and 180")
+	}
+	if body.Capacity < 0 {
+		return errors.New("capacity must not be negative")
+	}
+	v.Address = strings.TrimSpace(body.Address)
+	v.Latitude = body.Latitude
+	v.Longitude = body.Longitude
+	v.Capacity = body.Capacity
+	v.AccessibilityNotes = strings.TrimSpace(body.AccessibilityNotes)
+	return nil
+}
+
+// POST /api/venues
+func CreateVenue(c *gin.Context) {
+	userID, ok := getUserIDFromContext(c)
+	if !ok {
+		jsonError(c, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	var body VenueRequest
+	if err := c.ShouldBindJSON(&body); err != nil {
+		jsonError(c, http.StatusBadRequest, "invalid body: "+err.Error())
+		return
+	}
+	v := Venue{OwnerID: userID}
+	if err := body.apply(&v); err != nil {
+		jsonError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if err := DB.Create(&v).Error; err != nil {
+		jsonError(c, http.StatusInternalServerError, "could not create venue: "+err.Error())
+		return
+	}
+	c.JSON(http.StatusCreated, v)
+}
+
+var venueSorts = map[string]sortSpec{
+	"name": {column: "venues.name", key: "text"},
+}
+
+// GET /api/venues?q=&limit=&cursor=
+// Venues whose name or address contains q, by name, as a page envelope
+// (see pagination.go).
+func GetVenues(c *gin.Context) {
+	page, ok := parsePageRequest(c, venueSorts, "name")
+	if !ok {
+		return
+	}
+	query := DB.Model(&Venue{})
+	if q := strings.TrimSpace(c.Query("q")); q != "" {
+		pattern := "%" + escapeLike(q) + "%"
+		query = query.Where("name ILIKE ? OR address ILIKE ?", pattern, pattern)
+	}
+	list, next, total, ok := pageQuery(c, page, query, "venues.id", func(v Venue) (interface{}, uint) {
+		return v.Name, v.ID
+	})
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, pageEnvelope(list, next, total))
+}
+
+// GET /api/venues/:venue_id
+func GetVenue(c *gin.Context) {
+	v, ok := findVenue(c)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, v)
+}
+
+// PUT /api/venues/:venue_id
+func UpdateVenue(c *gin.Context) {
+	userID, ok := getUserIDFromContext(c)
+	if !ok {
+		jsonError(c, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	v, ok := findVenue(c)
+	if !ok {
+		return
+	}
+	if v.OwnerID != userID {
+		jsonError(c, http.StatusForbidden, "only the venue's creator can edit it")
+		return
+	}
+	var body VenueRequest
+	if err := c.ShouldBindJSON(&body); err != nil {
+		jsonError(c, http.StatusBadRequest, "invalid body: "+err.Error())
+		return
+	}
+	if err := body.apply(&v); err != nil {
+		jsonError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if err := DB.Save(&v).Error; err != nil {
+		jsonError(c, http.StatusInternalServerError, "could not update venue: "+err.Error())
+		return
+	}
+	c.JSON(http.StatusOK, v)
+}
+
+// DELETE /api/venues/:venue_id
+// Venues still used by events cannot be deleted.
+func DeleteVenue(c *gin.Context) {
+	userID, ok := getUserIDFromContext(c)
+	if !ok {
+		jsonError(c, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	v, ok := findVenue(c)
+	if !ok {
+		return
+	}
+	if v.OwnerID != userID {
+		jsonError(c, http.StatusForbidden, "only the venue's creator can delete it")
+		return
+	}
+	var used int64
+	if err := DB.Model(&Event{}).Where("venue_id = ?", v.ID).Count(&used).Error; err != nil {
+		jsonError(c, http.StatusInternalServerError, "db error: "+err.Error())
+		return
+	}
+	if used > 0 {
+		jsonError(c, http.StatusConflict, "venue is used by "+strconv.FormatInt(used, 10)+" event(s)")
+		return
+	}
+
+	if err := DB.Delete(&v).Error; err != nil {
+		jsonError(c, http.StatusInternalServerError, "delete failed: "+err.Error())
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "venue deleted"})
+}
+
+// GET /api/venues/:venue_id/conflicts
+// Pairs of events double-booking the venue from now on, e.g. after an event
+// was moved by a date poll. Only for the venue's creator.
+func GetVenueConflicts(c *gin.Context) {
+	userID, ok := getUserIDFromContext(c)
+	if !ok {
+		jsonError(c, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	v, ok := findVenue(c)
+	if !ok {
+		return
+	}
+	if v.OwnerID != userID {
+		jsonError(c, http.StatusForbidden, "only the venue's creator can see its bookings")
+		return
+	}
+
+	var rows []struct {
+		FirstID     uint
+		FirstStart  time.Time
+		FirstEnd    time.Time
+		SecondID    uint
+		SecondStart time.Time
+		SecondEnd   time.Time
+	}
+	otherEnd := strings.ReplaceAll(eventEndSQL, "events.", "other.")
+	err := DB.Table("events").
+		Select("events.id AS first_id, events.date AS first_start, "+eventEndSQL+" AS first_end, "+
+			"other.id AS second_id, other.date AS second_start, "+otherEnd+" AS second_end").
+		Joins("JOIN events other ON other.venue_id = events.venue_id AND other.id > events.id").
+		Where("events.venue_id = ? AND "+eventEndSQL+" > ?", v.ID, time.Now()).
+		Where("events.date < " + otherEnd + " AND other.date < " + eventEndSQL).
+		Order("events.date asc, other.date asc").
+		Scan(&rows).Error
+	if err != nil {
+		jsonError(c, http.StatusInternalServerError, "db error: "+err.Error())
+		return
+	}
+
+	out := make([][2]venueBooking, 0, len(rows))
+	for _, r := range rows {
+		out = append(out, [2]venueBooking{
+			{EventID: r.FirstID, StartsAt: r.FirstStart, EndsAt: r.FirstEnd},
+			{EventID: r.SecondID, StartsAt: r.SecondStart, EndsAt: r.SecondEnd},
+		})
+	}
+	c.JSON(http.StatusOK, out)
+}
+
+type EventVenueRequest struct {
+	VenueID *uint           `json:"venue_id"` // null clears the venue
+	EndsAt  json.RawMessage `json:"ends_at"`  // omitted keeps the end time; null or "" resets it to the default duration
+}
+
+// PUT /api/events/:id/venue
+// Sets the venue of an event, and its end time when ends_at is given; 409
+// with the overlapping bookings when the venue is taken.
+func SetEventVenue(c *gin.Context) {
+	userID, ok := getUserIDFromContext(c)
+	if !ok {
+		jsonError(c, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	eventID, ok := parseEventID(c)
+	if !ok {
+		return
+	}
+	ev, ok := findMemberEvent(c, eventID, userID)
+	if !ok {
+		return
+	}
+	if ev.OrganizerID != userID {
+		jsonError(c, http.StatusForbidden, "only organizer can change the venue")
+		return
+	}
+
+	var body EventVenueRequest
+	if err := c.ShouldBindJSON(&body); err != nil {
+		jsonError(c, http.StatusBadRequest, "invalid body: "+err.Error())
+		return
+	}
+	if len(body.EndsAt) > 0 {
+		var value *string
+		if err := json.Unmarshal(body.EndsAt, &value); err != nil {
+			jsonError(c, http.StatusBadRequest, "ends_at must be a string or null")
+			return
+		}
+		endsAt := ""
+		if value != nil {
+			endsAt = *value
+		}
+		end, err := parseEndsAt(endsAt, ev.Date)
+		if err != nil {
+			jsonError(c, http.StatusBadRequest, err.Error())
+			return
+		}
+		ev.EndsAt = end
+	}
+	ev.VenueID = body.VenueID
+
+	err := DB.Transaction(func(tx *gorm.DB) error {
+		if err := bookVenue(tx, &ev); err != nil {
+			return err
+		}
+		return tx.Model(&ev).Select("venue_id", "ends_at", "location").Updates(&ev).Error
+	})
+	if venueError(c, err) {
+		return
+	}
+	if err != nil {
+		jsonError(c, http.StatusInternalServerError, "could not update event: "+err.Error())
+		return
+	}
+	c.JSON(http.StatusOK, ev)
+}
+
+// boundingBox returns the latitude and longitude ranges within radiusKm of
+// a point. minLng > maxLng means the box wraps around the antimeridian.
+func boundingBox(lat, lng, radiusKm float64) (minLat, maxLat, minLng, maxLng float64) {
+	dLat := radiusKm / earthRadiusKm * 180 / math.Pi
+	minLat, maxLat = lat-dLat, lat+dLat
+	if minLat <= -90 || maxLat >= 90 {
+		// the circle contains a pole: every longitude qualifies
+		return math.Max(minLat, -90), math.Min(maxLat, 90), -180, 180
+	}
+	dLng := dLat / math.Cos(lat*math.Pi/180)
+	minLng, maxLng = lng-dLng, lng+dLng
+	if dLng >= 180 {
+		return minLat, maxLat, -180, 180
+	}
+	if minLng < -180 {
+		minLng += 360
+	}
+	if maxLng > 180 {
+		maxLng -= 360
+	}
+	return minLat, maxLat, minLng, maxLng
+}
+
+var nearbySorts = map[string]sortSpec{
+	"distance": {column: "n.distance_km", key: "float"},
+}
+
+// GET /api/events/nearby?lat=&lng=&radius_km=&limit=&cursor=
+// Upcoming events visible to the caller whose venue lies within radius_km
+// (default 10, at most 500) of the point, nearest first, as a page envelope
+// (see pagination.go). The bounding box narrows the venues using the
+// coordinate index before the exact haversine distance is computed.
+func GetNearbyEvents(c *gin.Context) {
+	userID, ok := getUserIDFromContext(c)
+	if !ok {
+		jsonError(c, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	lat, errLat := strconv.ParseFloat(c.Query("lat"), 64)
+	lng, errLng := strconv.ParseFloat(c.Query("lng"), 64)
+	if errLat != nil || errLng != nil || lat < -90 || lat > 90 || lng < -180 || lng > 180 {
+		jsonError(c, http.StatusBadRequest, "lat and lng are required (-90..90, -180..180)")
+		return
+	}
+	radius := 10.0
+	if v := c.Query("radius_km"); v != "" {
+		r, err := strconv.ParseFloat(v, 64)
+		if err != nil || r <= 0 || r > 500 {
+			jsonError(c, http.StatusBadRequest, "radius_km must be between 0 and 500")
+			return
+		}
+		radius = r
+	}
+	page, ok := parsePageRequest(c, nearbySorts, "distance")
+	if !ok {
+		return
+	}
+
+	minLat, maxLat, minLng, maxLng := boundingBox(lat, lng, radius)
+	query := DB.Table("events").
+		Joins("JOIN venues v ON v.id = events.venue_id").
+		Where("v.latitude BETWEEN ? AND ?", minLat, maxLat)
+	if minLng <= maxLng {
+		query = query.Where("v.longitude BETWEEN ? AND ?", minLng, maxLng)
+	} else {
+		query = query.Where("(v.longitude >= ? OR v.longitude <= ?)", minLng, maxLng)
+	}
+
+	// haversine; least() guards asin against rounding just above 1
+	distance := "2 * ? * asin(least(1, sqrt(" +
+		"power(sin(radians(v.latitude - ?) / 2), 2) + " +
+		"cos(radians(?)) * cos(radians(v.latitude)) * power(sin(radians(v.longitude - ?) / 2), 2))))"
+
+	base := DB.Table("(?) AS n", query.
+		Select("events.id, "+distance+" AS distance_km", earthRadiusKm, lat, lat, lng).
+		Where(eventEndSQL+" >= ?", time.Now()).
+		Scopes(visibleEvents(userID))).
+		Where("n.distance_km <= ?", radius).
+		Session(&gorm.Session{})
+	total, err := estimateTotal(base)
+	if err != nil {
+		jsonError(c, http.StatusInternalServerError, "db error: "+err.Error())
+		return
+	}
+
+	var afterID uint
+	if page.After != nil {
+		afterID = page.After.ID
+	}
+	var hits []struct {
+		ID         uint
+		DistanceKm float64
+	}
+	if err := page.seek(base.Select("n.*"), page.spec.column, "n.id", afterID).Scan(&hits).Error; err != nil {
+		jsonError(c, http.StatusInternalServerError, "db error: "+err.Error())
+		return
+	}
+	next := ""
+	if len(hits) > page.Limit {
+		hits = hits[:page.Limit]
+		last := hits[len(hits)-1]
+		next = page.cursorAfter(last.DistanceKm, 0, last.ID)
+	}
+
+	ids := make([]uint, 0, len(hits))
+	for _, h := range hits {
+		ids = append(ids, h.ID)
+	}
+	var events []Event
+	if len(ids) > 0 {
+		if err := DB.Preload("Venue").Preload("Tags").Where("id IN ?", ids).Find(&events).Error; err != nil {
+			jsonError(c, http.StatusInternalServerError, "db error: "+err.Error())
+			return
+		}
+	}
+	byID := make(map[uint]Event, len(events))
+	for _, e := range events {
+		byID[e.ID] = e
+	}
+
+	out := make([]gin.H, 0, len(hits))
+	for _, h := range hits {
+		if ev, ok := byID[h.ID]; ok {
+			out = append(out, gin.H{"event": ev, "distance_km": math.Round(h.DistanceKm*100) / 100})
+		}
+	}
+	c.JSON(http.StatusOK, pageEnvelope(out, next, total))
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ends_at is only touched when the request names it.
+func TestSetEventVenueKeepsEndsAtWhenOmitted(t *testing.T) {
+	useTestDB(t)
+	r := testRouter()
+	organizer := createTestUser(t, "Organizer")
+	ev := createTestEvent(t, organizer.ID, "Launch party", false)
+	path := fmt.Sprintf("/api/events/%d/venue", ev.ID)
+	end := ev.Date.Add(5 * time.Hour)
+
+	endsAt := func(body gin.H) *time.Time {
+		t.Helper()
+		w := apiRequest(t, r, organizer.ID, http.MethodPut, path, body)
+		if w.Code != http.StatusOK {
+			t.Fatalf("%v: got %d %s", body, w.Code, w.Body)
+		}
+		var got Event
+		decodeJSON(t, w, &got)
+		return got.EndsAt
+	}
+
+	if got := endsAt(gin.H{"ends_at": end.Format(time.RFC3339)}); got == nil || !got.Equal(end) {
+		t.Fatalf("set: ends_at = %v, want %v", got, end)
+	}
+	if got := endsAt(gin.H{"venue_id": nil}); got == nil || !got.Equal(end) {
+		t.Fatalf("omitted: ends_at = %v, want %v", got, end)
+	}
+	if got := endsAt(gin.H{"ends_at": nil}); got != nil {
+		t.Fatalf("null: ends_at = %v, want none", got)
+	}
+}